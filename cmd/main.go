@@ -2,51 +2,112 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"github.com/Dmitrij-bot/marketserv/config"
 	"github.com/Dmitrij-bot/marketserv/internal/app"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 )
 
-func GetParamFromEnv(env string, defFileName string) string {
-	configPath := os.Getenv(env)
-	if len(configPath) == 0 {
-		configPath = defFileName
-	}
-
-	return configPath
-}
-
 func main() {
 
-	configPath := GetParamFromEnv("CONFIG", "./config/config.json")
+	args := os.Args[1:]
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" {
+		os.Exit(printConfig(args[2:]))
+	}
 
-	cfg, err := config.Load(configPath)
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatal("cant load config: " + err.Error())
 	}
 
-	a := app.New(cfg)
+	level := new(slog.LevelVar)
+	lg, err := logger.New(cfg.Log, level, os.Stdout)
+	if err != nil {
+		log.Fatal("cant create logger: " + err.Error())
+	}
+	// Route the standard log package and libraries using it through lg.
+	slog.SetDefault(lg)
+
+	watcher := config.NewWatcher(cfg, args, lg)
+	watcher.Subscribe(func(cfg config.Config) {
+		// Validation guarantees a known level.
+		l, _ := logger.ParseLevel(cfg.Log.Level)
+		level.Set(l)
+	})
+
+	a := app.New(watcher, lg)
 
 	startCtx, startCancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer startCancel()
 
 	if err := a.Start(startCtx); err != nil {
-		log.Fatal(err.Error())
+		lg.Error("cannot start application", logger.Err(err))
+		os.Exit(1)
 	}
 
 	quitCh := make(chan os.Signal, 1)
-	signal.Notify(quitCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
-	<-quitCh
+	signal.Notify(quitCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
+
+	for running := true; running; {
+		select {
+		case <-reloadCh:
+			if err := watcher.Reload(); err != nil {
+				lg.Error("cannot reload config", logger.Err(err))
+			}
+		case <-quitCh:
+			running = false
+		}
+	}
 
 	stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer stopCancel()
 
 	err = a.Stop(stopCtx)
 	if err != nil {
-		log.Fatal(err.Error())
+		lg.Error("cannot stop application", logger.Err(err))
+		os.Exit(1)
+	}
+}
+
+// printConfig implements "config print [json|yaml] [flags]": it prints the
+// effective configuration with secrets masked and reports validation
+// problems on stderr.
+func printConfig(args []string) int {
+	format := "json"
+	if len(args) > 0 && (args[0] == "json" || args[0] == "yaml") {
+		format, args = args[0], args[1:]
+	}
+
+	cfg, err := config.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := cfg.Print(os.Stdout, format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
+	return 0
 }
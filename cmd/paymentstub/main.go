@@ -0,0 +1,88 @@
+// Command paymentstub plays a payment provider that settles payments
+// asynchronously: it signs a settlement callback and posts it to the webhook
+// endpoint, optionally several times to exercise redelivery.
+//
+//	go run ./cmd/paymentstub -secret s3cr3t -reference fake_... -result succeeded -repeat 2
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
+	"github.com/Dmitrij-bot/marketserv/internal/webhook"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+func main() {
+	url := flag.String("url", "http://localhost:8081"+webhook.CallbackPath, "webhook endpoint")
+	secret := flag.String("secret", "", "shared HMAC secret")
+	reference := flag.String("reference", "", "payment reference (authorization id) of the order")
+	result := flag.String("result", payment.SettlementSucceeded, "settlement result: succeeded or failed")
+	eventID := flag.String("event", "", "event id, random if empty")
+	repeat := flag.Int("repeat", 1, "how many times to deliver the same event")
+	badSignature := flag.Bool("bad-signature", false, "sign with a wrong secret")
+	flag.Parse()
+
+	if *secret == "" || *reference == "" {
+		log.Fatal("-secret and -reference are required")
+	}
+
+	if *eventID == "" {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			log.Fatal(err)
+		}
+		*eventID = "evt_" + hex.EncodeToString(b)
+	}
+
+	body, err := json.Marshal(webhook.Event{
+		EventID:          *eventID,
+		PaymentReference: *reference,
+		Result:           *result,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	key := *secret
+	if *badSignature {
+		key += "-wrong"
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	for i := 1; i <= *repeat; i++ {
+		status, respBody, err := deliver(client, *url, key, body)
+		if err != nil {
+			log.Fatalf("delivery %d failed: %v", i, err)
+		}
+		fmt.Printf("delivery %d: %d %s", i, status, respBody)
+	}
+}
+
+func deliver(client *http.Client, url, secret string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.TimestampHeader, fmt.Sprint(ts))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, ts, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, respBody, err
+}
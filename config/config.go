@@ -1,31 +1,134 @@
 package config
 
 import (
-	"encoding/json"
 	"github.com/Dmitrij-bot/marketserv/internal/grpc"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
+	"github.com/Dmitrij-bot/marketserv/internal/repository"
+	"github.com/Dmitrij-bot/marketserv/internal/shipping"
+	"github.com/Dmitrij-bot/marketserv/internal/tax"
+	"github.com/Dmitrij-bot/marketserv/internal/webhook"
+	"github.com/Dmitrij-bot/marketserv/pkg/kafka"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"github.com/Dmitrij-bot/marketserv/pkg/lyfecycle"
+	"github.com/Dmitrij-bot/marketserv/pkg/metrics"
 	"github.com/Dmitrij-bot/marketserv/pkg/postgres"
 	"github.com/Dmitrij-bot/marketserv/pkg/redis"
-	"os"
+	"github.com/Dmitrij-bot/marketserv/pkg/tracing"
+)
+
+// Storages the service can keep its data in.
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory" // in-process, lost on restart; for tests and demos
 )
 
 type Config struct {
-	GRPC     grpc.Config
-	Postgres postgres.Config
-	Redis    redis.Config
+	Storage    string // "postgres" (default) or "memory", which runs without Postgres, Redis and Kafka
+	Log        logger.Config
+	Lifecycle  lyfecycle.Config
+	GRPC       grpc.Config
+	Postgres   postgres.Config
+	Redis      redis.Config
+	Kafka      kafka.Config
+	Repository repository.Config
+	Tax        tax.Config
+	Shipping   shipping.Config
+	Payment    payment.Config
+	Webhook    webhook.Config
+	Metrics    metrics.Config
+	Tracing    tracing.Config
 }
 
-func Load(filepath string) (cfg Config, err error) {
+// Default returns the settings used for everything the config file,
+// environment and flags leave out. They match a local development setup.
+func Default() Config {
+	return Config{
+		Storage: StoragePostgres,
+		Log: logger.Config{
+			Level:  "info",
+			Format: logger.FormatJSON,
+		},
+		Lifecycle: lyfecycle.Config{
+			StartAttempts:       3,
+			RetryBackoffSeconds: 1,
+			MaxBackoffSeconds:   4,
+		},
+		GRPC: grpc.Config{
+			Host:                  "0.0.0.0:50051",
+			RequestTimeoutSeconds: 30,
+			Health: grpc.HealthConfig{
+				IntervalSeconds: 5,
+				TimeoutSeconds:  2,
+			},
+		},
+		Postgres: postgres.Config{
+			DBHost:  "localhost",
+			DBPort:  "5432",
+			DBUser:  "postgres",
+			DBName:  "first",
+			SSLMode: "disable",
+
+			MaxOpenConns:            20,
+			MaxIdleConns:            10,
+			ConnMaxLifetimeSeconds:  30 * 60,
+			ConnMaxIdleTimeSeconds:  5 * 60,
+			StatementTimeoutSeconds: 5,
 
-	file, err := os.Open(filepath)
-	if err != nil {
-		return cfg, err
+			MaxReplicaLagSeconds:        2,
+			ReplicaCheckIntervalSeconds: 5,
+		},
+		Redis: redis.Config{
+			Host: "127.0.0.1",
+			Port: "6379",
+		},
+		Kafka: kafka.Config{
+			Brokers: []string{"localhost:29092"},
+			Topic:   "test1",
+		},
+		Repository: repository.Config{
+			CartTTLSeconds:            7 * 24 * 60 * 60,
+			ProductTTLSeconds:         600,
+			SearchTTLSeconds:          60,
+			GuestCartTTLSeconds:       3 * 24 * 60 * 60,
+			CheckoutTTLSeconds:        15 * 60,
+			RedisCheckIntervalSeconds: 5,
+			TxAttempts:                3,
+		},
+		Payment: payment.Config{
+			Provider: payment.ProviderBalance,
+		},
+		Webhook: webhook.Config{
+			Host:             ":8081",
+			ToleranceSeconds: 300,
+		},
+		Metrics: metrics.Config{
+			Host: ":9090",
+		},
+		Tracing: tracing.Config{
+			Exporter:    tracing.ExporterNone,
+			Endpoint:    "localhost:4317",
+			Insecure:    true,
+			ServiceName: "marketserv",
+			SampleRatio: 1,
+		},
 	}
-	defer file.Close()
+}
 
-	err = json.NewDecoder(file).Decode(&cfg)
-	if err != nil {
-		return cfg, err
+// uses reports whether the service connects to what the top-level section
+// configures; the memory storage needs no Postgres, Redis or Kafka.
+func (c Config) uses(section string) bool {
+	if c.Storage != StorageMemory {
+		return true
+	}
+	switch section {
+	case "Postgres", "Redis", "Kafka":
+		return false
 	}
+	return true
+}
 
-	return cfg, nil
+// WebhookEnabled reports whether the payment webhook runs. The memory storage
+// runs without it unless it is given a secret.
+func (c Config) WebhookEnabled() bool {
+	return c.Storage != StorageMemory || c.Webhook.Secret != ""
 }
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// field is a setting. Scalar ones can be overridden by an environment
+// variable or a flag. Maps and lists of structs, such as tax rates and
+// shipping methods, can only be set in the config file.
+type field struct {
+	path   []string
+	value  reflect.Value
+	secret bool
+	reload bool // applied by Watcher.Reload without a restart
+}
+
+// EnvPrefix starts the name of every environment variable read by Load.
+const EnvPrefix = "MARKETSERV_"
+
+// envName returns e.g. MARKETSERV_POSTGRES_DBHOST.
+func (f field) envName() string {
+	return EnvPrefix + strings.ToUpper(strings.Join(f.path, "_"))
+}
+
+// flagName returns e.g. postgres.dbhost.
+func (f field) flagName() string {
+	return strings.ToLower(strings.Join(f.path, "."))
+}
+
+func (f field) set(s string) error {
+	v := f.value
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an unsigned integer", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+// fields lists the scalar settings of cfg, which must be a pointer to a
+// struct.
+func fields(cfg any) []field {
+	return walkFields(cfg, false)
+}
+
+// settings lists every setting of cfg like fields, plus the maps and lists of
+// structs that only the config file sets, so that a reload sees them change.
+func settings(cfg any) []field {
+	return walkFields(cfg, true)
+}
+
+func walkFields(cfg any, all bool) []field {
+	var out []field
+	var walk func(v reflect.Value, path []string, tag reflect.StructTag)
+	walk = func(v reflect.Value, path []string, tag reflect.StructTag) {
+		switch v.Kind() {
+		case reflect.Struct:
+			t := v.Type()
+			for i := 0; i < t.NumField(); i++ {
+				sf := t.Field(i)
+				if !sf.IsExported() {
+					continue
+				}
+				p := append(append([]string(nil), path...), sf.Name)
+				walk(v.Field(i), p, sf.Tag)
+			}
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			out = append(out, newField(path, v, tag))
+		case reflect.Slice:
+			if all || v.Type().Elem().Kind() == reflect.String {
+				out = append(out, newField(path, v, tag))
+			}
+		case reflect.Map:
+			if all {
+				out = append(out, newField(path, v, tag))
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), nil, "")
+	return out
+}
+
+func newField(path []string, v reflect.Value, tag reflect.StructTag) field {
+	return field{
+		path:   path,
+		value:  v,
+		secret: tag.Get("secret") == "true",
+		reload: tag.Get("reload") == "true",
+	}
+}
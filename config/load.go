@@ -0,0 +1,130 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultPath is read when no config file is named. Unlike an explicitly
+// named file, it may be missing.
+const DefaultPath = "./config/config.json"
+
+// Load builds the configuration from, in increasing priority, Default, the
+// config file, MARKETSERV_* environment variables and command-line flags, and
+// validates the result.
+//
+// The file is named by the -config flag, MARKETSERV_CONFIG or the legacy
+// CONFIG variable and may be JSON or YAML. Every scalar setting can be
+// overridden with an environment variable such as MARKETSERV_POSTGRES_DBHOST
+// or a flag such as -postgres.dbhost; lists are comma separated. Secrets may
+// be given as references, "file:/run/secrets/db_password" or
+// "env:DB_PASSWORD", which are resolved once every source is merged.
+func Load(args []string) (cfg Config, err error) {
+	cfg, err = Parse(args)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Parse merges the configuration like Load but does not validate it.
+func Parse(args []string) (cfg Config, err error) {
+	cfg = Default()
+
+	fs := flag.NewFlagSet("marketserv", flag.ContinueOnError)
+
+	path := fs.String("config", "", "config file, JSON or YAML")
+	var overrides []func() error
+	for _, f := range fields(&cfg) {
+		fs.Func(f.flagName(), "overrides "+strings.Join(f.path, "."), func(s string) error {
+			overrides = append(overrides, func() error {
+				if err := f.set(s); err != nil {
+					return fmt.Errorf("-%s: %w", f.flagName(), err)
+				}
+				return nil
+			})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if err := loadFile(&cfg, configPath(*path)); err != nil {
+		return cfg, err
+	}
+
+	var errs []error
+	for _, f := range fields(&cfg) {
+		if s, ok := os.LookupEnv(f.envName()); ok {
+			if err := f.set(s); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.envName(), err))
+			}
+		}
+	}
+	for _, override := range overrides {
+		if err := override(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return cfg, errors.Join(errs...)
+	}
+
+	return cfg, resolveSecrets(&cfg)
+}
+
+func configPath(flagValue string) string {
+	for _, p := range []string{flagValue, os.Getenv(EnvPrefix + "CONFIG"), os.Getenv("CONFIG")} {
+		if p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// loadFile decodes the file at path over cfg. YAML is converted to JSON first
+// so that both formats use the same, case-insensitive, key names.
+func loadFile(cfg *Config, path string) error {
+	optional := path == ""
+	if optional {
+		path = DefaultPath
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if optional && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("cannot parse %s: %w", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("cannot parse %s: %w", path, err)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	return nil
+}
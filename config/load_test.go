@@ -0,0 +1,199 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file named name and returns its path.
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParsePrecedence(t *testing.T) {
+	t.Setenv("MARKETSERV_CONFIG", "")
+	t.Setenv("CONFIG", "")
+	file := writeConfig(t, "config.json", `{"GRPC": {"Host": "0.0.0.0:1001"}}`)
+
+	tests := []struct {
+		name string
+		file bool
+		env  string
+		flag string
+		want string
+	}{
+		{name: "default", want: Default().GRPC.Host},
+		{name: "file over default", file: true, want: "0.0.0.0:1001"},
+		{name: "env over file", file: true, env: "0.0.0.0:1002", want: "0.0.0.0:1002"},
+		{name: "flag over env", file: true, env: "0.0.0.0:1002", flag: "0.0.0.0:1003", want: "0.0.0.0:1003"},
+		{name: "flag without file", flag: "0.0.0.0:1003", want: "0.0.0.0:1003"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []string
+			if tt.file {
+				args = append(args, "-config", file)
+			}
+			if tt.flag != "" {
+				args = append(args, "-grpc.host", tt.flag)
+			}
+			if tt.env != "" {
+				t.Setenv("MARKETSERV_GRPC_HOST", tt.env)
+			}
+
+			cfg, err := Parse(args)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if cfg.GRPC.Host != tt.want {
+				t.Errorf("GRPC.Host = %q, want %q", cfg.GRPC.Host, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFileFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "json",
+			file: "config.json",
+			content: `{"Log": {"Level": "debug"}, "Kafka": {"Brokers": ["a:9092", "b:9092"]},
+			           "Tax": {"Rates": {"RU": {"standard": 20}}}}`,
+		},
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: "log:\n  level: debug\nkafka:\n  brokers: [a:9092, b:9092]\n" +
+				"tax:\n  rates:\n    RU:\n      standard: 20\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]string{"-config", writeConfig(t, tt.file, tt.content)})
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if cfg.Log.Level != "debug" || !slices.Equal(cfg.Kafka.Brokers, []string{"a:9092", "b:9092"}) || cfg.Tax.Rates["RU"]["standard"] != 20 {
+				t.Errorf("Parse() = %+v, want the file's log level, brokers and tax rates", cfg)
+			}
+			// Settings the file leaves out keep their defaults.
+			if cfg.GRPC.Host != Default().GRPC.Host {
+				t.Errorf("GRPC.Host = %q, want the default", cfg.GRPC.Host)
+			}
+		})
+	}
+}
+
+func TestParseRejectsBadFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		args func(t *testing.T) []string
+	}{
+		{"missing named file", func(t *testing.T) []string { return []string{"-config", filepath.Join(t.TempDir(), "none.json")} }},
+		{"unknown key", func(t *testing.T) []string {
+			return []string{"-config", writeConfig(t, "config.json", `{"GRPC": {"Hots": "x"}}`)}
+		}},
+		{"broken yaml", func(t *testing.T) []string { return []string{"-config", writeConfig(t, "config.yml", "log: [")} }},
+		{"stray argument", func(t *testing.T) []string { return []string{"serve"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.args(t)); err == nil {
+				t.Error("Parse() error = nil")
+			}
+		})
+	}
+}
+
+func TestParseBadValues(t *testing.T) {
+	t.Setenv("MARKETSERV_CONFIG", "")
+	t.Setenv("CONFIG", "")
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want []string
+	}{
+		{
+			name: "bad int env",
+			env:  map[string]string{"MARKETSERV_REPOSITORY_TXATTEMPTS": "three"},
+			want: []string{`MARKETSERV_REPOSITORY_TXATTEMPTS: "three" is not an integer`},
+		},
+		{
+			name: "bad bool env",
+			env:  map[string]string{"MARKETSERV_TAX_PRICESINCLUDETAX": "maybe"},
+			want: []string{`MARKETSERV_TAX_PRICESINCLUDETAX: "maybe" is not a boolean`},
+		},
+		{
+			name: "every bad value is reported",
+			env:  map[string]string{"MARKETSERV_REPOSITORY_TXATTEMPTS": "three"},
+			args: []string{"-tax.pricesincludetax", "maybe"},
+			want: []string{
+				`MARKETSERV_REPOSITORY_TXATTEMPTS: "three" is not an integer`,
+				`-tax.pricesincludetax: "maybe" is not a boolean`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Parse(tt.args)
+			if err == nil {
+				t.Fatal("Parse() error = nil")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Parse() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Storage = "memory"
+	cfg.Log.Level = "loud"
+	cfg.GRPC.Host = "nowhere"
+	cfg.Repository.TxAttempts = -1
+	cfg.Tax.Rates = map[string]map[string]float64{"BY": {"standard": 120}}
+
+	var verr *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate() error = %v, want a *ValidationError", err)
+	}
+	want := []string{
+		`GRPC.Host: "nowhere" is not a host:port address`,
+		`Log.Level: "loud" is not a log level`,
+		"Repository.TxAttempts: must not be negative",
+		`Tax.DefaultRegion: "" has no rates in Tax.Rates`,
+		"Tax.Rates[BY][standard]: 120 is not a percentage",
+	}
+	if !slices.Equal(verr.Problems, want) {
+		t.Errorf("problems =\n%s\nwant\n%s", strings.Join(verr.Problems, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	cfg := Default()
+	cfg.Webhook.Secret = "secret"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() of the defaults error = %v", err)
+	}
+}
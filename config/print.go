@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+)
+
+const masked = "******"
+
+// Masked returns a copy of c with every field tagged secret:"true" masked.
+func (c Config) Masked() Config {
+	for _, f := range fields(&c) {
+		if f.secret && !f.value.IsZero() {
+			_ = f.set(masked)
+		}
+	}
+	return c
+}
+
+// Print writes the configuration with secrets masked as "json" or "yaml".
+func (c Config) Print(w io.Writer, format string) error {
+	data, err := json.MarshalIndent(c.Masked(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "", "json":
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case "yaml":
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		clearStyle(&doc)
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// clearStyle drops the flow style the JSON input leaves on maps, lists and
+// keys. String values stay quoted so that e.g. a port reads back as a string.
+func clearStyle(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+		n.Style = 0
+	}
+	for i, c := range n.Content {
+		if n.Kind == yaml.MappingNode && i%2 == 0 {
+			c.Style = 0
+			continue
+		}
+		clearStyle(c)
+	}
+}
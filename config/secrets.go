@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	secretFilePrefix = "file:"
+	secretEnvPrefix  = "env:"
+)
+
+var errSecretNotSet = errors.New("secret variable is not set")
+
+// resolveSecrets replaces the references held by fields tagged secret:"true"
+// with the secrets they point to: "file:/run/secrets/db_password" reads a
+// file, dropping the trailing newline, and "env:DB_PASSWORD" reads an
+// environment variable. Any other value is used as is. References of
+// sections the storage does not use are left unresolved. An unset webhook
+// secret of the memory storage resolves to empty and turns the webhook off.
+func resolveSecrets(cfg *Config) error {
+	var errs []error
+	for _, f := range fields(cfg) {
+		if !f.secret || f.value.Kind() != reflect.String || !cfg.uses(f.path[0]) {
+			continue
+		}
+		s, err := resolveSecret(f.value.String())
+		if errors.Is(err, errSecretNotSet) && cfg.Storage == StorageMemory && f.path[0] == "Webhook" {
+			f.value.SetString("")
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", strings.Join(f.path, "."), err))
+			continue
+		}
+		f.value.SetString(s)
+	}
+	return errors.Join(errs...)
+}
+
+func resolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, secretFilePrefix):
+		path := strings.TrimPrefix(ref, secretFilePrefix)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("cannot read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(ref, secretEnvPrefix):
+		name := strings.TrimPrefix(ref, secretEnvPrefix)
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", errSecretNotSet, name)
+		}
+		return s, nil
+	default:
+		return ref, nil
+	}
+}
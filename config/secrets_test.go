@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	t.Setenv("TEST_DB_PASSWORD", "from-env")
+	t.Setenv("TEST_EMPTY_SECRET", "")
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{name: "literal", ref: "plain-password", want: "plain-password"},
+		{name: "empty", ref: "", want: ""},
+		{name: "env", ref: "env:TEST_DB_PASSWORD", want: "from-env"},
+		{name: "env set to empty", ref: "env:TEST_EMPTY_SECRET", want: ""},
+		{name: "missing env", ref: "env:TEST_NO_SUCH_SECRET", wantErr: "Postgres.DBPassword: secret variable is not set: TEST_NO_SUCH_SECRET"},
+		{name: "file", ref: "file:" + writeSecret("plain", "from-file"), want: "from-file"},
+		{name: "file with trailing newline", ref: "file:" + writeSecret("lf", "from-file\n"), want: "from-file"},
+		{name: "file with trailing crlf", ref: "file:" + writeSecret("crlf", "from-file\r\n"), want: "from-file"},
+		{name: "file keeps inner newlines", ref: "file:" + writeSecret("multi", "line1\nline2\n"), want: "line1\nline2"},
+		{name: "missing file", ref: "file:" + filepath.Join(dir, "none"), wantErr: "Postgres.DBPassword: cannot read secret file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Postgres.DBPassword = tt.ref
+
+			err := resolveSecrets(&cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("resolveSecrets() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSecrets() error = %v", err)
+			}
+			if cfg.Postgres.DBPassword != tt.want {
+				t.Errorf("DBPassword = %q, want %q", cfg.Postgres.DBPassword, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSecretsLeavesOtherFieldsAlone(t *testing.T) {
+	t.Setenv("TEST_HOST", "db.internal")
+	cfg := Default()
+	cfg.Postgres.DBHost = "env:TEST_HOST"
+	cfg.Postgres.DBUser = "file:/etc/passwd"
+
+	if err := resolveSecrets(&cfg); err != nil {
+		t.Fatalf("resolveSecrets() error = %v", err)
+	}
+	if cfg.Postgres.DBHost != "env:TEST_HOST" || cfg.Postgres.DBUser != "file:/etc/passwd" {
+		t.Errorf("non-secret fields = %q, %q, want them kept literally", cfg.Postgres.DBHost, cfg.Postgres.DBUser)
+	}
+}
+
+func TestResolveSecretsReportsEveryMissingSecret(t *testing.T) {
+	cfg := Default()
+	cfg.Postgres.DBPassword = "env:TEST_NO_DB_PASSWORD"
+	cfg.Redis.Password = "env:TEST_NO_REDIS_PASSWORD"
+
+	err := resolveSecrets(&cfg)
+	if !errors.Is(err, errSecretNotSet) {
+		t.Fatalf("resolveSecrets() error = %v, want %v", err, errSecretNotSet)
+	}
+	for _, want := range []string{"Postgres.DBPassword", "Redis.Password"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("resolveSecrets() error = %v, want it to name %s", err, want)
+		}
+	}
+}
+
+func TestResolveSecretsSkipsUnusedSections(t *testing.T) {
+	cfg := Default()
+	cfg.Storage = StorageMemory
+	cfg.Postgres.DBPassword = "env:TEST_NO_DB_PASSWORD"
+	cfg.Webhook.Secret = "env:TEST_NO_WEBHOOK_SECRET"
+
+	if err := resolveSecrets(&cfg); err != nil {
+		t.Fatalf("resolveSecrets() error = %v", err)
+	}
+	if cfg.Postgres.DBPassword != "env:TEST_NO_DB_PASSWORD" {
+		t.Errorf("DBPassword = %q, want the unused reference left alone", cfg.Postgres.DBPassword)
+	}
+	if cfg.Webhook.Secret != "" || cfg.WebhookEnabled() {
+		t.Errorf("Webhook.Secret = %q, want the webhook disabled", cfg.Webhook.Secret)
+	}
+}
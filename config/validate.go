@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"github.com/Dmitrij-bot/marketserv/pkg/tracing"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValidationError lists every invalid setting of a configuration.
+type ValidationError struct {
+	Problems []string // "<field>: <problem>"
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) check(ok bool, field, format string, args ...any) {
+	if !ok {
+		v.problems = append(v.problems, field+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+func (v *validator) addr(field, addr string) {
+	_, port, err := net.SplitHostPort(addr)
+	v.check(err == nil && validPort(port), field, "%q is not a host:port address", addr)
+}
+
+func (v *validator) port(field, port string) {
+	v.check(validPort(port), field, "%q is not a port number", port)
+}
+
+func (v *validator) nonNegative(field string, n int) {
+	v.check(n >= 0, field, "must not be negative")
+}
+
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	v.check(slices.Contains(allowed, value), field, "%q is not one of %s", value, strings.Join(allowed, ", "))
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// Validate reports every invalid setting at once as a *ValidationError.
+func (c Config) Validate() error {
+	v := &validator{}
+
+	v.oneOf("Storage", c.Storage, StoragePostgres, StorageMemory)
+
+	var level slog.Level
+	v.check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "Log.Level", "%q is not a log level", c.Log.Level)
+	v.oneOf("Log.Format", strings.ToLower(c.Log.Format), logger.FormatJSON, logger.FormatText)
+
+	v.check(c.Lifecycle.StartAttempts >= 1, "Lifecycle.StartAttempts", "must be at least 1")
+	v.nonNegative("Lifecycle.RetryBackoffSeconds", c.Lifecycle.RetryBackoffSeconds)
+	v.nonNegative("Lifecycle.MaxBackoffSeconds", c.Lifecycle.MaxBackoffSeconds)
+
+	v.addr("GRPC.Host", c.GRPC.Host)
+	v.nonNegative("GRPC.RequestTimeoutSeconds", c.GRPC.RequestTimeoutSeconds)
+	v.nonNegative("GRPC.Health.IntervalSeconds", c.GRPC.Health.IntervalSeconds)
+	v.nonNegative("GRPC.Health.TimeoutSeconds", c.GRPC.Health.TimeoutSeconds)
+	v.nonNegative("GRPC.Health.DrainSeconds", c.GRPC.Health.DrainSeconds)
+
+	if c.uses("Postgres") {
+		v.check(c.Postgres.DBHost != "", "Postgres.DBHost", "is required")
+		v.port("Postgres.DBPort", c.Postgres.DBPort)
+		v.check(c.Postgres.DBUser != "", "Postgres.DBUser", "is required")
+		v.check(c.Postgres.DBName != "", "Postgres.DBName", "is required")
+		v.oneOf("Postgres.SSLMode", c.Postgres.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+		v.check(c.Postgres.SSLKey != "" || c.Postgres.SSLCert == "", "Postgres.SSLKey", "is required with Postgres.SSLCert")
+		v.check(c.Postgres.SSLCert != "" || c.Postgres.SSLKey == "", "Postgres.SSLCert", "is required with Postgres.SSLKey")
+		v.check(c.Postgres.SSLCert == "" || c.Postgres.SSLMode != "disable", "Postgres.SSLCert", "needs an SSLMode other than disable")
+		v.nonNegative("Postgres.MaxOpenConns", c.Postgres.MaxOpenConns)
+		v.nonNegative("Postgres.MaxIdleConns", c.Postgres.MaxIdleConns)
+		v.check(c.Postgres.MaxOpenConns == 0 || c.Postgres.MaxIdleConns <= c.Postgres.MaxOpenConns, "Postgres.MaxIdleConns", "must not exceed Postgres.MaxOpenConns")
+		v.nonNegative("Postgres.ConnMaxLifetimeSeconds", c.Postgres.ConnMaxLifetimeSeconds)
+		v.nonNegative("Postgres.ConnMaxIdleTimeSeconds", c.Postgres.ConnMaxIdleTimeSeconds)
+		v.nonNegative("Postgres.StatementTimeoutSeconds", c.Postgres.StatementTimeoutSeconds)
+		for i, replica := range c.Postgres.Replicas {
+			v.addr(fmt.Sprintf("Postgres.Replicas[%d]", i), replica)
+		}
+		v.nonNegative("Postgres.MaxReplicaLagSeconds", c.Postgres.MaxReplicaLagSeconds)
+		v.nonNegative("Postgres.ReplicaCheckIntervalSeconds", c.Postgres.ReplicaCheckIntervalSeconds)
+	}
+
+	if c.uses("Redis") {
+		v.check(c.Redis.Host != "", "Redis.Host", "is required")
+		v.port("Redis.Port", c.Redis.Port)
+		v.check(c.Redis.Password != "" || c.Redis.Username == "", "Redis.Password", "is required with Redis.Username")
+		if c.Redis.TLS.Enabled {
+			v.check(c.Redis.TLS.KeyFile != "" || c.Redis.TLS.CertFile == "", "Redis.TLS.KeyFile", "is required with Redis.TLS.CertFile")
+			v.check(c.Redis.TLS.CertFile != "" || c.Redis.TLS.KeyFile == "", "Redis.TLS.CertFile", "is required with Redis.TLS.KeyFile")
+		}
+	}
+
+	if c.uses("Kafka") {
+		v.check(len(c.Kafka.Brokers) > 0, "Kafka.Brokers", "at least one broker is required")
+		for i, broker := range c.Kafka.Brokers {
+			v.addr(fmt.Sprintf("Kafka.Brokers[%d]", i), broker)
+		}
+		v.check(c.Kafka.Topic != "", "Kafka.Topic", "is required")
+	}
+
+	v.nonNegative("Repository.CartTTLSeconds", c.Repository.CartTTLSeconds)
+	v.nonNegative("Repository.ProductTTLSeconds", c.Repository.ProductTTLSeconds)
+	v.nonNegative("Repository.SearchTTLSeconds", c.Repository.SearchTTLSeconds)
+	v.nonNegative("Repository.GuestCartTTLSeconds", c.Repository.GuestCartTTLSeconds)
+	v.nonNegative("Repository.CheckoutTTLSeconds", c.Repository.CheckoutTTLSeconds)
+	v.nonNegative("Repository.RedisCheckIntervalSeconds", c.Repository.RedisCheckIntervalSeconds)
+	v.nonNegative("Repository.TxAttempts", c.Repository.TxAttempts)
+
+	if len(c.Tax.Rates) > 0 {
+		_, ok := c.Tax.Rates[c.Tax.DefaultRegion]
+		v.check(ok, "Tax.DefaultRegion", "%q has no rates in Tax.Rates", c.Tax.DefaultRegion)
+	}
+	for region, classes := range c.Tax.Rates {
+		for class, rate := range classes {
+			v.check(rate >= 0 && rate <= 100, fmt.Sprintf("Tax.Rates[%s][%s]", region, class), "%v is not a percentage", rate)
+		}
+	}
+
+	for i, m := range c.Shipping.Methods {
+		v.check(m.Code != "", fmt.Sprintf("Shipping.Methods[%d].Code", i), "is required")
+	}
+
+	v.oneOf("Payment.Provider", c.Payment.Provider, "", payment.ProviderBalance, payment.ProviderFake)
+	if c.Payment.Provider == payment.ProviderFake {
+		v.oneOf("Payment.Fake.Outcome", c.Payment.Fake.Outcome, "", payment.OutcomeSucceed, payment.OutcomeDecline, payment.OutcomeTimeout, payment.OutcomeCaptureDecline)
+		v.nonNegative("Payment.Fake.TimeoutSeconds", c.Payment.Fake.TimeoutSeconds)
+	}
+
+	if c.WebhookEnabled() {
+		v.addr("Webhook.Host", c.Webhook.Host)
+		v.check(c.Webhook.Secret != "", "Webhook.Secret", "is required")
+		v.nonNegative("Webhook.ToleranceSeconds", c.Webhook.ToleranceSeconds)
+	}
+
+	v.addr("Metrics.Host", c.Metrics.Host)
+
+	v.oneOf("Tracing.Exporter", c.Tracing.Exporter, "", tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP)
+	if c.Tracing.Exporter == tracing.ExporterOTLP {
+		v.check(c.Tracing.Endpoint != "", "Tracing.Endpoint", "is required for the otlp exporter")
+	}
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "Tracing.SampleRatio", "must be between 0 and 1")
+
+	if len(v.problems) > 0 {
+		slices.Sort(v.problems)
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// Watcher holds the running configuration and hands reloaded versions of it
+// to subscribers. Only settings tagged reload:"true", such as the log level,
+// cache lifetimes, tax rates and shipping rates, change on reload; the rest
+// keep their startup values until the service is restarted.
+type Watcher struct {
+	args []string
+	log  *slog.Logger
+
+	reloadMu sync.Mutex
+
+	mu   sync.Mutex
+	cfg  Config
+	subs []func(cfg Config)
+}
+
+// NewWatcher returns a watcher for cfg, which was loaded from args.
+func NewWatcher(cfg Config, args []string, log *slog.Logger) *Watcher {
+	return &Watcher{
+		args: args,
+		log:  log,
+		cfg:  cfg,
+	}
+}
+
+// Config returns the running configuration.
+func (w *Watcher) Config() Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cfg
+}
+
+// Subscribe registers fn to be called with the configuration after every
+// reload that changed a setting. Subscribers are called one at a time and
+// outside the watcher's lock, so they may call Config.
+func (w *Watcher) Subscribe(fn func(cfg Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// Reload loads the configuration again from the same sources as at startup.
+// An invalid configuration is rejected as a whole and the running one is
+// kept. Changes to settings that need a restart are logged and ignored.
+func (w *Watcher) Reload() error {
+	next, err := Load(w.args)
+	if err != nil {
+		return fmt.Errorf("config reload rejected: %w", err)
+	}
+
+	// Reloads are serialized so that subscribers see them in order.
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	w.mu.Lock()
+	cfg := w.cfg
+	applied, ignored := merge(&cfg, &next)
+	if len(applied) > 0 {
+		w.cfg = cfg
+	}
+	subs := slices.Clone(w.subs)
+	w.mu.Unlock()
+
+	if len(ignored) > 0 {
+		w.log.Warn("config changes need a restart", slog.Any("settings", ignored))
+	}
+	if len(applied) == 0 {
+		w.log.Info("config reloaded without changes")
+		return nil
+	}
+
+	for _, fn := range subs {
+		fn(cfg)
+	}
+	w.log.Info("config reloaded", slog.Any("settings", applied))
+	return nil
+}
+
+// merge copies the reloadable settings of next into cfg and returns the
+// names of the settings that differ, split by whether they were copied.
+func merge(cfg, next *Config) (applied, ignored []string) {
+	cur, upd := settings(cfg), settings(next)
+	for i, f := range cur {
+		if reflect.DeepEqual(f.value.Interface(), upd[i].value.Interface()) {
+			continue
+		}
+		if !f.reload {
+			ignored = append(ignored, f.flagName())
+			continue
+		}
+		f.value.Set(upd[i].value)
+		applied = append(applied, f.flagName())
+	}
+	return applied, ignored
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"github.com/Dmitrij-bot/marketserv/internal/shipping"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	cfg, next := Default(), Default()
+	next.Log.Level = "debug"
+	next.Repository.CartTTLSeconds = 60
+	next.GRPC.Host = "0.0.0.0:6000"
+	next.Tax.Rates = map[string]map[string]float64{"RU": {"standard": 20}}
+	next.Shipping.Methods = []shipping.Method{{Code: "courier"}}
+
+	applied, ignored := merge(&cfg, &next)
+	slices.Sort(applied)
+
+	if want := []string{"log.level", "repository.cartttlseconds", "shipping.methods", "tax.rates"}; !slices.Equal(applied, want) {
+		t.Errorf("applied = %q, want %q", applied, want)
+	}
+	if want := []string{"grpc.host"}; !slices.Equal(ignored, want) {
+		t.Errorf("ignored = %q, want %q", ignored, want)
+	}
+	if cfg.Log.Level != "debug" || cfg.Tax.Rates["RU"]["standard"] != 20 || len(cfg.Shipping.Methods) != 1 {
+		t.Errorf("reloadable settings were not copied: %+v", cfg)
+	}
+	if cfg.GRPC.Host != Default().GRPC.Host {
+		t.Errorf("GRPC.Host = %q, want the running value kept", cfg.GRPC.Host)
+	}
+}
+
+func TestMergeWithoutChanges(t *testing.T) {
+	cfg, next := Default(), Default()
+	if applied, ignored := merge(&cfg, &next); len(applied) > 0 || len(ignored) > 0 {
+		t.Errorf("merge() = %q, %q, want no changes", applied, ignored)
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"Storage": "memory", "Log": {"Level": "info"}}`)
+	args := []string{"-config", path}
+	cfg, err := Load(args)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	w := NewWatcher(cfg, args, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var seen []string
+	w.Subscribe(func(cfg Config) {
+		// Subscribers may read the running configuration.
+		seen = append(seen, w.Config().Log.Level)
+	})
+
+	write(`{"Storage": "memory", "Log": {"Level": "debug"}, "GRPC": {"Host": "0.0.0.0:6000"}}`)
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := w.Config(); got.Log.Level != "debug" || got.GRPC.Host != cfg.GRPC.Host {
+		t.Errorf("after reload Log.Level = %q, GRPC.Host = %q, want debug and the startup host", got.Log.Level, got.GRPC.Host)
+	}
+
+	write(`{"Storage": "memory", "Log": {"Level": "loud"}}`)
+	if err := w.Reload(); err == nil {
+		t.Error("Reload() of an invalid config error = nil")
+	}
+	if got := w.Config().Log.Level; got != "debug" {
+		t.Errorf("after a rejected reload Log.Level = %q, want debug kept", got)
+	}
+
+	write(`{"Storage": "memory", "Log": {"Level": "debug"}}`)
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if want := []string{"debug"}; !slices.Equal(seen, want) {
+		t.Errorf("subscribers saw %q, want %q", seen, want)
+	}
+}
@@ -6,102 +6,185 @@ import (
 	"github.com/Dmitrij-bot/marketserv/config"
 	"github.com/Dmitrij-bot/marketserv/internal/delivery/grpc"
 	grpc2 "github.com/Dmitrij-bot/marketserv/internal/grpc"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
 	"github.com/Dmitrij-bot/marketserv/internal/repository"
+	"github.com/Dmitrij-bot/marketserv/internal/shipping"
+	"github.com/Dmitrij-bot/marketserv/internal/tax"
 	"github.com/Dmitrij-bot/marketserv/internal/usecase"
+	"github.com/Dmitrij-bot/marketserv/internal/webhook"
+	"github.com/Dmitrij-bot/marketserv/pkg/kafka"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
 	"github.com/Dmitrij-bot/marketserv/pkg/lyfecycle"
+	"github.com/Dmitrij-bot/marketserv/pkg/metrics"
 	"github.com/Dmitrij-bot/marketserv/pkg/postgres"
 	"github.com/Dmitrij-bot/marketserv/pkg/redis"
-	"log"
+	"github.com/Dmitrij-bot/marketserv/pkg/tracing"
+	"log/slog"
 )
 
 type App struct {
-	cfg  config.Config
-	cmps []cmp
-}
-type cmp struct {
-	Service lyfecycle.Lyfecycle
-	Name    string
+	cfg        config.Config
+	watcher    *config.Watcher
+	log        *slog.Logger
+	health     *grpc2.Health
+	components *lyfecycle.Manager
 }
 
-func New(cfg config.Config) *App {
-	return &App{cfg: cfg}
+func New(watcher *config.Watcher, log *slog.Logger) *App {
+	return &App{cfg: watcher.Config(), watcher: watcher, log: log}
 }
 
 func (app *App) Start(ctx context.Context) error {
 
-	db := postgres.NewDB(app.cfg.Postgres)
-	redisClient := redis.NewRedisDB(app.cfg.Redis)
-	userRepo := repository.NewUserRepository(db, redisClient)
-	userUseCase := usecase.New(userRepo)
-	userService := grpc.NewUserService(userUseCase)
-	grpcServer := grpc2.NewGRPCServer(app.cfg.GRPC, userService)
-
-	app.cmps = append(
-		app.cmps,
-		cmp{db, "grpc db"},
-		cmp{grpcServer, "grpcServ"},
-		cmp{redisClient, "redisClient"},
-	)
-
-	okCh, errCh := make(chan struct{}), make(chan error)
+	app.health = grpc2.NewHealth(app.cfg.GRPC.Health, app.log)
+	app.components = lyfecycle.NewManager(app.cfg.Lifecycle, app.log)
 
-	go func() {
-		for _, c := range app.cmps {
-			log.Printf("%v is starting", c.Name)
+	var (
+		userRepo        repository.Interface
+		paymentProvider payment.PaymentProvider
+		grpcDeps        = []string{"tracing", "metricsServ", "kafkaProducer"}
+		webhookDeps     = []string{"tracing", "metricsServ", "kafkaProducer"}
+		err             error
+	)
+	kafkaCfg := app.cfg.Kafka
+	if app.cfg.Storage == config.StorageMemory {
+		userRepo, paymentProvider, err = app.memoryStorage()
+		// Events are dropped: the memory storage runs without Kafka.
+		kafkaCfg.Brokers = nil
+	} else {
+		userRepo, paymentProvider, err = app.postgresStorage()
+		grpcDeps = append(grpcDeps, "postgres", "redisClient")
+		webhookDeps = append(webhookDeps, "postgres")
+	}
+	if err != nil {
+		return err
+	}
 
-			if err := c.Service.Start(ctx); err != nil {
-				err = fmt.Errorf("Cannot start %s: %v", c.Name, err)
+	taxTable := tax.NewTable(app.cfg.Tax)
+	shippingTable := shipping.NewTable(app.cfg.Shipping)
+	app.watcher.Subscribe(func(cfg config.Config) {
+		taxTable.Reload(cfg.Tax)
+		shippingTable.Reload(cfg.Shipping)
+	})
+
+	kafkaProducer := kafka.NewProducer(kafkaCfg, app.log)
+	userUseCase := usecase.New(userRepo, taxTable, shippingTable, paymentProvider, kafkaProducer, app.log)
+	userService := grpc.NewUserService(userUseCase, app.log)
+	grpcServer := grpc2.NewGRPCServer(app.cfg.GRPC, userService, app.health, app.log)
+	webhookServer := webhook.NewServer(app.cfg.Webhook, userUseCase, app.log)
+	metricsServer := metrics.NewServer(app.cfg.Metrics, app.log)
+	tracingProvider := tracing.NewProvider(app.cfg.Tracing, app.log)
+
+	if kafkaProducer.Enabled() {
+		app.health.Register("kafka", kafkaProducer, false)
+	}
 
-				log.Println(err)
+	app.components.Add("tracing", tracingProvider)
+	app.components.Add("metricsServ", metricsServer)
+	app.components.Add("kafkaProducer", kafkaProducer)
+	app.components.Add("grpcServ", grpcServer, grpcDeps...)
+	if app.cfg.WebhookEnabled() {
+		app.components.Add("webhookServ", webhookServer, webhookDeps...)
+	} else {
+		app.log.Warn("payment webhook is disabled: Webhook.Secret is not set")
+	}
 
-				errCh <- err
+	if err := app.components.Start(ctx); err != nil {
+		return err
+	}
 
-				return
-			}
+	if err := app.health.Start(ctx); err != nil {
+		return err
+	}
+	app.log.Info("application started")
+	return nil
+}
 
-			log.Printf("%v started", c.Name)
+func (app *App) Stop(ctx context.Context) error {
+	app.log.Info("shutting down service")
+	if app.health != nil {
+		if err := app.health.Stop(ctx); err != nil {
+			app.log.Warn("health drain interrupted", logger.Err(err))
 		}
-		okCh <- struct{}{}
-	}()
+	}
 
-	select {
-	case <-ctx.Done():
-		return fmt.Errorf("timeout gg")
-	case err := <-errCh:
-		return err
-	case <-okCh:
-		log.Printf("Application started!")
+	if app.components == nil {
 		return nil
 	}
+	if err := app.components.Stop(ctx); err != nil {
+		return err
+	}
+
+	app.log.Info("application stopped")
+	return nil
 }
 
-func (app *App) Stop(ctx context.Context) error {
-	log.Println("shutting down service...")
-	okCh, errCh := make(chan struct{}), make(chan error)
+// postgresStorage adds the Postgres and Redis components of UserRepository.
+func (app *App) postgresStorage() (repository.Interface, payment.PaymentProvider, error) {
+	db := postgres.NewDB(app.cfg.Postgres, app.log)
+	redisClient := redis.NewRedisDB(app.cfg.Redis)
+	userRepo := repository.NewUserRepository(app.cfg.Repository, db, redisClient, app.log)
+	productInvalidator := repository.NewProductInvalidator(db, userRepo)
+	app.watcher.Subscribe(func(cfg config.Config) {
+		userRepo.Reload(cfg.Repository)
+	})
+	redisMonitor := repository.NewRedisMonitor(app.cfg.Repository, userRepo, redisClient)
+	paymentProvider, err := newPaymentProvider(app.cfg.Payment, func() payment.PaymentProvider {
+		return repository.NewBalanceProvider(db)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-	go func() {
-		for i := len(app.cmps) - 1; i >= 0; i-- {
-			c := app.cmps[i]
-			log.Println("stopping %q...", c.Name)
+	// Carts fall back to Postgres without Redis and events are best effort,
+	// so only Postgres decides whether the service is ready.
+	app.health.Register("postgres", db, true)
+	app.health.Register("redis", redisClient, false)
 
-			if err := c.Service.Stop(ctx); err != nil {
-				log.Println(err)
-				errCh <- err
+	app.components.Add("postgres", db)
+	app.components.Add("redisClient", redisClient)
+	app.components.Add("productInvalidator", productInvalidator, "postgres", "redisClient")
+	app.components.Add("redisMonitor", redisMonitor, "postgres", "redisClient")
 
-				return
-			}
+	return userRepo, paymentProvider, nil
+}
+
+// memoryStorage creates a MemoryRepository seeded from
+// Repository.MemoryDataFile, or with demo data.
+func (app *App) memoryStorage() (repository.Interface, payment.PaymentProvider, error) {
+	data := repository.DemoMemoryData()
+	if path := app.cfg.Repository.MemoryDataFile; path != "" {
+		var err error
+		if data, err = repository.LoadMemoryData(path); err != nil {
+			return nil, nil, err
 		}
+	}
+
+	userRepo := repository.NewMemoryRepository(app.cfg.Repository, data, app.log)
+	app.watcher.Subscribe(func(cfg config.Config) {
+		userRepo.Reload(cfg.Repository)
+	})
+	paymentProvider, err := newPaymentProvider(app.cfg.Payment, func() payment.PaymentProvider {
+		return repository.NewMemoryBalanceProvider(userRepo)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-		okCh <- struct{}{}
-	}()
+	app.log.Warn("using the memory storage, data is lost on restart",
+		slog.Int("clients", len(data.Clients)), slog.Int("products", len(data.Products)))
+	return userRepo, paymentProvider, nil
+}
 
-	select {
-	case <-ctx.Done():
-		return fmt.Errorf("timeout gg")
-	case err := <-errCh:
-		return err
-	case <-okCh:
-		log.Println("Application stopped!")
-		return nil
+// newPaymentProvider returns the configured provider; balance is the one of
+// the storage in use.
+func newPaymentProvider(cfg payment.Config, balance func() payment.PaymentProvider) (payment.PaymentProvider, error) {
+	switch cfg.Provider {
+	case "", payment.ProviderBalance:
+		return balance(), nil
+	case payment.ProviderFake:
+		return payment.NewFakeGateway(cfg.Fake)
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.Provider)
 	}
 }
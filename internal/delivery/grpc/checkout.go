@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/usecase"
+	pb "github.com/Dmitrij-bot/marketserv/proto"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+func (s *UserService) CreateCheckout(ctx context.Context, req *pb.CreateCheckoutRequest) (*pb.CreateCheckoutResponse, error) {
+	s.log.DebugContext(ctx, "CreateCheckout received", slog.Int("user_id", int(req.UserId)), slog.Int("address_id", int(req.AddressId)), slog.String("shipping_option", req.ShippingOption))
+	checkoutResp, err := s.useCase.CreateCheckout(ctx, usecase.CreateCheckoutRequest{
+		ClientId:          req.UserId,
+		AddressID:         req.AddressId,
+		ShippingOption:    req.ShippingOption,
+		AcknowledgedTotal: req.AcknowledgedTotal,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkout: %w", err)
+	}
+
+	var items []*pb.CartItem
+	for _, item := range checkoutResp.Items {
+		items = append(items, &pb.CartItem{
+			ProductId:    item.ProductID,
+			Quantity:     strconv.Itoa(int(item.ProductQuantity)),
+			Price:        strconv.FormatFloat(item.ProductPrice, 'f', 2, 64),
+			CurrentPrice: strconv.FormatFloat(item.CurrentPrice, 'f', 2, 64),
+		})
+	}
+
+	var discounts []*pb.DiscountLine
+	for _, d := range checkoutResp.Discounts {
+		discounts = append(discounts, toDiscountLine(d))
+	}
+
+	return &pb.CreateCheckoutResponse{
+		CheckoutId:    checkoutResp.CheckoutID,
+		Items:         items,
+		Discounts:     discounts,
+		ItemsTotal:    checkoutResp.ItemsTotal,
+		DiscountTotal: checkoutResp.DiscountTotal,
+		TaxTotal:      checkoutResp.TaxTotal,
+		ShippingCost:  checkoutResp.ShippingCost,
+		Total:         checkoutResp.Total,
+		ExpiresAt:     checkoutResp.ExpiresAt.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func (s *UserService) ConfirmCheckout(ctx context.Context, req *pb.ConfirmCheckoutRequest) (*pb.ConfirmCheckoutResponse, error) {
+	s.log.DebugContext(ctx, "ConfirmCheckout received", slog.Int("user_id", int(req.UserId)), slog.String("checkout_id", req.CheckoutId))
+	confirmResp, err := s.useCase.ConfirmCheckout(ctx, usecase.ConfirmCheckoutRequest{
+		ClientId:   req.UserId,
+		CheckoutID: req.CheckoutId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm checkout: %w", err)
+	}
+
+	message := fmt.Sprintf("Order %d placed successfully", confirmResp.OrderID)
+	if confirmResp.Status == usecase.OrderStatusPending {
+		message = fmt.Sprintf("Order %d placed, payment is awaiting confirmation", confirmResp.OrderID)
+	}
+
+	return &pb.ConfirmCheckoutResponse{
+		OrderId:      confirmResp.OrderID,
+		ChargedTotal: confirmResp.ChargedTotal,
+		Message:      message,
+		Status:       confirmResp.Status,
+	}, nil
+}
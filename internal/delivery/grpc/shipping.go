@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/usecase"
+	pb "github.com/Dmitrij-bot/marketserv/proto"
+	"log/slog"
+	"strconv"
+)
+
+func (s *UserService) AddAddress(ctx context.Context, req *pb.AddAddressRequest) (*pb.AddAddressResponse, error) {
+	s.log.DebugContext(ctx, "AddAddress received", slog.Int("user_id", int(req.UserId)))
+	addResp, err := s.useCase.AddAddress(ctx, usecase.AddAddressRequest{
+		Address: usecase.Address{
+			ClientId:   req.UserId,
+			Recipient:  req.Address.Recipient,
+			Line1:      req.Address.Line1,
+			Line2:      req.Address.Line2,
+			City:       req.Address.City,
+			PostalCode: req.Address.PostalCode,
+			Country:    req.Address.Country,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add address: %w", err)
+	}
+
+	return &pb.AddAddressResponse{Address: toPbAddress(addResp.Address)}, nil
+}
+
+func (s *UserService) ListAddresses(ctx context.Context, req *pb.ListAddressesRequest) (*pb.ListAddressesResponse, error) {
+	listResp, err := s.useCase.ListAddresses(ctx, usecase.ListAddressesRequest{ClientId: req.UserId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+
+	var addresses []*pb.Address
+	for _, a := range listResp.Addresses {
+		addresses = append(addresses, toPbAddress(a))
+	}
+
+	return &pb.ListAddressesResponse{Addresses: addresses}, nil
+}
+
+func (s *UserService) DeleteAddress(ctx context.Context, req *pb.DeleteAddressRequest) (*pb.DeleteAddressResponse, error) {
+	s.log.DebugContext(ctx, "DeleteAddress received", slog.Int("user_id", int(req.UserId)), slog.Int("address_id", int(req.AddressId)))
+	_, err := s.useCase.DeleteAddress(ctx, usecase.DeleteAddressRequest{
+		ClientId:  req.UserId,
+		AddressID: req.AddressId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete address: %w", err)
+	}
+
+	return &pb.DeleteAddressResponse{
+		Message: fmt.Sprintf("Address %d deleted successfully", req.AddressId),
+	}, nil
+}
+
+func (s *UserService) GetShippingOptions(ctx context.Context, req *pb.GetShippingOptionsRequest) (*pb.GetShippingOptionsResponse, error) {
+	optionsResp, err := s.useCase.GetShippingOptions(ctx, usecase.GetShippingOptionsRequest{
+		ClientId:  req.UserId,
+		AddressID: req.AddressId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipping options: %w", err)
+	}
+
+	var options []*pb.ShippingOption
+	for _, o := range optionsResp.Options {
+		options = append(options, &pb.ShippingOption{
+			Code:         o.Code,
+			Name:         o.Name,
+			Cost:         strconv.FormatFloat(o.Cost, 'f', 2, 64),
+			DeliveryDays: int32(o.DeliveryDays),
+		})
+	}
+
+	return &pb.GetShippingOptionsResponse{Options: options}, nil
+}
+
+func (s *UserService) Checkout(ctx context.Context, req *pb.CheckoutRequest) (*pb.CheckoutResponse, error) {
+	s.log.DebugContext(ctx, "Checkout received", slog.Int("user_id", int(req.UserId)), slog.Int("address_id", int(req.AddressId)), slog.String("shipping_option", req.ShippingOption))
+	checkoutResp, err := s.useCase.Checkout(ctx, usecase.CheckoutRequest{
+		ClientId:          req.UserId,
+		AddressID:         req.AddressId,
+		ShippingOption:    req.ShippingOption,
+		AcknowledgedTotal: req.AcknowledgedTotal,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkout: %w", err)
+	}
+
+	return &pb.CheckoutResponse{
+		OrderId:      checkoutResp.OrderID,
+		ChargedTotal: checkoutResp.ChargedTotal,
+		Message:      fmt.Sprintf("Order %d placed successfully", checkoutResp.OrderID),
+	}, nil
+}
+
+func toPbAddress(a usecase.Address) *pb.Address {
+	return &pb.Address{
+		Id:         a.AddressID,
+		Recipient:  a.Recipient,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+	}
+}
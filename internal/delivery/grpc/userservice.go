@@ -3,38 +3,38 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
 	"github.com/Dmitrij-bot/marketserv/internal/usecase"
 	pb "github.com/Dmitrij-bot/marketserv/proto"
-	"log"
+	"log/slog"
 	"strconv"
 )
 
 type UserService struct {
 	useCase *usecase.UserUseCase
+	log     *slog.Logger
 	pb.UnimplementedUserServiceServer
 }
 
-func NewUserService(u *usecase.UserUseCase) *UserService {
+func NewUserService(u *usecase.UserUseCase, log *slog.Logger) *UserService {
 	return &UserService{
 		useCase: u,
+		log:     log,
 	}
 }
 
 func (s *UserService) FindClientByUsername(ctx context.Context, req *pb.FindClientByUsernameRequest) (*pb.FindClientByUsernameResponse, error) {
 
-	log.Printf("Received FindClientByUsername request: %v", req)
-
+	// The validation interceptor guarantees a positive decimal id.
 	id, err := strconv.Atoi(req.Id)
 	if err != nil {
-		log.Printf("Error converting ID to int: %v", err)
-		return nil, err
+		return nil, domain.InvalidArgument("id", "invalid client id %q", req.Id)
 	}
 
 	userResp, err := s.useCase.FindClientByUsername(ctx, usecase.FindClientByUsernameRequest{
 		ClientID: id,
 	})
 	if err != nil {
-		log.Printf("Error finding user: %v", err)
 		return nil, err
 	}
 
@@ -44,23 +44,16 @@ func (s *UserService) FindClientByUsername(ctx context.Context, req *pb.FindClie
 		Role:     userResp.Role,
 	}
 
-	log.Printf("User found: %v", resp)
-
 	return resp, nil
 }
 
 func (s *UserService) SearchProductByName(ctx context.Context, req *pb.SearchProductByNameRequest) (*pb.SearchProductByNameResponse, error) {
-	log.Printf("Received SearchProductByName request: %v", req)
-
-	if req.Name == "" {
-		return nil, fmt.Errorf("product name cannot be empty")
-	}
+	s.log.DebugContext(ctx, "SearchProductByName received", slog.String("name", req.Name))
 
 	productResp, err := s.useCase.SearchProductByName(ctx, usecase.SearchProductByNameRequest{
 		ProductName: req.Name,
 	})
 	if err != nil {
-		log.Printf("Error finding name: %v", err)
 		return nil, err
 	}
 	var products []*pb.Product
@@ -77,17 +70,11 @@ func (s *UserService) SearchProductByName(ctx context.Context, req *pb.SearchPro
 		Products: products,
 	}
 
-	log.Printf("Product found: %v", resp)
-
 	return resp, nil
 }
 
 func (s *UserService) AddItemToCart(ctx context.Context, req *pb.AddToCartRequest) (*pb.AddToCartResponse, error) {
-	log.Printf("Received AddItemToCart request: user_id: %d, product_id: %d, quantity: %d", req.UserId, req.ProductId, req.Quantity)
-
-	if req.UserId == 0 || req.ProductId == 0 || req.Quantity == 0 {
-		return nil, fmt.Errorf("invalid input: userId, productId, and quantity must be greater than zero")
-	}
+	s.log.DebugContext(ctx, "AddItemToCart received", slog.Int("user_id", int(req.UserId)), slog.Int("product_id", int(req.ProductId)), slog.Int("quantity", int(req.Quantity)))
 
 	_, err := s.useCase.AddItemToCart(
 		ctx,
@@ -98,7 +85,6 @@ func (s *UserService) AddItemToCart(ctx context.Context, req *pb.AddToCartReques
 		})
 
 	if err != nil {
-		log.Printf("Error adding item to cart: %v", err)
 		return nil, fmt.Errorf("failed to add item to cart: %w", err)
 	}
 
@@ -111,11 +97,7 @@ func (s *UserService) AddItemToCart(ctx context.Context, req *pb.AddToCartReques
 }
 
 func (s *UserService) DeleteItemFromCart(ctx context.Context, req *pb.DeleteFromCartRequest) (*pb.DeleteFromCartResponse, error) {
-	log.Printf("Receved DeleteItemFromCart : user_id: %d, product_id: %d", req.UserId, req.ProductId)
-	if req.UserId == 0 || req.ProductId == 0 {
-		return nil, fmt.Errorf("invalid input: userId, productId must be greater than zero")
-	}
-
+	s.log.DebugContext(ctx, "DeleteItemFromCart received", slog.Int("user_id", int(req.UserId)), slog.Int("product_id", int(req.ProductId)))
 	_, err := s.useCase.DeleteItemFromCart(
 		ctx,
 		usecase.DeleteItemFromCartRequest{
@@ -124,7 +106,6 @@ func (s *UserService) DeleteItemFromCart(ctx context.Context, req *pb.DeleteFrom
 		})
 
 	if err != nil {
-		log.Printf("Error delete item from cart: %v", err)
 		return nil, fmt.Errorf("failed to delete item from cart: %w", err)
 	}
 	resp := &pb.DeleteFromCartResponse{
@@ -139,34 +120,46 @@ func (s *UserService) GetCart(ctx context.Context, req *pb.GetCartRequest) (*pb.
 		ClientId: req.UserId,
 	})
 	if err != nil {
-		log.Printf("failed to get cart for user_id %d: %v", req.UserId, err)
 		return nil, fmt.Errorf("failed to get cart for user_id %d: %v", req.UserId, err)
 	}
-	log.Printf("Usecase returned cart: Items - %v, TotalPrice - %s", cartResp.CartItems, cartResp.TotalPrice)
 	var cartItems []*pb.CartItem
 	for _, item := range cartResp.CartItems {
 		cartItems = append(cartItems, &pb.CartItem{
-			ProductId: item.ProductID,
-			Quantity:  strconv.Itoa(int(item.ProductQuantity)),
-			Price:     strconv.FormatFloat(item.ProductPrice, 'f', 2, 64),
+			ProductId:    item.ProductID,
+			Quantity:     strconv.Itoa(int(item.ProductQuantity)),
+			Price:        strconv.FormatFloat(item.ProductPrice, 'f', 2, 64),
+			CurrentPrice: strconv.FormatFloat(item.CurrentPrice, 'f', 2, 64),
+			PriceChanged: item.PriceChanged,
 		})
 	}
 
-	log.Printf("Returning response: Items - %v, TotalPrice - %s", cartItems, cartResp.TotalPrice)
+	var discounts []*pb.DiscountLine
+	for _, d := range cartResp.Discounts {
+		discounts = append(discounts, toDiscountLine(d))
+	}
 
 	resp := &pb.GetCartResponse{
-		Items:      cartItems,
-		TotalPrice: cartResp.TotalPrice,
+		Items:             cartItems,
+		TotalPrice:        cartResp.TotalPrice,
+		PricesChanged:     cartResp.PricesChanged,
+		CurrentTotalPrice: cartResp.CurrentTotalPrice,
+		PromoCode:         cartResp.PromoCode,
+		Discounts:         discounts,
+		DiscountTotal:     cartResp.DiscountTotal,
+		GrandTotal:        cartResp.GrandTotal,
+		Subtotal:          cartResp.Subtotal,
+		TaxTotal:          cartResp.TaxTotal,
 	}
 	return resp, nil
 }
 
 func (s *UserService) SimulatePayment(ctx context.Context, req *pb.PaymentRequest) (*pb.PaymentResponse, error) {
 
-	_, err := s.useCase.SimulatePayment(
+	paymentResp, err := s.useCase.SimulatePayment(
 		ctx,
 		usecase.PaymentRequest{
-			ClientId: req.UserId,
+			ClientId:          req.UserId,
+			AcknowledgedTotal: req.AcknowledgedTotal,
 		})
 
 	if err != nil {
@@ -174,9 +167,147 @@ func (s *UserService) SimulatePayment(ctx context.Context, req *pb.PaymentReques
 	}
 
 	resp := &pb.PaymentResponse{
-		Success: true,
-		Message: "Оплата успешно выполнена.",
+		Success:      true,
+		Message:      "Оплата успешно выполнена.",
+		OrderId:      paymentResp.OrderID,
+		ChargedTotal: paymentResp.ChargedTotal,
 	}
 
 	return resp, nil
 }
+
+func (s *UserService) AddItemToGuestCart(ctx context.Context, req *pb.AddToGuestCartRequest) (*pb.AddToGuestCartResponse, error) {
+	s.log.DebugContext(ctx, "AddItemToGuestCart received", slog.Int("product_id", int(req.ProductId)), slog.Int("quantity", int(req.Quantity)))
+	addResp, err := s.useCase.AddItemToGuestCart(
+		ctx,
+		usecase.AddItemToGuestCartRequest{
+			SessionToken: req.SessionToken,
+			ProductID:    req.ProductId,
+			Quantity:     req.Quantity,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add item to guest cart: %w", err)
+	}
+
+	resp := &pb.AddToGuestCartResponse{
+		SessionToken: addResp.SessionToken,
+		Message:      fmt.Sprintf("Item with product ID %d added to guest cart successfully", req.ProductId),
+	}
+
+	return resp, nil
+}
+
+func (s *UserService) DeleteItemFromGuestCart(ctx context.Context, req *pb.DeleteFromGuestCartRequest) (*pb.DeleteFromCartResponse, error) {
+	s.log.DebugContext(ctx, "DeleteItemFromGuestCart received", slog.Int("product_id", int(req.ProductId)))
+	_, err := s.useCase.DeleteItemFromGuestCart(
+		ctx,
+		usecase.DeleteItemFromGuestCartRequest{
+			SessionToken: req.SessionToken,
+			ProductID:    req.ProductId,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete item from guest cart: %w", err)
+	}
+
+	resp := &pb.DeleteFromCartResponse{
+		Message: fmt.Sprintf("Item with product ID %d delete from guest cart successfully", req.ProductId),
+	}
+	return resp, nil
+}
+
+func (s *UserService) GetGuestCart(ctx context.Context, req *pb.GetGuestCartRequest) (*pb.GetCartResponse, error) {
+
+	cartResp, err := s.useCase.GetGuestCart(ctx, usecase.GetGuestCartRequest{
+		SessionToken: req.SessionToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guest cart: %w", err)
+	}
+
+	var cartItems []*pb.CartItem
+	for _, item := range cartResp.CartItems {
+		cartItems = append(cartItems, &pb.CartItem{
+			ProductId: item.ProductID,
+			Quantity:  strconv.Itoa(int(item.ProductQuantity)),
+			Price:     strconv.FormatFloat(item.ProductPrice, 'f', 2, 64),
+		})
+	}
+
+	resp := &pb.GetCartResponse{
+		Items:      cartItems,
+		TotalPrice: cartResp.TotalPrice,
+	}
+	return resp, nil
+}
+
+func (s *UserService) MergeCart(ctx context.Context, req *pb.MergeCartRequest) (*pb.MergeCartResponse, error) {
+	s.log.DebugContext(ctx, "MergeCart received", slog.Int("user_id", int(req.UserId)))
+	mergeResp, err := s.useCase.MergeCart(
+		ctx,
+		usecase.MergeCartRequest{
+			SessionToken: req.SessionToken,
+			ClientId:     req.UserId,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge cart: %w", err)
+	}
+
+	var items []*pb.MergedItem
+	for _, item := range mergeResp.Items {
+		items = append(items, &pb.MergedItem{
+			ProductId:         item.ProductID,
+			RequestedQuantity: item.RequestedQuantity,
+			MergedQuantity:    item.MergedQuantity,
+		})
+	}
+
+	resp := &pb.MergeCartResponse{
+		Items:   items,
+		Message: fmt.Sprintf("Guest cart merged into cart of user %d", req.UserId),
+	}
+	return resp, nil
+}
+
+func (s *UserService) ApplyPromoCode(ctx context.Context, req *pb.ApplyPromoCodeRequest) (*pb.ApplyPromoCodeResponse, error) {
+	s.log.DebugContext(ctx, "ApplyPromoCode received", slog.Int("user_id", int(req.UserId)), slog.String("code", req.Code))
+	applyResp, err := s.useCase.ApplyPromoCode(
+		ctx,
+		usecase.ApplyPromoCodeRequest{
+			ClientId: req.UserId,
+			Code:     req.Code,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply promo code: %w", err)
+	}
+
+	resp := &pb.ApplyPromoCodeResponse{
+		Discount: toDiscountLine(applyResp.Discount),
+		Message:  fmt.Sprintf("Promo code %s applied to cart", applyResp.Discount.Code),
+	}
+	return resp, nil
+}
+
+func (s *UserService) RemovePromoCode(ctx context.Context, req *pb.RemovePromoCodeRequest) (*pb.RemovePromoCodeResponse, error) {
+	s.log.DebugContext(ctx, "RemovePromoCode received", slog.Int("user_id", int(req.UserId)))
+	_, err := s.useCase.RemovePromoCode(
+		ctx,
+		usecase.RemovePromoCodeRequest{
+			ClientId: req.UserId,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove promo code: %w", err)
+	}
+
+	resp := &pb.RemovePromoCodeResponse{
+		Message: "Promo code removed from cart",
+	}
+	return resp, nil
+}
+
+func toDiscountLine(d usecase.DiscountLine) *pb.DiscountLine {
+	return &pb.DiscountLine{
+		Code:        d.Code,
+		Description: d.Description,
+		Amount:      strconv.FormatFloat(d.Amount, 'f', 2, 64),
+	}
+}
@@ -0,0 +1,72 @@
+// Package domain holds the error kinds shared by the repository, usecase and
+// delivery layers. Each domain error matches exactly one kind with errors.Is
+// and carries a machine readable reason for clients.
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds.
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrOutOfStock        = errors.New("out of stock")
+	ErrInvalidArgument   = errors.New("invalid argument")
+	ErrConflict          = errors.New("conflict")
+)
+
+type Error struct {
+	Kind    error  // one of the kinds above
+	Reason  string // upper snake case, e.g. PRICE_CHANGED
+	Field   string // offending request field of an ErrInvalidArgument
+	Message string
+	Err     error // optional cause
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+func newError(kind error, reason, field, format string, args ...any) *Error {
+	return &Error{Kind: kind, Reason: reason, Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func NotFound(reason, format string, args ...any) *Error {
+	return newError(ErrNotFound, reason, "", format, args...)
+}
+
+func InsufficientFunds(format string, args ...any) *Error {
+	return newError(ErrInsufficientFunds, "INSUFFICIENT_FUNDS", "", format, args...)
+}
+
+func OutOfStock(format string, args ...any) *Error {
+	return newError(ErrOutOfStock, "OUT_OF_STOCK", "", format, args...)
+}
+
+// InvalidArgument reports a bad value of the request field.
+func InvalidArgument(field, format string, args ...any) *Error {
+	return newError(ErrInvalidArgument, "INVALID_ARGUMENT", field, format, args...)
+}
+
+func Conflict(reason, format string, args ...any) *Error {
+	return newError(ErrConflict, reason, "", format, args...)
+}
+
+// As returns the outermost domain error in err's chain.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
@@ -1,5 +1,13 @@
 package grpc
 
 type Config struct {
-	Host string
+	Host                  string
+	RequestTimeoutSeconds int // upper bound of a request deadline, 0 keeps the client one
+	Health                HealthConfig
+}
+
+type HealthConfig struct {
+	IntervalSeconds int // how often dependencies are checked, 5 by default
+	TimeoutSeconds  int // timeout of a single check, 2 by default
+	DrainSeconds    int // how long NOT_SERVING is reported before shutdown proceeds
 }
@@ -0,0 +1,22 @@
+package grpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// deadlineInterceptor caps the deadline of every request at timeout. The
+// handler context is what repository queries run under, so a request without
+// a deadline, or with a very long one, cannot hold a database connection
+// for longer than timeout. A non-positive timeout keeps the client deadline.
+func deadlineInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,87 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+)
+
+// ErrorDomain names the service in errdetails.ErrorInfo.
+const ErrorDomain = "marketserv"
+
+// errorInterceptor turns domain errors returned by handlers into gRPC statuses
+// with a matching code and error details. Any other error becomes Internal;
+// its message is logged here and not sent to the client.
+func errorInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+
+		st := toStatus(err)
+		if st.Code() == codes.Internal {
+			log.ErrorContext(ctx, "internal error", slog.String("method", info.FullMethod), logger.Err(err))
+		}
+		return resp, st.Err()
+	}
+}
+
+func toStatus(err error) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, err.Error())
+	}
+
+	de, ok := domain.As(err)
+	if !ok {
+		return status.New(codes.Internal, "internal error")
+	}
+
+	info := &errdetails.ErrorInfo{Reason: de.Reason, Domain: ErrorDomain}
+	st := status.New(codeOf(de.Kind), err.Error())
+
+	var withDetails *status.Status
+	switch de.Kind {
+	case domain.ErrInvalidArgument:
+		withDetails, err = st.WithDetails(info, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: de.Field, Description: de.Message}},
+		})
+	case domain.ErrInsufficientFunds, domain.ErrOutOfStock, domain.ErrConflict:
+		withDetails, err = st.WithDetails(info, &errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{Type: de.Reason, Description: de.Message}},
+		})
+	default:
+		withDetails, err = st.WithDetails(info)
+	}
+	if err != nil {
+		// Details are best effort, the code and message still go out.
+		return st
+	}
+	return withDetails
+}
+
+func codeOf(kind error) codes.Code {
+	switch kind {
+	case domain.ErrNotFound:
+		return codes.NotFound
+	case domain.ErrInvalidArgument:
+		return codes.InvalidArgument
+	case domain.ErrInsufficientFunds, domain.ErrOutOfStock, domain.ErrConflict:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		code       codes.Code
+		reason     string
+		field      string // of the BadRequest violation
+		condition  string // type of the PreconditionFailure violation
+		message    string // when set, the status message
+		hidesCause bool
+	}{
+		{name: "not found", err: domain.NotFound("PRODUCT_NOT_FOUND", "product 7 not found"), code: codes.NotFound, reason: "PRODUCT_NOT_FOUND"},
+		{name: "invalid argument", err: domain.InvalidArgument("quantity", "quantity must be positive"), code: codes.InvalidArgument, reason: "INVALID_ARGUMENT", field: "quantity"},
+		{name: "insufficient funds", err: domain.InsufficientFunds("balance is too low"), code: codes.FailedPrecondition, reason: "INSUFFICIENT_FUNDS", condition: "INSUFFICIENT_FUNDS"},
+		{name: "out of stock", err: domain.OutOfStock("only 2 left"), code: codes.FailedPrecondition, reason: "OUT_OF_STOCK", condition: "OUT_OF_STOCK"},
+		{name: "conflict", err: domain.Conflict("PRICE_CHANGED", "price changed"), code: codes.FailedPrecondition, reason: "PRICE_CHANGED", condition: "PRICE_CHANGED"},
+		{name: "wrapped domain error", err: fmt.Errorf("failed to add item: %w", domain.OutOfStock("only 2 left")), code: codes.FailedPrecondition, reason: "OUT_OF_STOCK", condition: "OUT_OF_STOCK"},
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), code: codes.DeadlineExceeded, message: "query: context deadline exceeded"},
+		{name: "cancelled", err: context.Canceled, code: codes.Canceled, message: "context canceled"},
+		{name: "status passes through", err: status.Error(codes.Unavailable, "try later"), code: codes.Unavailable, message: "try later"},
+		{name: "unknown error", err: errors.New(`pq: relation "carts" does not exist`), code: codes.Internal, hidesCause: true},
+		{name: "wrapped unknown error", err: fmt.Errorf("failed to get cart: %w", errors.New("dial tcp 10.0.0.5:5432: refused")), code: codes.Internal, hidesCause: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := toStatus(tt.err)
+			if st.Code() != tt.code {
+				t.Errorf("code = %s, want %s", st.Code(), tt.code)
+			}
+			if tt.hidesCause && (st.Message() != "internal error" || len(st.Details()) > 0) {
+				t.Errorf("status = %q with %d details, want the cause hidden", st.Message(), len(st.Details()))
+			}
+			if tt.message != "" && st.Message() != tt.message {
+				t.Errorf("message = %q, want %q", st.Message(), tt.message)
+			}
+
+			var reason, field, condition string
+			for _, d := range st.Details() {
+				switch d := d.(type) {
+				case *errdetails.ErrorInfo:
+					if d.Domain != ErrorDomain {
+						t.Errorf("ErrorInfo.Domain = %q, want %q", d.Domain, ErrorDomain)
+					}
+					reason = d.Reason
+				case *errdetails.BadRequest:
+					field = d.FieldViolations[0].Field
+				case *errdetails.PreconditionFailure:
+					condition = d.Violations[0].Type
+				}
+			}
+			if reason != tt.reason || field != tt.field || condition != tt.condition {
+				t.Errorf("details reason = %q, field = %q, condition = %q, want %q, %q, %q",
+					reason, field, condition, tt.reason, tt.field, tt.condition)
+			}
+		})
+	}
+}
+
+func TestToStatusKeepsDomainMessage(t *testing.T) {
+	st := toStatus(fmt.Errorf("failed to apply promo code: %w", domain.Conflict("PROMO_EXPIRED", "promo code has expired")))
+	if !strings.Contains(st.Message(), "promo code has expired") {
+		t.Errorf("message = %q, want the domain message", st.Message())
+	}
+}
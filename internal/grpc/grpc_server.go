@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	grpc2 "github.com/Dmitrij-bot/marketserv/internal/delivery/grpc"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
 	order "github.com/Dmitrij-bot/marketserv/proto"
-	"log"
+	"log/slog"
 	"net"
+	"time"
 
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -16,12 +19,16 @@ type Server struct {
 	cfg         Config             // Ваша конфигурация
 	grpcServer  *grpc.Server       // Указатель на gRPC сервер
 	userService *grpc2.UserService // Ваш сервис, реализующий методы gRPC
+	health      *Health
+	log         *slog.Logger
 }
 
-func NewGRPCServer(cfg Config, userService *grpc2.UserService) *Server {
+func NewGRPCServer(cfg Config, userService *grpc2.UserService, health *Health, log *slog.Logger) *Server {
 	return &Server{
 		cfg:         cfg,
 		userService: userService,
+		health:      health,
+		log:         log,
 	}
 }
 
@@ -30,24 +37,32 @@ func (s *Server) Start(ctx context.Context) error {
 		return errors.New("server is already running")
 	}
 
-	s.grpcServer = grpc.NewServer()
-
-	order.RegisterUserServiceServer(s.grpcServer, s.userService)
-
-	reflection.Register(s.grpcServer)
-
 	lis, err := net.Listen("tcp", s.cfg.Host)
 	if err != nil {
 		return err
 	}
 
+	s.grpcServer = grpc.NewServer(grpc.ChainUnaryInterceptor(
+		tracingInterceptor,
+		deadlineInterceptor(time.Duration(s.cfg.RequestTimeoutSeconds)*time.Second),
+		loggingInterceptor(s.log),
+		metricsInterceptor,
+		errorInterceptor(s.log),
+		validationInterceptor,
+	))
+
+	order.RegisterUserServiceServer(s.grpcServer, s.userService)
+	healthpb.RegisterHealthServer(s.grpcServer, s.health.server)
+
+	reflection.Register(s.grpcServer)
+
 	go func() {
 		if err := s.grpcServer.Serve(lis); err != nil {
-			log.Printf("failed to serve: %v", err)
+			s.log.Error("failed to serve gRPC", logger.Err(err))
 		}
 	}()
 
-	log.Printf("gRPC server is running on %s", s.cfg.Host)
+	s.log.Info("gRPC server is running", slog.String("addr", s.cfg.Host))
 
 	return nil
 }
@@ -56,6 +71,18 @@ func (s *Server) Stop(ctx context.Context) error {
 	if s.grpcServer == nil {
 		return errors.New("server is not running")
 	}
-	s.grpcServer.GracefulStop()
+
+	// Health watch streams never end on their own, so graceful stop is
+	// bounded by ctx.
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+	}
 	return nil
 }
@@ -0,0 +1,168 @@
+package grpc
+
+import (
+	"context"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"github.com/Dmitrij-bot/marketserv/pkg/lyfecycle"
+	order "github.com/Dmitrij-bot/marketserv/proto"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type healthCheck struct {
+	name     string
+	checker  lyfecycle.HealthChecker
+	critical bool
+	ok       bool
+}
+
+// Health serves grpc.health.v1. Every registered dependency is reported under
+// its own service name. The overall status, reported for "" and the
+// UserService, is SERVING only between SetReady and Stop and while all
+// critical dependencies are healthy.
+type Health struct {
+	cfg    HealthConfig
+	server *health.Server
+	log    *slog.Logger
+	mu     sync.Mutex
+	checks []*healthCheck
+	ready  bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewHealth(cfg HealthConfig, log *slog.Logger) *Health {
+	h := &Health{
+		cfg:    cfg,
+		server: health.NewServer(),
+		log:    log,
+	}
+	h.setOverall(healthpb.HealthCheckResponse_NOT_SERVING)
+	return h
+}
+
+// Register adds a dependency check. A failing critical dependency makes the
+// whole service NOT_SERVING, other failures are only reported for the
+// dependency itself.
+func (h *Health) Register(name string, checker lyfecycle.HealthChecker, critical bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, &healthCheck{name: name, checker: checker, critical: critical, ok: true})
+	h.server.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+func (h *Health) interval() time.Duration {
+	if h.cfg.IntervalSeconds <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(h.cfg.IntervalSeconds) * time.Second
+}
+
+func (h *Health) timeout() time.Duration {
+	if h.cfg.TimeoutSeconds <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(h.cfg.TimeoutSeconds) * time.Second
+}
+
+// Start checks the dependencies once, marks the service ready and keeps
+// checking them in the background. It is called after every component has
+// started.
+func (h *Health) Start(ctx context.Context) error {
+	h.mu.Lock()
+	h.ready = true
+	h.mu.Unlock()
+	h.check(ctx)
+
+	h.done = make(chan struct{})
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ticker := time.NewTicker(h.interval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.done:
+				return
+			case <-ticker.C:
+				h.check(context.Background())
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (h *Health) check(ctx context.Context) {
+	h.mu.Lock()
+	checks := h.checks
+	h.mu.Unlock()
+
+	serving := true
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, h.timeout())
+		err := c.checker.Health(checkCtx)
+		cancel()
+
+		if err != nil && c.ok {
+			h.log.Warn("dependency is unhealthy", slog.String("dependency", c.name), logger.Err(err))
+		} else if err == nil && !c.ok {
+			h.log.Info("dependency is healthy again", slog.String("dependency", c.name))
+		}
+		c.ok = err == nil
+
+		status := healthpb.HealthCheckResponse_SERVING
+		if err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			if c.critical {
+				serving = false
+			}
+		}
+		h.server.SetServingStatus(c.name, status)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.ready {
+		return
+	}
+	if serving {
+		h.setOverall(healthpb.HealthCheckResponse_SERVING)
+	} else {
+		h.setOverall(healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+func (h *Health) setOverall(status healthpb.HealthCheckResponse_ServingStatus) {
+	h.server.SetServingStatus("", status)
+	h.server.SetServingStatus(order.UserService_ServiceDesc.ServiceName, status)
+}
+
+// Stop reports NOT_SERVING for everything and waits DrainSeconds so that load
+// balancers stop routing new requests before the components shut down.
+func (h *Health) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	h.ready = false
+	h.mu.Unlock()
+	h.server.Shutdown()
+
+	if h.done != nil {
+		close(h.done)
+		h.wg.Wait()
+		h.done = nil
+	}
+
+	if h.cfg.DrainSeconds > 0 {
+		h.log.Info("draining before shutdown", slog.Int("seconds", h.cfg.DrainSeconds))
+		select {
+		case <-time.After(time.Duration(h.cfg.DrainSeconds) * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"context"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"time"
+)
+
+// RequestIDHeader is the metadata key carrying the request id. A caller may
+// set it to correlate its own logs; otherwise one is generated. It is echoed
+// back in the response header either way.
+const RequestIDHeader = "x-request-id"
+
+const maxRequestIDLen = 128
+
+// loggingInterceptor attaches the request id to the context, so that every
+// record logged with it carries the id, and logs the outcome of the request.
+func loggingInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		id := metadataCarrier(md).Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLen {
+			id = logger.NewRequestID()
+		}
+		ctx = logger.WithRequestID(ctx, id)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		attrs := []any{
+			slog.String("method", info.FullMethod),
+			slog.String("code", code.String()),
+			slog.Duration("duration", time.Since(start)),
+		}
+		switch code {
+		case codes.OK:
+			log.InfoContext(ctx, "request handled", attrs...)
+		case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss:
+			log.ErrorContext(ctx, "request failed", append(attrs, logger.Err(err))...)
+		default:
+			log.WarnContext(ctx, "request rejected", append(attrs, logger.Err(err))...)
+		}
+
+		return resp, err
+	}
+}
@@ -0,0 +1,35 @@
+package grpc
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "marketserv_grpc_requests_total",
+		Help: "Handled gRPC requests by method and status code.",
+	}, []string{"method", "code"})
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "marketserv_grpc_request_duration_seconds",
+		Help:    "Latency of handled gRPC requests by method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+)
+
+// metricsInterceptor must run ahead of the error and validation interceptors
+// so it sees the final status code they produce.
+func metricsInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	code := status.Code(err).String()
+	requestsTotal.WithLabelValues(info.FullMethod, code).Inc()
+	requestDuration.WithLabelValues(info.FullMethod, code).Observe(time.Since(start).Seconds())
+
+	return resp, err
+}
@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	order "github.com/Dmitrij-bot/marketserv/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// validationInterceptor enforces the (validate.rules) field options declared
+// in proto/order.proto on every request.
+func validationInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if msg, ok := req.(proto.Message); ok {
+		if violations := validate(msg.ProtoReflect(), ""); len(violations) > 0 {
+			return nil, invalidArgument(violations)
+		}
+	}
+	return handler(ctx, req)
+}
+
+func invalidArgument(violations []*errdetails.BadRequest_FieldViolation) error {
+	descriptions := make([]string, 0, len(violations))
+	for _, v := range violations {
+		descriptions = append(descriptions, v.Field+": "+v.Description)
+	}
+
+	st := status.New(codes.InvalidArgument, "invalid request: "+strings.Join(descriptions, "; "))
+	withDetails, err := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: "INVALID_ARGUMENT", Domain: ErrorDomain},
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// validate checks msg and its nested messages and returns one violation per
+// broken rule. prefix is the path of msg inside the request.
+func validate(msg protoreflect.Message, prefix string) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+
+		if rules, ok := proto.GetExtension(fd.Options(), order.E_Rules).(*order.FieldRules); ok && rules != nil {
+			for _, problem := range checkField(msg, fd, rules) {
+				violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: path, Description: problem})
+			}
+		}
+
+		if fd.Kind() != protoreflect.MessageKind || !msg.Has(fd) || fd.IsMap() {
+			continue
+		}
+		if fd.IsList() {
+			list := msg.Get(fd).List()
+			for j := 0; j < list.Len(); j++ {
+				violations = append(violations, validate(list.Get(j).Message(), fmt.Sprintf("%s[%d].", path, j))...)
+			}
+			continue
+		}
+		violations = append(violations, validate(msg.Get(fd).Message(), path+".")...)
+	}
+
+	return violations
+}
+
+func checkField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, rules *order.FieldRules) []string {
+	if fd.IsList() || fd.IsMap() {
+		return nil
+	}
+
+	var problems []string
+	if rules.Required && !msg.Has(fd) {
+		return []string{"is required"}
+	}
+
+	v := msg.Get(fd)
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		problems = checkInt(v.Int(), rules)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		problems = checkInt(int64(v.Uint()), rules)
+	case protoreflect.StringKind:
+		if s := v.String(); s != "" {
+			problems = checkString(s, rules)
+		}
+	}
+	return problems
+}
+
+func checkInt(n int64, rules *order.FieldRules) []string {
+	var problems []string
+	if rules.Gt != nil && n <= *rules.Gt {
+		problems = append(problems, fmt.Sprintf("must be greater than %d", *rules.Gt))
+	}
+	if rules.Gte != nil && n < *rules.Gte {
+		problems = append(problems, fmt.Sprintf("must be at least %d", *rules.Gte))
+	}
+	if rules.Lt != nil && n >= *rules.Lt {
+		problems = append(problems, fmt.Sprintf("must be less than %d", *rules.Lt))
+	}
+	if rules.Lte != nil && n > *rules.Lte {
+		problems = append(problems, fmt.Sprintf("must be at most %d", *rules.Lte))
+	}
+	return problems
+}
+
+func checkString(s string, rules *order.FieldRules) []string {
+	var problems []string
+
+	n := uint32(utf8.RuneCountInString(s))
+	if rules.MinLen != nil && n < *rules.MinLen {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", *rules.MinLen))
+	}
+	if rules.MaxLen != nil && n > *rules.MaxLen {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters", *rules.MaxLen))
+	}
+	if rules.Pattern != "" {
+		re, err := compilePattern(rules.Pattern)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("cannot be validated: invalid pattern %q", rules.Pattern))
+		} else if !re.MatchString(s) {
+			problems = append(problems, fmt.Sprintf("must match %s", rules.Pattern))
+		}
+	}
+	if len(rules.In) > 0 && !contains(rules.In, s) {
+		problems = append(problems, fmt.Sprintf("must be one of %s", strings.Join(rules.In, ", ")))
+	}
+	return problems
+}
+
+var patterns sync.Map // pattern -> *regexp.Regexp
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
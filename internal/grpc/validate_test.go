@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	order "github.com/Dmitrij-bot/marketserv/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func violationFields(violations []*errdetails.BadRequest_FieldViolation) []string {
+	fields := make([]string, 0, len(violations))
+	for _, v := range violations {
+		fields = append(fields, v.Field+": "+v.Description)
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	token := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name string
+		req  proto.Message
+		want []string
+	}{
+		{
+			name: "guest add without a token",
+			req:  &order.AddToGuestCartRequest{ProductId: 1, Quantity: 1},
+		},
+		{
+			name: "guest add with a token",
+			req:  &order.AddToGuestCartRequest{SessionToken: token, ProductId: 1, Quantity: 1},
+		},
+		{
+			name: "malformed guest token",
+			req:  &order.AddToGuestCartRequest{SessionToken: "not-a-token", ProductId: 1, Quantity: 1},
+			want: []string{"session_token: must match ^[0-9a-f]{64}$"},
+		},
+		{
+			name: "negative quantity",
+			req:  &order.AddToGuestCartRequest{ProductId: 1, Quantity: -2},
+			want: []string{"quantity: must be greater than 0"},
+		},
+		{
+			name: "missing address",
+			req:  &order.AddAddressRequest{UserId: 1},
+			want: []string{"address: is required"},
+		},
+		{
+			name: "nested address rules",
+			req: &order.AddAddressRequest{UserId: 1, Address: &order.Address{
+				Recipient: "Alice", Line1: "Lenina 1", City: "Minsk", Country: "BLR",
+			}},
+			want: []string{"address.postal_code: is required", "address.country: must match ^[A-Za-z]{2}$"},
+		},
+		{
+			name: "valid address",
+			req: &order.AddAddressRequest{UserId: 1, Address: &order.Address{
+				Recipient: "Alice", Line1: "Lenina 1", City: "Minsk", PostalCode: "220000", Country: "by",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violationFields(validate(tt.req.ProtoReflect(), ""))
+			if !slices.Equal(got, tt.want) && (len(got) > 0 || len(tt.want) > 0) {
+				t.Errorf("validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidArgumentDetails(t *testing.T) {
+	err := invalidArgument(validate((&order.AddToGuestCartRequest{ProductId: 0, Quantity: 1}).ProtoReflect(), ""))
+
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code = %s, want %s", st.Code(), codes.InvalidArgument)
+	}
+	var badRequest *errdetails.BadRequest
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			badRequest = br
+		}
+	}
+	if badRequest == nil || len(badRequest.FieldViolations) != 1 || badRequest.FieldViolations[0].Field != "product_id" {
+		t.Errorf("details = %v, want one product_id violation", st.Details())
+	}
+}
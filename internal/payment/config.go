@@ -0,0 +1,17 @@
+package payment
+
+const (
+	ProviderBalance = "balance"
+	ProviderFake    = "fake"
+)
+
+type Config struct {
+	Provider string // "balance" (default) or "fake"
+	Fake     FakeConfig
+}
+
+type FakeConfig struct {
+	Outcome        string // "succeed" (default), "decline", "timeout" or "capture_decline"
+	TimeoutSeconds int    // how long a timed out authorization hangs
+	AsyncCapture   bool   // captures settle later through the webhook
+}
@@ -0,0 +1,148 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	OutcomeSucceed = "succeed"
+	OutcomeDecline = "decline"
+	OutcomeTimeout = "timeout"
+	// OutcomeCaptureDecline authorizes payments and declines their capture.
+	OutcomeCaptureDecline = "capture_decline"
+)
+
+type fakeAuthorization struct {
+	amount   float64
+	captured float64
+	refunded float64
+	status   string
+}
+
+// FakeGateway is a local card gateway that keeps authorizations in memory.
+// Its outcome is fixed by configuration so failure paths can be exercised
+// without a real provider.
+type FakeGateway struct {
+	cfg FakeConfig
+
+	mu    sync.Mutex
+	auths map[string]*fakeAuthorization
+}
+
+func NewFakeGateway(cfg FakeConfig) (*FakeGateway, error) {
+	switch cfg.Outcome {
+	case "", OutcomeSucceed, OutcomeDecline, OutcomeTimeout, OutcomeCaptureDecline:
+	default:
+		return nil, fmt.Errorf("unknown fake gateway outcome %q", cfg.Outcome)
+	}
+	return &FakeGateway{cfg: cfg, auths: make(map[string]*fakeAuthorization)}, nil
+}
+
+func (g *FakeGateway) Name() string {
+	return ProviderFake
+}
+
+func (g *FakeGateway) timeout() time.Duration {
+	if g.cfg.TimeoutSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(g.cfg.TimeoutSeconds) * time.Second
+}
+
+func (g *FakeGateway) Authorize(ctx context.Context, req AuthorizeRequest) (Authorization, error) {
+	if req.Amount <= 0 {
+		return Authorization{}, fmt.Errorf("invalid amount %.2f", req.Amount)
+	}
+
+	switch g.cfg.Outcome {
+	case OutcomeDecline:
+		return Authorization{}, fmt.Errorf("%w: card declined by fake gateway", ErrDeclined)
+	case OutcomeTimeout:
+		timer := time.NewTimer(g.timeout())
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		return Authorization{}, ErrTimeout
+	}
+
+	id, err := NewAuthorizationID("fake")
+	if err != nil {
+		return Authorization{}, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.auths[id] = &fakeAuthorization{amount: req.Amount, status: StatusAuthorized}
+
+	return Authorization{ID: id, Amount: req.Amount}, nil
+}
+
+func (g *FakeGateway) Capture(ctx context.Context, req CaptureRequest) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	a, ok := g.auths[req.AuthorizationID]
+	if !ok {
+		return ErrAuthorizationNotFound
+	}
+	if a.status != StatusAuthorized {
+		return fmt.Errorf("%w: cannot capture %s authorization", ErrInvalidState, a.status)
+	}
+	if req.Amount <= 0 || req.Amount > a.amount {
+		return fmt.Errorf("invalid capture amount %.2f of %.2f", req.Amount, a.amount)
+	}
+
+	if g.cfg.Outcome == OutcomeCaptureDecline {
+		return fmt.Errorf("%w: capture declined by fake gateway", ErrDeclined)
+	}
+
+	a.captured = req.Amount
+	a.status = StatusCaptured
+	if g.cfg.AsyncCapture {
+		return ErrSettlementPending
+	}
+	return nil
+}
+
+func (g *FakeGateway) Void(ctx context.Context, req VoidRequest) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	a, ok := g.auths[req.AuthorizationID]
+	if !ok {
+		return ErrAuthorizationNotFound
+	}
+	if a.status != StatusAuthorized {
+		return fmt.Errorf("%w: cannot void %s authorization", ErrInvalidState, a.status)
+	}
+
+	a.status = StatusVoided
+	return nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, req RefundRequest) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	a, ok := g.auths[req.AuthorizationID]
+	if !ok {
+		return ErrAuthorizationNotFound
+	}
+	if a.status != StatusCaptured {
+		return fmt.Errorf("%w: cannot refund %s authorization", ErrInvalidState, a.status)
+	}
+	if req.Amount <= 0 || req.Amount > a.captured-a.refunded {
+		return fmt.Errorf("invalid refund amount %.2f of %.2f", req.Amount, a.captured-a.refunded)
+	}
+
+	a.refunded += req.Amount
+	if a.refunded >= a.captured {
+		a.status = StatusRefunded
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+)
+
+var (
+	// ErrDeclined is returned when the provider refuses an authorization.
+	ErrDeclined = domain.Conflict("PAYMENT_DECLINED", "payment declined")
+	// ErrInsufficientFunds is a decline caused by a too low balance.
+	ErrInsufficientFunds = &domain.Error{
+		Kind:    domain.ErrInsufficientFunds,
+		Reason:  "INSUFFICIENT_FUNDS",
+		Message: "insufficient funds",
+		Err:     ErrDeclined,
+	}
+	// ErrTimeout is returned when the provider did not answer in time. The
+	// outcome of the operation is unknown.
+	ErrTimeout = errors.New("payment provider timed out")
+	// ErrSettlementPending is returned by Capture when the provider accepted
+	// the capture and will report the result through a webhook.
+	ErrSettlementPending = errors.New("payment settlement is pending")
+	// ErrAuthorizationNotFound is returned for an unknown authorization.
+	ErrAuthorizationNotFound = domain.NotFound("PAYMENT_AUTHORIZATION_NOT_FOUND", "payment authorization not found")
+	// ErrInvalidState is returned when an operation does not fit the current
+	// state of the authorization, e.g. capturing a voided one.
+	ErrInvalidState = domain.Conflict("PAYMENT_INVALID_STATE", "payment authorization is in an invalid state")
+)
+
+// OutcomeUnknown reports whether err leaves open if the provider carried the
+// operation out: a timeout or a transport failure rather than an answer.
+func OutcomeUnknown(err error) bool {
+	if errors.Is(err, ErrTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+const (
+	StatusAuthorized = "authorized"
+	StatusCaptured   = "captured"
+	StatusVoided     = "voided"
+	StatusRefunded   = "refunded"
+)
+
+// Results reported by providers in settlement webhooks.
+const (
+	SettlementSucceeded = "succeeded"
+	SettlementFailed    = "failed"
+)
+
+type AuthorizeRequest struct {
+	ClientId  int32
+	Amount    float64
+	Reference string // checkout session the payment is for
+}
+
+type Authorization struct {
+	ID     string
+	Amount float64
+}
+
+type CaptureRequest struct {
+	AuthorizationID string
+	Amount          float64 // at most the authorized amount
+}
+
+type VoidRequest struct {
+	AuthorizationID string
+}
+
+type RefundRequest struct {
+	AuthorizationID string
+	Amount          float64 // at most the captured amount not refunded yet
+}
+
+// PaymentProvider moves money for orders. Authorize reserves the amount,
+// Capture takes it, Void releases a reservation that was not captured and
+// Refund returns captured money.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (Authorization, error)
+	Capture(ctx context.Context, req CaptureRequest) error
+	Void(ctx context.Context, req VoidRequest) error
+	Refund(ctx context.Context, req RefundRequest) error
+}
+
+// NewAuthorizationID returns a random authorization ID with the given prefix.
+func NewAuthorizationID(prefix string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate authorization id: %w", err)
+	}
+	return prefix + "_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,127 @@
+package promotion
+
+import (
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"math"
+	"strings"
+	"time"
+)
+
+type RuleType string
+
+const (
+	RulePercentage   RuleType = "percentage"    // Percent off the subtotal
+	RuleFixedAmount  RuleType = "fixed_amount"  // Amount off the subtotal
+	RuleBuyXGetY     RuleType = "buy_x_get_y"   // every BuyQuantity+FreeQuantity units of ProductID, FreeQuantity are free
+	RuleMinimumSpend RuleType = "minimum_spend" // Amount off once the subtotal reaches MinSpend
+)
+
+var (
+	ErrNotStarted    = domain.Conflict("PROMO_NOT_STARTED", "promo code is not active yet")
+	ErrExpired       = domain.Conflict("PROMO_EXPIRED", "promo code has expired")
+	ErrUsageLimit    = domain.Conflict("PROMO_USAGE_LIMIT", "promo code usage limit reached")
+	ErrMinimumSpend  = domain.Conflict("PROMO_MINIMUM_SPEND", "cart total is below the promo code minimum spend")
+	ErrNotApplicable = domain.Conflict("PROMO_NOT_APPLICABLE", "promo code does not apply to the cart")
+)
+
+// Promotion is a discount rule behind a promo code. MinSpend applies to every
+// rule type; zero values of ValidFrom, ValidUntil and UsageLimitPerClient mean
+// no restriction.
+type Promotion struct {
+	Code                string
+	Description         string
+	Type                RuleType
+	Percent             float64
+	Amount              float64
+	ProductID           int32
+	BuyQuantity         int32
+	FreeQuantity        int32
+	MinSpend            float64
+	ValidFrom           time.Time
+	ValidUntil          time.Time
+	UsageLimitPerClient int
+}
+
+type Line struct {
+	ProductID int32
+	Quantity  int32
+	Price     float64
+}
+
+type Discount struct {
+	Code        string
+	Description string
+	Amount      float64
+}
+
+// NormalizeCode returns the canonical form promo codes are stored in.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Evaluate returns the discount p gives on lines at time now for a client
+// that has already redeemed it clientUses times.
+func Evaluate(p Promotion, lines []Line, now time.Time, clientUses int) (Discount, error) {
+	if !p.ValidFrom.IsZero() && now.Before(p.ValidFrom) {
+		return Discount{}, ErrNotStarted
+	}
+	if !p.ValidUntil.IsZero() && !now.Before(p.ValidUntil) {
+		return Discount{}, ErrExpired
+	}
+	if p.UsageLimitPerClient > 0 && clientUses >= p.UsageLimitPerClient {
+		return Discount{}, ErrUsageLimit
+	}
+
+	subtotal := 0.0
+	for _, l := range lines {
+		subtotal += l.Price * float64(l.Quantity)
+	}
+	if subtotal < p.MinSpend {
+		return Discount{}, ErrMinimumSpend
+	}
+
+	var amount float64
+	switch p.Type {
+	case RulePercentage:
+		amount = subtotal * p.Percent / 100
+	case RuleFixedAmount, RuleMinimumSpend:
+		amount = p.Amount
+	case RuleBuyXGetY:
+		amount = buyXGetY(p, lines)
+	default:
+		return Discount{}, fmt.Errorf("unknown promotion rule %q", p.Type)
+	}
+
+	amount = math.Min(roundCents(amount), roundCents(subtotal))
+	if amount <= 0 {
+		return Discount{}, ErrNotApplicable
+	}
+
+	return Discount{
+		Code:        p.Code,
+		Description: p.Description,
+		Amount:      amount,
+	}, nil
+}
+
+func buyXGetY(p Promotion, lines []Line) float64 {
+	group := p.BuyQuantity + p.FreeQuantity
+	if p.BuyQuantity <= 0 || p.FreeQuantity <= 0 {
+		return 0
+	}
+
+	amount := 0.0
+	for _, l := range lines {
+		if p.ProductID != 0 && l.ProductID != p.ProductID {
+			continue
+		}
+		free := l.Quantity / group * p.FreeQuantity
+		amount += float64(free) * l.Price
+	}
+	return amount
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
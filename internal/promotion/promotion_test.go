@@ -0,0 +1,75 @@
+package promotion
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cart := []Line{
+		{ProductID: 1, Quantity: 2, Price: 19.99},
+		{ProductID: 2, Quantity: 5, Price: 4},
+	} // subtotal 59.98
+
+	tests := []struct {
+		name       string
+		p          Promotion
+		lines      []Line
+		clientUses int
+		want       float64
+		wantErr    error
+	}{
+		{name: "percentage", p: Promotion{Type: RulePercentage, Percent: 10}, lines: cart, want: 6},
+		{name: "percentage rounds up from half a cent", p: Promotion{Type: RulePercentage, Percent: 15}, lines: cart, want: 9},
+		{name: "percentage rounds down below half a cent", p: Promotion{Type: RulePercentage, Percent: 12.5}, lines: []Line{{ProductID: 1, Quantity: 1, Price: 0.5}}, want: 0.06},
+		{name: "fixed amount", p: Promotion{Type: RuleFixedAmount, Amount: 5}, lines: cart, want: 5},
+		{name: "fixed amount is capped at the subtotal", p: Promotion{Type: RuleFixedAmount, Amount: 100}, lines: cart, want: 59.98},
+		{name: "minimum spend reached", p: Promotion{Type: RuleMinimumSpend, Amount: 10, MinSpend: 59.98}, lines: cart, want: 10},
+		{name: "minimum spend missed", p: Promotion{Type: RuleMinimumSpend, Amount: 10, MinSpend: 60}, lines: cart, wantErr: ErrMinimumSpend},
+		{name: "minimum spend of another rule", p: Promotion{Type: RulePercentage, Percent: 10, MinSpend: 100}, lines: cart, wantErr: ErrMinimumSpend},
+		{name: "buy 2 get 1 of a product", p: Promotion{Type: RuleBuyXGetY, ProductID: 2, BuyQuantity: 2, FreeQuantity: 1}, lines: cart, want: 4},
+		{name: "buy 1 get 1 of any product", p: Promotion{Type: RuleBuyXGetY, BuyQuantity: 1, FreeQuantity: 1}, lines: cart, want: 19.99 + 8},
+		{name: "buy x get y below the group size", p: Promotion{Type: RuleBuyXGetY, ProductID: 1, BuyQuantity: 2, FreeQuantity: 1}, lines: cart, wantErr: ErrNotApplicable},
+		{name: "buy x get y without quantities", p: Promotion{Type: RuleBuyXGetY, ProductID: 2}, lines: cart, wantErr: ErrNotApplicable},
+		{name: "zero discount", p: Promotion{Type: RulePercentage, Percent: 0}, lines: cart, wantErr: ErrNotApplicable},
+		{name: "empty cart", p: Promotion{Type: RuleFixedAmount, Amount: 5}, wantErr: ErrNotApplicable},
+		{name: "starts now", p: Promotion{Type: RuleFixedAmount, Amount: 5, ValidFrom: now}, lines: cart, want: 5},
+		{name: "not started", p: Promotion{Type: RuleFixedAmount, Amount: 5, ValidFrom: now.Add(time.Second)}, lines: cart, wantErr: ErrNotStarted},
+		{name: "ends just after now", p: Promotion{Type: RuleFixedAmount, Amount: 5, ValidUntil: now.Add(time.Second)}, lines: cart, want: 5},
+		{name: "ends now", p: Promotion{Type: RuleFixedAmount, Amount: 5, ValidUntil: now}, lines: cart, wantErr: ErrExpired},
+		{name: "below the usage limit", p: Promotion{Type: RuleFixedAmount, Amount: 5, UsageLimitPerClient: 2}, lines: cart, clientUses: 1, want: 5},
+		{name: "usage limit reached", p: Promotion{Type: RuleFixedAmount, Amount: 5, UsageLimitPerClient: 2}, lines: cart, clientUses: 2, wantErr: ErrUsageLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.Code = "CODE"
+			got, err := Evaluate(tt.p, tt.lines, now, tt.clientUses)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Evaluate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if got.Code != "CODE" || got.Amount != roundCents(tt.want) {
+				t.Errorf("Evaluate() = %+v, want %.2f off", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateUnknownRule(t *testing.T) {
+	if _, err := Evaluate(Promotion{Type: "bogus"}, []Line{{ProductID: 1, Quantity: 1, Price: 1}}, time.Now(), 0); err == nil {
+		t.Error("Evaluate() of an unknown rule error = nil")
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	if got := NormalizeCode("  summer10 "); got != "SUMMER10" {
+		t.Errorf("NormalizeCode() = %q, want SUMMER10", got)
+	}
+}
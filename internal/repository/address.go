@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+)
+
+func (r *UserRepository) AddAddress(ctx context.Context, req AddAddressRequest) (resp AddAddressResponse, err error) {
+	a := req.Address
+	err = r.conn(ctx).QueryRowContext(ctx, AddAddressSQL,
+		a.ClientId, a.Recipient, a.Line1, a.Line2, a.City, a.PostalCode, a.Country,
+	).Scan(&resp.AddressID)
+	if err != nil {
+		return resp, fmt.Errorf("failed to add address: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *UserRepository) ListAddresses(ctx context.Context, req ListAddressesRequest) (resp ListAddressesResponse, err error) {
+	resp.Addresses = []Address{}
+	if err := r.conn(ctx).SelectContext(ctx, &resp.Addresses, ListAddressesSQL, req.ClientId); err != nil {
+		return resp, fmt.Errorf("failed to list addresses: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *UserRepository) GetAddress(ctx context.Context, req GetAddressRequest) (resp GetAddressResponse, err error) {
+	err = r.conn(ctx).GetContext(ctx, &resp.Address, GetAddressSQL, req.AddressID, req.ClientId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return resp, domain.NotFound("ADDRESS_NOT_FOUND", "address %d not found for user_id %d", req.AddressID, req.ClientId)
+		}
+		return resp, fmt.Errorf("failed to get address: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *UserRepository) DeleteAddress(ctx context.Context, req DeleteAddressRequest) (resp DeleteAddressResponse, err error) {
+	result, err := r.conn(ctx).ExecContext(ctx, DeleteAddressSQL, req.AddressID, req.ClientId)
+	if err != nil {
+		return DeleteAddressResponse{Success: false}, fmt.Errorf("failed to delete address: %w", err)
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return DeleteAddressResponse{Success: false}, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if affectedRows == 0 {
+		return DeleteAddressResponse{Success: false}, domain.NotFound("ADDRESS_NOT_FOUND", "address %d not found for user_id %d", req.AddressID, req.ClientId)
+	}
+
+	return DeleteAddressResponse{Success: true}, nil
+}
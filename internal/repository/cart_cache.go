@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	redis2 "github.com/go-redis/redis/v8"
+)
+
+// Carts are cached as a Redis hash under cart:<client_id> with two fields per
+// product: qty:<product_id> and price:<product_id>. Mutations only touch a
+// cart that is already cached; a cold cart is rebuilt from Postgres by GetCart.
+
+const (
+	cartQtyPrefix   = "qty:"
+	cartPricePrefix = "price:"
+
+	cartNotCached = 0
+	cartUpdated   = 1
+	cartLegacy    = -1
+	cartNoItem    = -2
+)
+
+var incrCartItemScript = redis2.NewScript(`
+local t = redis.call('TYPE', KEYS[1]).ok
+if t == 'none' then return 0 end
+if t ~= 'hash' then return -1 end
+redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[3])
+redis.call('HSETNX', KEYS[1], ARGV[2], ARGV[4])
+if tonumber(ARGV[5]) > 0 then redis.call('EXPIRE', KEYS[1], ARGV[5]) end
+return 1
+`)
+
+var decrCartItemScript = redis2.NewScript(`
+local t = redis.call('TYPE', KEYS[1]).ok
+if t == 'none' then return 0 end
+if t ~= 'hash' then return -1 end
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then return -2 end
+local n = redis.call('HINCRBY', KEYS[1], ARGV[1], -1)
+if n <= 0 then redis.call('HDEL', KEYS[1], ARGV[1], ARGV[2]) end
+if tonumber(ARGV[3]) > 0 and redis.call('EXISTS', KEYS[1]) == 1 then redis.call('EXPIRE', KEYS[1], ARGV[3]) end
+return 1
+`)
+
+// repriceCartScript sets the price of every cached line in ARGV, given as
+// qty field, price field and price triples.
+var repriceCartScript = redis2.NewScript(`
+local t = redis.call('TYPE', KEYS[1]).ok
+if t == 'none' then return 0 end
+if t ~= 'hash' then return -1 end
+for i = 1, #ARGV, 3 do
+  if redis.call('HEXISTS', KEYS[1], ARGV[i]) == 1 then
+    redis.call('HSET', KEYS[1], ARGV[i + 1], ARGV[i + 2])
+  end
+end
+return 1
+`)
+
+func cartKey(clientID int32) string {
+	return fmt.Sprintf("cart:%d", clientID)
+}
+
+func cartQtyField(productID int32) string {
+	return cartQtyPrefix + strconv.Itoa(int(productID))
+}
+
+func cartPriceField(productID int32) string {
+	return cartPricePrefix + strconv.Itoa(int(productID))
+}
+
+func isWrongType(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
+
+// incrCartItem atomically adds quantity to a cached cart line.
+func (r *UserRepository) incrCartItem(ctx context.Context, clientID, productID, quantity int32, price float64) error {
+	key := cartKey(clientID)
+	args := []interface{}{cartQtyField(productID), cartPriceField(productID), quantity, price, r.config().CartTTLSeconds}
+
+	res, err := incrCartItemScript.Run(ctx, r.redisClient.Client, []string{key}, args...).Int()
+	if err != nil {
+		return fmt.Errorf("failed to update cart in Redis: %w", err)
+	}
+	if res == cartLegacy {
+		if err := r.migrateLegacyCart(ctx, clientID); err != nil {
+			return err
+		}
+		if _, err := incrCartItemScript.Run(ctx, r.redisClient.Client, []string{key}, args...).Int(); err != nil {
+			return fmt.Errorf("failed to update cart in Redis: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// decrCartItem atomically removes one unit of a product from a cached cart
+// and drops the line when its quantity reaches zero.
+func (r *UserRepository) decrCartItem(ctx context.Context, clientID, productID int32) error {
+	key := cartKey(clientID)
+	args := []interface{}{cartQtyField(productID), cartPriceField(productID), r.config().CartTTLSeconds}
+
+	res, err := decrCartItemScript.Run(ctx, r.redisClient.Client, []string{key}, args...).Int()
+	if err != nil {
+		return fmt.Errorf("failed to update cart in Redis: %w", err)
+	}
+	if res == cartLegacy {
+		if err := r.migrateLegacyCart(ctx, clientID); err != nil {
+			return err
+		}
+		res, err = decrCartItemScript.Run(ctx, r.redisClient.Client, []string{key}, args...).Int()
+		if err != nil {
+			return fmt.Errorf("failed to update cart in Redis: %w", err)
+		}
+	}
+	if res == cartNoItem {
+		// Cache and database disagree, let the next GetCart rebuild it.
+		return r.dropCart(ctx, clientID)
+	}
+
+	return nil
+}
+
+// repriceCart atomically replaces the prices of the cached cart lines of
+// prices, keyed by product id. Lines that are not cached are left alone.
+func (r *UserRepository) repriceCart(ctx context.Context, clientID int32, prices map[int32]float64) error {
+	key := cartKey(clientID)
+	args := make([]interface{}, 0, len(prices)*3)
+	for productID, price := range prices {
+		args = append(args, cartQtyField(productID), cartPriceField(productID), price)
+	}
+
+	res, err := repriceCartScript.Run(ctx, r.redisClient.Client, []string{key}, args...).Int()
+	if err != nil {
+		return fmt.Errorf("failed to update cart prices in Redis: %w", err)
+	}
+	if res == cartLegacy {
+		if err := r.migrateLegacyCart(ctx, clientID); err != nil {
+			return err
+		}
+		if _, err := repriceCartScript.Run(ctx, r.redisClient.Client, []string{key}, args...).Int(); err != nil {
+			return fmt.Errorf("failed to update cart prices in Redis: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// loadCart returns the cached cart items. found is false when the cart is not
+// cached.
+func (r *UserRepository) loadCart(ctx context.Context, clientID int32) (items []CartItem, found bool, err error) {
+	defer func() {
+		switch {
+		case err != nil:
+			cartCacheReads.WithLabelValues("error").Inc()
+		case found:
+			cartCacheReads.WithLabelValues("hit").Inc()
+		default:
+			cartCacheReads.WithLabelValues("miss").Inc()
+		}
+	}()
+
+	key := cartKey(clientID)
+
+	fields, err := r.redisClient.Client.HGetAll(ctx, key).Result()
+	if isWrongType(err) {
+		if err := r.migrateLegacyCart(ctx, clientID); err != nil {
+			return nil, false, err
+		}
+		fields, err = r.redisClient.Client.HGetAll(ctx, key).Result()
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get cart from Redis: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, false, nil
+	}
+
+	items, err = parseCartHash(fields)
+	if err != nil {
+		// A half-written or corrupted hash would fail every read until it
+		// expires, so drop it and let GetCart rebuild it from Postgres.
+		r.log.WarnContext(ctx, "dropping unreadable cached cart", slog.Int("client_id", int(clientID)), logger.Err(err))
+		return nil, false, r.dropCart(ctx, clientID)
+	}
+
+	if ttl := r.cartTTL(); ttl > 0 {
+		if err := r.redisClient.Client.Expire(ctx, key, ttl).Err(); err != nil {
+			return nil, false, fmt.Errorf("failed to refresh cart TTL in Redis: %w", err)
+		}
+	}
+
+	return items, true, nil
+}
+
+// storeCart replaces the cached cart with items.
+func (r *UserRepository) storeCart(ctx context.Context, clientID int32, items []CartItem) error {
+	key := cartKey(clientID)
+
+	_, err := r.redisClient.Client.TxPipelined(ctx, func(pipe redis2.Pipeliner) error {
+		pipe.Del(ctx, key)
+		if len(items) == 0 {
+			return nil
+		}
+
+		values := make([]interface{}, 0, len(items)*4)
+		for _, item := range items {
+			values = append(values,
+				cartQtyField(item.ProductID), item.ProductQuantity,
+				cartPriceField(item.ProductID), item.ProductPrice,
+			)
+		}
+		pipe.HSet(ctx, key, values...)
+		if ttl := r.cartTTL(); ttl > 0 {
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save cart to Redis: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UserRepository) cartTTL() time.Duration {
+	return time.Duration(r.config().CartTTLSeconds) * time.Second
+}
+
+func (r *UserRepository) dropCart(ctx context.Context, clientID int32) error {
+	if err := r.redisClient.Client.Del(ctx, cartKey(clientID)).Err(); err != nil {
+		return fmt.Errorf("failed to delete cart from Redis: %w", err)
+	}
+	return nil
+}
+
+// migrateLegacyCart converts a cart stored as a JSON blob into the hash layout.
+func (r *UserRepository) migrateLegacyCart(ctx context.Context, clientID int32) error {
+	key := cartKey(clientID)
+
+	cartData, err := r.redisClient.Client.Get(ctx, key).Result()
+	if errors.Is(err, redis2.Nil) {
+		return nil
+	}
+	if isWrongType(err) {
+		// Another request has already migrated the cart.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get legacy cart from Redis: %w", err)
+	}
+
+	var cartItems []CartItem
+	if err := json.Unmarshal([]byte(cartData), &cartItems); err != nil {
+		r.log.WarnContext(ctx, "dropping unreadable legacy cart", slog.Int("client_id", int(clientID)), logger.Err(err))
+		return r.dropCart(ctx, clientID)
+	}
+
+	r.log.InfoContext(ctx, "migrating legacy cart to hash", slog.Int("client_id", int(clientID)))
+	return r.storeCart(ctx, clientID, cartItems)
+}
+
+// parseCartHash turns the fields of a cached cart into items sorted by product
+// id. Lines without a positive quantity are skipped; a line with a quantity but
+// no price is an error.
+func parseCartHash(fields map[string]string) ([]CartItem, error) {
+	byProduct := make(map[int32]*CartItem)
+	priced := make(map[int32]bool)
+	item := func(id string) (*CartItem, error) {
+		productID, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cart field product id %q: %w", id, err)
+		}
+		ci, ok := byProduct[int32(productID)]
+		if !ok {
+			ci = &CartItem{ProductID: int32(productID)}
+			byProduct[int32(productID)] = ci
+		}
+		return ci, nil
+	}
+
+	for field, value := range fields {
+		switch {
+		case strings.HasPrefix(field, cartQtyPrefix):
+			ci, err := item(strings.TrimPrefix(field, cartQtyPrefix))
+			if err != nil {
+				return nil, err
+			}
+			quantity, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cart quantity %q: %w", value, err)
+			}
+			ci.ProductQuantity = int32(quantity)
+		case strings.HasPrefix(field, cartPricePrefix):
+			ci, err := item(strings.TrimPrefix(field, cartPricePrefix))
+			if err != nil {
+				return nil, err
+			}
+			price, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cart price %q: %w", value, err)
+			}
+			ci.ProductPrice = price
+			priced[ci.ProductID] = true
+		}
+	}
+
+	items := make([]CartItem, 0, len(byProduct))
+	for _, ci := range byProduct {
+		if ci.ProductQuantity <= 0 {
+			continue
+		}
+		if !priced[ci.ProductID] {
+			return nil, fmt.Errorf("cart product %d has no price", ci.ProductID)
+		}
+		items = append(items, *ci)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ProductID < items[j].ProductID })
+
+	return items, nil
+}
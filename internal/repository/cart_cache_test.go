@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/Dmitrij-bot/marketserv/pkg/redis"
+	"github.com/alicebob/miniredis/v2"
+	redis2 "github.com/go-redis/redis/v8"
+)
+
+func TestParseCartHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		want    []CartItem
+		wantErr bool
+	}{
+		{
+			name:   "empty hash",
+			fields: map[string]string{},
+			want:   []CartItem{},
+		},
+		{
+			name: "lines sorted by product",
+			fields: map[string]string{
+				"qty:7": "1", "price:7": "2.5",
+				"qty:3": "2", "price:3": "10",
+			},
+			want: []CartItem{
+				{ProductID: 3, ProductQuantity: 2, ProductPrice: 10},
+				{ProductID: 7, ProductQuantity: 1, ProductPrice: 2.5},
+			},
+		},
+		{
+			name:   "line without quantity is skipped",
+			fields: map[string]string{"qty:1": "0", "price:1": "5", "price:2": "3"},
+			want:   []CartItem{},
+		},
+		{
+			name:   "unknown fields are ignored",
+			fields: map[string]string{"qty:1": "1", "price:1": "5", "version": "2"},
+			want:   []CartItem{{ProductID: 1, ProductQuantity: 1, ProductPrice: 5}},
+		},
+		{
+			name:    "malformed quantity",
+			fields:  map[string]string{"qty:1": "x", "price:1": "5"},
+			wantErr: true,
+		},
+		{
+			name:    "malformed price",
+			fields:  map[string]string{"qty:1": "1", "price:1": "x"},
+			wantErr: true,
+		},
+		{
+			name:    "malformed product id",
+			fields:  map[string]string{"qty:a": "1", "price:a": "5"},
+			wantErr: true,
+		},
+		{
+			name:    "quantity without price",
+			fields:  map[string]string{"qty:1": "1", "qty:2": "1", "price:2": "5"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCartHash(tt.fields)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseCartHash() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCartHash() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCartHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newCartCacheRepository(t *testing.T) (*UserRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis2.NewClient(&redis2.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := &UserRepository{
+		redisClient: &redis.RedisDB{Client: client},
+		cacheState:  newCacheState(log),
+		log:         log,
+	}
+	r.cfg.Store(&Config{CartTTLSeconds: 60})
+	return r, mr
+}
+
+func TestLoadCart(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("not cached", func(t *testing.T) {
+		r, _ := newCartCacheRepository(t)
+
+		items, found, err := r.loadCart(ctx, 1)
+		if err != nil || found || items != nil {
+			t.Fatalf("loadCart() = %v, %v, %v, want a miss", items, found, err)
+		}
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		mr.HSet(cartKey(1), "qty:5", "2", "price:5", "9.99")
+
+		items, found, err := r.loadCart(ctx, 1)
+		if err != nil || !found {
+			t.Fatalf("loadCart() = %v, %v, %v, want a hit", items, found, err)
+		}
+		want := []CartItem{{ProductID: 5, ProductQuantity: 2, ProductPrice: 9.99}}
+		if !reflect.DeepEqual(items, want) {
+			t.Errorf("loadCart() items = %v, want %v", items, want)
+		}
+		if ttl := mr.TTL(cartKey(1)); ttl <= 0 {
+			t.Errorf("cart TTL = %v, want it refreshed", ttl)
+		}
+	})
+
+	t.Run("partially migrated hash is dropped", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		mr.HSet(cartKey(1), "qty:5", "2", "qty:6", "1", "price:6", "3")
+
+		items, found, err := r.loadCart(ctx, 1)
+		if err != nil || found || items != nil {
+			t.Fatalf("loadCart() = %v, %v, %v, want a miss", items, found, err)
+		}
+		if mr.Exists(cartKey(1)) {
+			t.Error("unreadable cart is still cached")
+		}
+	})
+
+	t.Run("malformed hash is dropped", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		mr.HSet(cartKey(1), "qty:5", "two", "price:5", "3")
+
+		if _, found, err := r.loadCart(ctx, 1); err != nil || found {
+			t.Fatalf("loadCart() found = %v, err = %v, want a miss", found, err)
+		}
+		if mr.Exists(cartKey(1)) {
+			t.Error("unreadable cart is still cached")
+		}
+	})
+
+	t.Run("legacy JSON cart is migrated", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		if err := mr.Set(cartKey(1), `[{"id":5,"quantity":2,"price":9.99},{"id":2,"quantity":1,"price":4}]`); err != nil {
+			t.Fatal(err)
+		}
+
+		items, found, err := r.loadCart(ctx, 1)
+		if err != nil || !found {
+			t.Fatalf("loadCart() = %v, %v, %v, want a hit", items, found, err)
+		}
+		want := []CartItem{
+			{ProductID: 2, ProductQuantity: 1, ProductPrice: 4},
+			{ProductID: 5, ProductQuantity: 2, ProductPrice: 9.99},
+		}
+		if !reflect.DeepEqual(items, want) {
+			t.Errorf("loadCart() items = %v, want %v", items, want)
+		}
+		if got := mr.Type(cartKey(1)); got != "hash" {
+			t.Errorf("cart key type = %q, want hash", got)
+		}
+	})
+
+	t.Run("empty legacy JSON cart", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		if err := mr.Set(cartKey(1), `[]`); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, found, err := r.loadCart(ctx, 1); err != nil || found {
+			t.Fatalf("loadCart() found = %v, err = %v, want a miss", found, err)
+		}
+		if mr.Exists(cartKey(1)) {
+			t.Error("empty legacy cart is still cached")
+		}
+	})
+
+	t.Run("unreadable legacy JSON cart is dropped", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		if err := mr.Set(cartKey(1), `{not json`); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, found, err := r.loadCart(ctx, 1); err != nil || found {
+			t.Fatalf("loadCart() found = %v, err = %v, want a miss", found, err)
+		}
+		if mr.Exists(cartKey(1)) {
+			t.Error("unreadable legacy cart is still cached")
+		}
+	})
+}
+
+func TestIncrCartItemMigratesLegacyCart(t *testing.T) {
+	ctx := context.Background()
+	r, mr := newCartCacheRepository(t)
+	if err := mr.Set(cartKey(1), `[{"id":5,"quantity":2,"price":9.99}]`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.incrCartItem(ctx, 1, 5, 3, 9.99); err != nil {
+		t.Fatalf("incrCartItem() error = %v", err)
+	}
+	if err := r.incrCartItem(ctx, 1, 7, 1, 1.5); err != nil {
+		t.Fatalf("incrCartItem() error = %v", err)
+	}
+
+	items, found, err := r.loadCart(ctx, 1)
+	if err != nil || !found {
+		t.Fatalf("loadCart() = %v, %v, %v, want a hit", items, found, err)
+	}
+	want := []CartItem{
+		{ProductID: 5, ProductQuantity: 5, ProductPrice: 9.99},
+		{ProductID: 7, ProductQuantity: 1, ProductPrice: 1.5},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("loadCart() items = %v, want %v", items, want)
+	}
+}
+
+func TestDecrCartItem(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("legacy cart", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		if err := mr.Set(cartKey(1), `[{"id":5,"quantity":2,"price":9.99}]`); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := r.decrCartItem(ctx, 1, 5); err != nil {
+			t.Fatalf("decrCartItem() error = %v", err)
+		}
+		if got := mr.HGet(cartKey(1), "qty:5"); got != "1" {
+			t.Errorf("qty:5 = %q, want 1", got)
+		}
+	})
+
+	t.Run("last unit drops the line", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		mr.HSet(cartKey(1), "qty:5", "1", "price:5", "9.99", "qty:6", "1", "price:6", "2")
+
+		if err := r.decrCartItem(ctx, 1, 5); err != nil {
+			t.Fatalf("decrCartItem() error = %v", err)
+		}
+		if keys, _ := mr.HKeys(cartKey(1)); !reflect.DeepEqual(keys, []string{"price:6", "qty:6"}) {
+			t.Errorf("cart fields = %v, want only product 6", keys)
+		}
+	})
+
+	t.Run("item missing from cache drops the cart", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		mr.HSet(cartKey(1), "qty:6", "1", "price:6", "2")
+
+		if err := r.decrCartItem(ctx, 1, 5); err != nil {
+			t.Fatalf("decrCartItem() error = %v", err)
+		}
+		if mr.Exists(cartKey(1)) {
+			t.Error("inconsistent cart is still cached")
+		}
+	})
+}
+
+func TestRepriceCart(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("cached lines", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		mr.HSet(cartKey(1), "qty:5", "2", "price:5", "9.99", "qty:6", "1", "price:6", "2")
+
+		if err := r.repriceCart(ctx, 1, map[int32]float64{5: 12.5, 7: 3}); err != nil {
+			t.Fatalf("repriceCart() error = %v", err)
+		}
+		items, _, err := r.loadCart(ctx, 1)
+		if err != nil {
+			t.Fatalf("loadCart() error = %v", err)
+		}
+		want := []CartItem{
+			{ProductID: 5, ProductQuantity: 2, ProductPrice: 12.5},
+			{ProductID: 6, ProductQuantity: 1, ProductPrice: 2},
+		}
+		if !reflect.DeepEqual(items, want) {
+			t.Errorf("loadCart() items = %v, want %v", items, want)
+		}
+	})
+
+	t.Run("not cached", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+
+		if err := r.repriceCart(ctx, 1, map[int32]float64{5: 12.5}); err != nil {
+			t.Fatalf("repriceCart() error = %v", err)
+		}
+		if mr.Exists(cartKey(1)) {
+			t.Error("repriceCart() cached a cart")
+		}
+	})
+
+	t.Run("legacy cart", func(t *testing.T) {
+		r, mr := newCartCacheRepository(t)
+		if err := mr.Set(cartKey(1), `[{"id":5,"quantity":2,"price":9.99}]`); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := r.repriceCart(ctx, 1, map[int32]float64{5: 12.5}); err != nil {
+			t.Fatalf("repriceCart() error = %v", err)
+		}
+		if got := mr.HGet(cartKey(1), "price:5"); got != "12.5" {
+			t.Errorf("price:5 = %q, want 12.5", got)
+		}
+	})
+}
@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"github.com/Dmitrij-bot/marketserv/pkg/postgres"
+	"log/slog"
+)
+
+func (r *UserRepository) checkoutTTLSeconds() int {
+	if ttl := r.config().CheckoutTTLSeconds; ttl > 0 {
+		return ttl
+	}
+	return 15 * 60
+}
+
+// CreateCheckoutSession freezes the cart contents at current product prices
+// together with the quoted discount, tax and shipping. Older open sessions of
+// the client are superseded. The session is refused with ErrPriceChanged
+// unless the cart total equals req.ExpectedTotal; once it is accepted the cart
+// lines take the current prices, so the change is not reported again.
+func (r *UserRepository) CreateCheckoutSession(ctx context.Context, req CreateCheckoutSessionRequest) (resp CreateCheckoutSessionResponse, err error) {
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return resp, fmt.Errorf("failed to begin checkout transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var cartID int32
+	err = tx.QueryRowContext(ctx, LockCartSQL, req.ClientId).Scan(&cartID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return resp, cartNotFound(req.ClientId)
+		}
+		return resp, fmt.Errorf("failed to lock cart: %w", err)
+	}
+
+	var itemsTotal float64
+	if err = tx.QueryRowContext(ctx, CartTotalSQL, cartID).Scan(&itemsTotal); err != nil {
+		return resp, fmt.Errorf("failed to calculate cart total: %w", err)
+	}
+	if itemsTotal == 0 {
+		return resp, domain.Conflict("CART_EMPTY", "cart is empty")
+	}
+	if FormatPrice(itemsTotal) != FormatPrice(req.ExpectedTotal) {
+		return resp, fmt.Errorf("%w: expected %s, actual %s", ErrPriceChanged, FormatPrice(req.ExpectedTotal), FormatPrice(itemsTotal))
+	}
+
+	repriced, err := repriceCart(ctx, tx, cartID)
+	if err != nil {
+		return resp, err
+	}
+
+	if _, err = tx.ExecContext(ctx, SupersedeCheckoutSessionsSQL, req.ClientId, CheckoutStatusSuperseded); err != nil {
+		return resp, fmt.Errorf("failed to supersede checkout sessions: %w", err)
+	}
+
+	total := itemsTotal - req.Discount
+	if total < 0 {
+		total = 0
+	}
+	total += req.Tax + req.ShippingCost
+
+	s := CheckoutSession{
+		ID:             req.ID,
+		ClientId:       req.ClientId,
+		CartId:         cartID,
+		Status:         CheckoutStatusOpen,
+		ItemsTotal:     itemsTotal,
+		Discount:       req.Discount,
+		Tax:            req.Tax,
+		ShippingCost:   req.ShippingCost,
+		Total:          total,
+		PromoCode:      req.PromoCode,
+		AddressID:      req.AddressID,
+		ShippingOption: req.ShippingOption,
+	}
+
+	err = tx.QueryRowContext(ctx, CreateCheckoutSessionSQL,
+		s.ID, s.ClientId, s.CartId, s.ItemsTotal, s.Discount, s.Tax, s.ShippingCost, s.Total,
+		s.PromoCode, s.AddressID, s.ShippingOption, r.checkoutTTLSeconds(),
+	).Scan(&s.ExpiresAt)
+	if err != nil {
+		return resp, fmt.Errorf("failed to create checkout session: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, CreateCheckoutSessionItemsSQL, s.ID, cartID)
+	if err != nil {
+		return resp, fmt.Errorf("failed to freeze checkout items: %w", err)
+	}
+	for rows.Next() {
+		var item CheckoutItem
+		if err = rows.Scan(&item.ProductID, &item.ProductQuantity, &item.ProductPrice); err != nil {
+			_ = rows.Close()
+			return resp, fmt.Errorf("failed to scan checkout item: %w", err)
+		}
+		s.Items = append(s.Items, item)
+	}
+	if err = rows.Close(); err != nil {
+		return resp, fmt.Errorf("failed to close rows: %w", err)
+	}
+	if err = rows.Err(); err != nil {
+		return resp, fmt.Errorf("error occurred during row iteration: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return resp, fmt.Errorf("failed to commit checkout session: %w", err)
+	}
+
+	if len(repriced) > 0 && !r.cacheState.skip(req.ClientId) {
+		if err := r.repriceCart(context.WithoutCancel(ctx), req.ClientId, repriced); err != nil {
+			r.staleCart(ctx, req.ClientId, err)
+		}
+	}
+
+	return CreateCheckoutSessionResponse{Session: s}, nil
+}
+
+// repriceCart moves the lines of a locked cart to the current product prices
+// and returns the new prices of the lines that changed.
+func repriceCart(ctx context.Context, tx *postgres.Tx, cartID int32) (map[int32]float64, error) {
+	rows, err := tx.QueryContext(ctx, RepriceCartSQL, cartID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update cart prices: %w", err)
+	}
+	defer rows.Close()
+
+	prices := make(map[int32]float64)
+	for rows.Next() {
+		var (
+			productID int32
+			price     float64
+		)
+		if err := rows.Scan(&productID, &price); err != nil {
+			return nil, fmt.Errorf("failed to scan cart price: %w", err)
+		}
+		prices[productID] = price
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred during row iteration: %w", err)
+	}
+	return prices, nil
+}
+
+// GetCheckoutSession returns an open, unexpired checkout session.
+func (r *UserRepository) GetCheckoutSession(ctx context.Context, req GetCheckoutSessionRequest) (resp GetCheckoutSessionResponse, err error) {
+
+	s, err := openCheckoutSession(r.conn(ctx).QueryRowContext(ctx, GetCheckoutSessionSQL, req.ID, req.ClientId))
+	if err != nil {
+		return resp, err
+	}
+
+	return GetCheckoutSessionResponse{Session: s}, nil
+}
+
+// ConfirmCheckoutSession turns the frozen items of an open session into a
+// pending order paid through req.PaymentReference and records the promo code
+// redemption in the same transaction. Charging is left to the payment
+// provider.
+func (r *UserRepository) ConfirmCheckoutSession(ctx context.Context, req ConfirmCheckoutSessionRequest) (resp ConfirmCheckoutSessionResponse, err error) {
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return resp, fmt.Errorf("ошибка начала транзакции оформления: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	s, err := openCheckoutSession(tx.QueryRowContext(ctx, LockCheckoutSessionSQL, req.ID, req.ClientId))
+	if err != nil {
+		return resp, err
+	}
+
+	var cartID int32
+	if err = tx.QueryRowContext(ctx, LockCartSQL, req.ClientId).Scan(&cartID); err != nil {
+		return resp, fmt.Errorf("ошибка обработки платежа: %v", err)
+	}
+
+	var mismatches int
+	if err = tx.QueryRowContext(ctx, CheckoutCartMismatchSQL, s.ID, cartID).Scan(&mismatches); err != nil {
+		return resp, fmt.Errorf("ошибка проверки корзины: %v", err)
+	}
+	if mismatches > 0 {
+		return resp, ErrCheckoutStale
+	}
+
+	if s.PromoCode != "" {
+		result, err := tx.ExecContext(ctx, RedeemPromoCodeSQL, s.PromoCode, req.ClientId, s.Discount)
+		if err != nil {
+			return resp, fmt.Errorf("ошибка применения промокода: %v", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return resp, fmt.Errorf("ошибка проверки затронутых строк: %v", err)
+		}
+		if rowsAffected == 0 {
+			return resp, ErrPromoUsageLimit
+		}
+	}
+
+	var orderID int64
+	err = tx.QueryRowContext(ctx, CreateOrderSQL,
+		req.ClientId, OrderStatusPending, s.ItemsTotal, s.Discount, s.Tax, s.ShippingCost, s.Total,
+		s.PromoCode, s.AddressID, s.ShippingOption, req.PaymentProvider, req.PaymentReference,
+	).Scan(&orderID)
+	if err != nil {
+		return resp, fmt.Errorf("ошибка создания заказа: %v", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, CreateOrderItemsSQL, orderID, s.ID); err != nil {
+		return resp, fmt.Errorf("ошибка создания позиций заказа: %v", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, SetCartPromoCodeSQL, req.ClientId, ""); err != nil {
+		return resp, fmt.Errorf("ошибка очистки промокода: %v", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, ClearCartSQL, cartID); err != nil {
+		return resp, fmt.Errorf("ошибка очистки корзины: %v", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, CloseCheckoutSessionSQL, s.ID, CheckoutStatusConfirmed, orderID); err != nil {
+		return resp, fmt.Errorf("ошибка закрытия сессии оформления: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return resp, fmt.Errorf("ошибка фиксации заказа: %v", err)
+	}
+
+	if r.cacheState.skip(req.ClientId) {
+		r.log.InfoContext(ctx, "Redis is unavailable, cached cart will be cleared after recovery", slog.Int("client_id", int(req.ClientId)))
+	} else if err := r.dropCart(context.WithoutCancel(ctx), req.ClientId); err != nil {
+		r.staleCart(ctx, req.ClientId, err)
+	}
+
+	return ConfirmCheckoutSessionResponse{
+		Success:      true,
+		OrderID:      orderID,
+		ChargedTotal: s.Total,
+	}, nil
+}
+
+// SetOrderStatus moves an order to a new payment status.
+func (r *UserRepository) SetOrderStatus(ctx context.Context, req SetOrderStatusRequest) (resp SetOrderStatusResponse, err error) {
+
+	result, err := r.conn(ctx).ExecContext(ctx, SetOrderStatusSQL, req.OrderID, req.Status)
+	if err != nil {
+		return resp, fmt.Errorf("failed to set order status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return resp, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return resp, domain.NotFound("ORDER_NOT_FOUND", "order %d not found", req.OrderID)
+	}
+
+	return SetOrderStatusResponse{Success: true}, nil
+}
+
+// openCheckoutSession scans a checkout session row and makes sure the
+// session can still be confirmed.
+func openCheckoutSession(row *sql.Row) (s CheckoutSession, err error) {
+
+	var expired bool
+	err = row.Scan(
+		&s.ID, &s.ClientId, &s.CartId, &s.Status, &s.ItemsTotal, &s.Discount, &s.Tax, &s.ShippingCost, &s.Total,
+		&s.PromoCode, &s.AddressID, &s.ShippingOption, &s.ExpiresAt, &expired,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return s, ErrCheckoutNotFound
+		}
+		return s, fmt.Errorf("failed to get checkout session: %w", err)
+	}
+	if s.Status != CheckoutStatusOpen {
+		return s, fmt.Errorf("%w: status %s", ErrCheckoutClosed, s.Status)
+	}
+	if expired {
+		return s, fmt.Errorf("%w at %s", ErrCheckoutExpired, s.ExpiresAt)
+	}
+
+	return s, nil
+}
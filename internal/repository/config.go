@@ -0,0 +1,14 @@
+package repository
+
+type Config struct {
+	CartTTLSeconds      int `reload:"true"` // idle cart lifetime in Redis, 0 disables expiry
+	ProductTTLSeconds   int `reload:"true"` // product lookup cache lifetime
+	SearchTTLSeconds    int `reload:"true"` // product search cache lifetime
+	GuestCartTTLSeconds int `reload:"true"` // guest cart lifetime in Redis, 3 days by default
+	CheckoutTTLSeconds  int `reload:"true"` // checkout quote lifetime, 15 minutes by default
+
+	RedisCheckIntervalSeconds int // Redis probe interval in degraded mode, 5 by default
+	TxAttempts                int // tries of a serializable transaction, 3 by default
+
+	MemoryDataFile string // JSON MemoryData the memory storage starts with, demo data when empty
+}
@@ -0,0 +1,659 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
+	"slices"
+	"testing"
+)
+
+// contractStore is a storage under the contract tests together with
+// accessors for the state the repository API does not expose.
+type contractStore struct {
+	repo     Interface
+	payments payment.PaymentProvider
+	stock    func(t *testing.T, productID int32) int32
+	balance  func(t *testing.T, clientID int32) float64
+	market   func(t *testing.T) float64
+	setPrice func(t *testing.T, productID int32, price float64)
+}
+
+// contractData is what every contract test starts with.
+func contractData() MemoryData {
+	return MemoryData{
+		Clients: []MemoryClient{
+			{ID: 1, Username: "alice", Role: "user", Region: "RU", Balance: 1000},
+			{ID: 2, Username: "bob", Role: "user", Region: "BY", Balance: 10},
+		},
+		Products: []MemoryProduct{
+			{ID: 1, Name: "Laptop", Description: "14-inch laptop", Price: 500, Quantity: 3, TaxClass: "standard", WeightGrams: 1500},
+			{ID: 2, Name: "Wireless mouse", Description: "Bluetooth mouse", Price: 25.5, Quantity: 10, TaxClass: "standard", WeightGrams: 100},
+			{ID: 3, Name: "Desk lamp", Description: "LED desk lamp", Price: 40, Quantity: 0, TaxClass: "reduced", WeightGrams: 900},
+		},
+		Promotions: []Promotion{
+			{Code: "ONCE", Description: "5 off, once", RuleType: "fixed_amount", Amount: 5, UsageLimitPerClient: 1},
+		},
+		MarketBalance: 100,
+	}
+}
+
+// testRepositoryContract checks the behaviour every storage must share, so
+// that switching between them changes nothing the client can see.
+func testRepositoryContract(t *testing.T, newStore func(t *testing.T, data MemoryData) contractStore) {
+	ctx := context.Background()
+
+	t.Run("AddItemToCart reserves stock", func(t *testing.T) {
+		s := newStore(t, contractData())
+
+		mustAdd(t, s.repo, 1, 1, 2)
+		mustAdd(t, s.repo, 1, 1, 1)
+
+		if got := s.stock(t, 1); got != 0 {
+			t.Errorf("stock = %d, want 0", got)
+		}
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 1, ProductQuantity: 3, ProductPrice: 500}}, "1500.00")
+	})
+
+	t.Run("AddItemToCart out of stock changes nothing", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustAdd(t, s.repo, 1, 2, 1)
+
+		_, err := s.repo.AddItemToCart(ctx, AddItemToCartRequest{ClientId: 1, ProductID: 1, Quantity: 4})
+		wantReason(t, err, "OUT_OF_STOCK")
+		_, err = s.repo.AddItemToCart(ctx, AddItemToCartRequest{ClientId: 1, ProductID: 2, Quantity: 10})
+		wantReason(t, err, "OUT_OF_STOCK")
+
+		if got := s.stock(t, 1); got != 3 {
+			t.Errorf("stock of a new line = %d, want 3", got)
+		}
+		if got := s.stock(t, 2); got != 9 {
+			t.Errorf("stock of an existing line = %d, want 9", got)
+		}
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 1, ProductPrice: 25.5}}, "25.50")
+	})
+
+	t.Run("AddItemToCart unknown product", func(t *testing.T) {
+		s := newStore(t, contractData())
+
+		_, err := s.repo.AddItemToCart(ctx, AddItemToCartRequest{ClientId: 1, ProductID: 99, Quantity: 1})
+		wantReason(t, err, "PRODUCT_NOT_FOUND")
+	})
+
+	t.Run("DeleteItemFromCart removes the last unit", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustAdd(t, s.repo, 1, 1, 2)
+		mustAdd(t, s.repo, 1, 2, 1)
+
+		for range 2 {
+			if _, err := s.repo.DeleteItemFromCart(ctx, DeleteItemFromCartRequest{ClientId: 1, ProductID: 1}); err != nil {
+				t.Fatalf("DeleteItemFromCart() error = %v", err)
+			}
+		}
+
+		if got := s.stock(t, 1); got != 3 {
+			t.Errorf("stock = %d, want 3", got)
+		}
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 1, ProductPrice: 25.5}}, "25.50")
+
+		_, err := s.repo.DeleteItemFromCart(ctx, DeleteItemFromCartRequest{ClientId: 1, ProductID: 1})
+		wantReason(t, err, "CART_ITEM_NOT_FOUND")
+		if got := s.stock(t, 1); got != 3 {
+			t.Errorf("stock after deleting a missing line = %d, want 3", got)
+		}
+	})
+
+	t.Run("carts of unknown clients", func(t *testing.T) {
+		s := newStore(t, contractData())
+
+		_, err := s.repo.GetCart(ctx, GetCartRequest{ClientId: 1})
+		wantReason(t, err, "CART_NOT_FOUND")
+		_, err = s.repo.DeleteItemFromCart(ctx, DeleteItemFromCartRequest{ClientId: 1, ProductID: 1})
+		wantReason(t, err, "CART_NOT_FOUND")
+		_, err = s.repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{ID: "s1", ClientId: 1})
+		wantReason(t, err, "CART_NOT_FOUND")
+	})
+
+	t.Run("CreateCartIfNotExists is idempotent", func(t *testing.T) {
+		s := newStore(t, contractData())
+
+		first, err := s.repo.CreateCartIfNotExists(ctx, CreateCartIfNotExistsRequest{ClientId: 1})
+		if err != nil {
+			t.Fatalf("CreateCartIfNotExists() error = %v", err)
+		}
+		second, err := s.repo.CreateCartIfNotExists(ctx, CreateCartIfNotExistsRequest{ClientId: 1})
+		if err != nil {
+			t.Fatalf("CreateCartIfNotExists() error = %v", err)
+		}
+		if first.CartId != second.CartId {
+			t.Errorf("cart ids %d and %d, want the same cart", first.CartId, second.CartId)
+		}
+		wantCart(t, s.repo, 1, nil, "0.00")
+	})
+
+	t.Run("catalog", func(t *testing.T) {
+		s := newStore(t, contractData())
+
+		found, err := s.repo.SearchProductByName(ctx, SearchProductByNameRequest{ProductName: "MOUSE"})
+		if err != nil {
+			t.Fatalf("SearchProductByName() error = %v", err)
+		}
+		want := []Product{{ProductID: 2, ProductName: "Wireless mouse", ProductDescription: "Bluetooth mouse", ProductPrice: "25.50"}}
+		if !slices.Equal(found.Products, want) {
+			t.Errorf("SearchProductByName() = %+v, want %+v", found.Products, want)
+		}
+
+		prices, err := s.repo.GetProductPrices(ctx, GetProductPricesRequest{ProductIDs: []int32{1, 3, 99}})
+		if err != nil {
+			t.Fatalf("GetProductPrices() error = %v", err)
+		}
+		if len(prices.Prices) != 2 || prices.Prices[1] != 500 || prices.TaxClasses[3] != "reduced" || prices.WeightGrams[1] != 1500 {
+			t.Errorf("GetProductPrices() = %+v", prices)
+		}
+
+		client, err := s.repo.FindClientByUsername(ctx, FindClientByUsernameRequest{ClientID: 2})
+		if err != nil || client.Username != "bob" || client.Role != "user" {
+			t.Errorf("FindClientByUsername() = %+v, %v", client, err)
+		}
+		region, err := s.repo.GetClientRegion(ctx, GetClientRegionRequest{ClientId: 2})
+		if err != nil || region.Region != "BY" {
+			t.Errorf("GetClientRegion() = %+v, %v", region, err)
+		}
+	})
+
+	t.Run("guest carts check stock without reserving it", func(t *testing.T) {
+		s := newStore(t, contractData())
+
+		if _, err := s.repo.AddItemToGuestCart(ctx, AddItemToGuestCartRequest{SessionToken: "guest", ProductID: 1, Quantity: 3}); err != nil {
+			t.Fatalf("AddItemToGuestCart() error = %v", err)
+		}
+		_, err := s.repo.AddItemToGuestCart(ctx, AddItemToGuestCartRequest{SessionToken: "guest", ProductID: 1, Quantity: 1})
+		wantReason(t, err, "OUT_OF_STOCK")
+		if got := s.stock(t, 1); got != 3 {
+			t.Errorf("stock = %d, want 3", got)
+		}
+
+		if _, err := s.repo.DeleteItemFromGuestCart(ctx, DeleteItemFromGuestCartRequest{SessionToken: "guest", ProductID: 1}); err != nil {
+			t.Fatalf("DeleteItemFromGuestCart() error = %v", err)
+		}
+		cart, err := s.repo.GetGuestCart(ctx, GetGuestCartRequest{SessionToken: "guest"})
+		if err != nil {
+			t.Fatalf("GetGuestCart() error = %v", err)
+		}
+		if cart.TotalPrice != "1000.00" {
+			t.Errorf("guest cart total = %s, want 1000.00", cart.TotalPrice)
+		}
+
+		_, err = s.repo.GetGuestCart(ctx, GetGuestCartRequest{SessionToken: "other"})
+		wantReason(t, err, "GUEST_CART_NOT_FOUND")
+		_, err = s.repo.DeleteItemFromGuestCart(ctx, DeleteItemFromGuestCartRequest{SessionToken: "guest", ProductID: 2})
+		wantReason(t, err, "CART_ITEM_NOT_FOUND")
+	})
+
+	t.Run("MergeCart clamps to stock", func(t *testing.T) {
+		s := newStore(t, contractData())
+		for _, id := range []int32{1, 2} {
+			if _, err := s.repo.AddItemToGuestCart(ctx, AddItemToGuestCartRequest{SessionToken: "guest", ProductID: id, Quantity: 3}); err != nil {
+				t.Fatalf("AddItemToGuestCart() error = %v", err)
+			}
+		}
+		// Another client takes two laptops meanwhile.
+		mustAdd(t, s.repo, 2, 1, 2)
+
+		merged, err := s.repo.MergeCart(ctx, MergeCartRequest{SessionToken: "guest", ClientId: 1})
+		if err != nil {
+			t.Fatalf("MergeCart() error = %v", err)
+		}
+		slices.SortFunc(merged.Items, func(a, b MergedItem) int { return int(a.ProductID - b.ProductID) })
+		want := []MergedItem{
+			{ProductID: 1, RequestedQuantity: 3, MergedQuantity: 1},
+			{ProductID: 2, RequestedQuantity: 3, MergedQuantity: 3},
+		}
+		if !slices.Equal(merged.Items, want) {
+			t.Errorf("MergeCart() = %+v, want %+v", merged.Items, want)
+		}
+		if got := s.stock(t, 1); got != 0 {
+			t.Errorf("stock = %d, want 0", got)
+		}
+		wantCart(t, s.repo, 1, []CartItem{
+			{ProductID: 1, ProductQuantity: 1, ProductPrice: 500},
+			{ProductID: 2, ProductQuantity: 3, ProductPrice: 25.5},
+		}, "576.50")
+
+		_, err = s.repo.GetGuestCart(ctx, GetGuestCartRequest{SessionToken: "guest"})
+		wantReason(t, err, "GUEST_CART_NOT_FOUND")
+	})
+
+	t.Run("MergeCart merges a guest cart once", func(t *testing.T) {
+		s := newStore(t, contractData())
+		if _, err := s.repo.AddItemToGuestCart(ctx, AddItemToGuestCartRequest{SessionToken: "guest", ProductID: 2, Quantity: 2}); err != nil {
+			t.Fatalf("AddItemToGuestCart() error = %v", err)
+		}
+		if _, err := s.repo.MergeCart(ctx, MergeCartRequest{SessionToken: "guest", ClientId: 1}); err != nil {
+			t.Fatalf("MergeCart() error = %v", err)
+		}
+
+		// The guest cart comes back as if deleting it had failed, and the
+		// client retries the login.
+		if _, err := s.repo.AddItemToGuestCart(ctx, AddItemToGuestCartRequest{SessionToken: "guest", ProductID: 2, Quantity: 2}); err != nil {
+			t.Fatalf("AddItemToGuestCart() error = %v", err)
+		}
+		_, err := s.repo.MergeCart(ctx, MergeCartRequest{SessionToken: "guest", ClientId: 1})
+		wantReason(t, err, "GUEST_CART_NOT_FOUND")
+
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 2, ProductPrice: 25.5}}, "51.00")
+		_, err = s.repo.GetGuestCart(ctx, GetGuestCartRequest{SessionToken: "guest"})
+		wantReason(t, err, "GUEST_CART_NOT_FOUND")
+	})
+
+	t.Run("checkout", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustAdd(t, s.repo, 1, 1, 1)
+		mustAdd(t, s.repo, 1, 2, 2)
+		if _, err := s.repo.SetCartPromoCode(ctx, SetCartPromoCodeRequest{ClientId: 1, Code: "ONCE"}); err != nil {
+			t.Fatalf("SetCartPromoCode() error = %v", err)
+		}
+
+		_, err := s.repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{ID: "s0", ClientId: 1, ExpectedTotal: 500})
+		wantReason(t, err, "PRICE_CHANGED")
+
+		created, err := s.repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{
+			ID: "s1", ClientId: 1, ExpectedTotal: 551, PromoCode: "ONCE", Discount: 5, Tax: 10, ShippingCost: 4,
+		})
+		if err != nil {
+			t.Fatalf("CreateCheckoutSession() error = %v", err)
+		}
+		if got := created.Session; got.Status != CheckoutStatusOpen || got.ItemsTotal != 551 || got.Total != 560 || len(got.Items) != 2 {
+			t.Errorf("session = %+v, want an open session of 2 items totalling 560", got)
+		}
+
+		got, err := s.repo.GetCheckoutSession(ctx, GetCheckoutSessionRequest{ID: "s1", ClientId: 1})
+		if err != nil || got.Session.Total != 560 {
+			t.Errorf("GetCheckoutSession() = %+v, %v", got.Session, err)
+		}
+		_, err = s.repo.GetCheckoutSession(ctx, GetCheckoutSessionRequest{ID: "s1", ClientId: 2})
+		wantReason(t, err, "CHECKOUT_NOT_FOUND")
+
+		confirmed, err := s.repo.ConfirmCheckoutSession(ctx, ConfirmCheckoutSessionRequest{
+			ID: "s1", ClientId: 1, PaymentProvider: payment.ProviderBalance, PaymentReference: "ref-1",
+		})
+		if err != nil {
+			t.Fatalf("ConfirmCheckoutSession() error = %v", err)
+		}
+		if !confirmed.Success || confirmed.OrderID == 0 || confirmed.ChargedTotal != 560 {
+			t.Errorf("ConfirmCheckoutSession() = %+v", confirmed)
+		}
+
+		wantCart(t, s.repo, 1, nil, "0.00")
+		if code, err := s.repo.GetCartPromoCode(ctx, GetCartPromoCodeRequest{ClientId: 1}); err != nil || code.Code != "" {
+			t.Errorf("cart promo code = %q, %v, want it cleared", code.Code, err)
+		}
+		if n, err := s.repo.CountPromoRedemptions(ctx, CountPromoRedemptionsRequest{Code: "ONCE", ClientId: 1}); err != nil || n.Count != 1 {
+			t.Errorf("redemptions = %d, %v, want 1", n.Count, err)
+		}
+		if got := s.stock(t, 1); got != 2 {
+			t.Errorf("stock = %d, want the ordered unit to stay taken", got)
+		}
+
+		_, err = s.repo.ConfirmCheckoutSession(ctx, ConfirmCheckoutSessionRequest{ID: "s1", ClientId: 1, PaymentReference: "ref-2"})
+		wantReason(t, err, "CHECKOUT_CLOSED")
+	})
+
+	t.Run("checkout takes the acknowledged prices into the cart", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustAdd(t, s.repo, 1, 2, 2)
+		s.setPrice(t, 2, 30)
+
+		// The cart keeps the price of the time the product was added.
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 2, ProductPrice: 25.5}}, "51.00")
+		_, err := s.repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{ID: "s0", ClientId: 1, ExpectedTotal: 51})
+		wantReason(t, err, "PRICE_CHANGED")
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 2, ProductPrice: 25.5}}, "51.00")
+
+		if _, err := s.repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{ID: "s1", ClientId: 1, ExpectedTotal: 60}); err != nil {
+			t.Fatalf("CreateCheckoutSession() error = %v", err)
+		}
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 2, ProductPrice: 30}}, "60.00")
+	})
+
+	t.Run("checkout of an empty cart", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustAdd(t, s.repo, 1, 1, 1)
+		if _, err := s.repo.DeleteItemFromCart(ctx, DeleteItemFromCartRequest{ClientId: 1, ProductID: 1}); err != nil {
+			t.Fatalf("DeleteItemFromCart() error = %v", err)
+		}
+
+		_, err := s.repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{ID: "s1", ClientId: 1})
+		wantReason(t, err, "CART_EMPTY")
+	})
+
+	t.Run("a newer checkout supersedes the open one", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustAdd(t, s.repo, 1, 2, 1)
+		for _, id := range []string{"s1", "s2"} {
+			if _, err := s.repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{ID: id, ClientId: 1, ExpectedTotal: 25.5}); err != nil {
+				t.Fatalf("CreateCheckoutSession(%s) error = %v", id, err)
+			}
+		}
+
+		_, err := s.repo.GetCheckoutSession(ctx, GetCheckoutSessionRequest{ID: "s1", ClientId: 1})
+		wantReason(t, err, "CHECKOUT_CLOSED")
+		if _, err := s.repo.GetCheckoutSession(ctx, GetCheckoutSessionRequest{ID: "s2", ClientId: 1}); err != nil {
+			t.Errorf("GetCheckoutSession(s2) error = %v", err)
+		}
+	})
+
+	t.Run("confirming a checkout of a changed cart", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustAdd(t, s.repo, 1, 2, 1)
+		if _, err := s.repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{ID: "s1", ClientId: 1, ExpectedTotal: 25.5}); err != nil {
+			t.Fatalf("CreateCheckoutSession() error = %v", err)
+		}
+		mustAdd(t, s.repo, 1, 2, 1)
+
+		_, err := s.repo.ConfirmCheckoutSession(ctx, ConfirmCheckoutSessionRequest{ID: "s1", ClientId: 1, PaymentReference: "ref-1"})
+		wantReason(t, err, "CHECKOUT_STALE")
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 2, ProductPrice: 25.5}}, "51.00")
+	})
+
+	t.Run("promo usage limit", func(t *testing.T) {
+		s := newStore(t, contractData())
+		for i, id := range []string{"s1", "s2"} {
+			mustAdd(t, s.repo, 1, 2, 1)
+			if _, err := s.repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{ID: id, ClientId: 1, ExpectedTotal: 25.5, PromoCode: "ONCE", Discount: 5}); err != nil {
+				t.Fatalf("CreateCheckoutSession(%s) error = %v", id, err)
+			}
+			_, err := s.repo.ConfirmCheckoutSession(ctx, ConfirmCheckoutSessionRequest{ID: id, ClientId: 1, PaymentReference: id})
+			if i == 0 && err != nil {
+				t.Fatalf("ConfirmCheckoutSession(%s) error = %v", id, err)
+			}
+			if i == 1 {
+				wantReason(t, err, "PROMO_USAGE_LIMIT")
+			}
+		}
+
+		// The refused confirmation left the cart and the session alone.
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 1, ProductPrice: 25.5}}, "25.50")
+		if _, err := s.repo.GetCheckoutSession(ctx, GetCheckoutSessionRequest{ID: "s2", ClientId: 1}); err != nil {
+			t.Errorf("GetCheckoutSession(s2) error = %v", err)
+		}
+	})
+
+	t.Run("SettleOrderPayment", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustOrder(t, s.repo, 1, "ref-1")
+
+		settled, err := s.repo.SettleOrderPayment(ctx, SettleOrderPaymentRequest{EventID: "e1", PaymentReference: "ref-1", Status: OrderStatusPaid})
+		if err != nil {
+			t.Fatalf("SettleOrderPayment() error = %v", err)
+		}
+		if !settled.Changed || settled.Status != OrderStatusPaid || settled.ClientId != 1 || settled.Total != 25.5 {
+			t.Errorf("SettleOrderPayment() = %+v, want a change to paid", settled)
+		}
+
+		again, err := s.repo.SettleOrderPayment(ctx, SettleOrderPaymentRequest{EventID: "e1", PaymentReference: "ref-1", Status: OrderStatusPaid})
+		if err != nil || again.Changed || again.Status != OrderStatusPaid {
+			t.Errorf("redelivered SettleOrderPayment() = %+v, %v, want no change", again, err)
+		}
+
+		_, err = s.repo.SettleOrderPayment(ctx, SettleOrderPaymentRequest{EventID: "e2", PaymentReference: "ref-1", Status: OrderStatusFailed})
+		wantReason(t, err, "ORDER_STATUS_CONFLICT")
+		_, err = s.repo.SettleOrderPayment(ctx, SettleOrderPaymentRequest{EventID: "e3", PaymentReference: "ref-unknown", Status: OrderStatusPaid})
+		wantReason(t, err, "ORDER_NOT_FOUND")
+	})
+
+	t.Run("a failed settlement returns the order to the cart", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustOrder(t, s.repo, 1, "ref-1")
+
+		settled, err := s.repo.SettleOrderPayment(ctx, SettleOrderPaymentRequest{EventID: "e1", PaymentReference: "ref-1", Status: OrderStatusFailed})
+		if err != nil || !settled.Changed || settled.Status != OrderStatusFailed {
+			t.Fatalf("SettleOrderPayment() = %+v, %v, want a change to failed", settled, err)
+		}
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 1, ProductPrice: 25.5}}, "25.50")
+		if got := s.stock(t, 2); got != 9 {
+			t.Errorf("stock = %d, want the returned unit to stay reserved", got)
+		}
+
+		again, err := s.repo.SettleOrderPayment(ctx, SettleOrderPaymentRequest{EventID: "e1", PaymentReference: "ref-1", Status: OrderStatusFailed})
+		if err != nil || again.Changed {
+			t.Errorf("redelivered SettleOrderPayment() = %+v, %v, want no change", again, err)
+		}
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 1, ProductPrice: 25.5}}, "25.50")
+	})
+
+	t.Run("FailOrder returns the order to the cart", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustAdd(t, s.repo, 1, 1, 1)
+		mustAdd(t, s.repo, 1, 2, 2)
+		if _, err := s.repo.SetCartPromoCode(ctx, SetCartPromoCodeRequest{ClientId: 1, Code: "ONCE"}); err != nil {
+			t.Fatalf("SetCartPromoCode() error = %v", err)
+		}
+		if _, err := s.repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{ID: "s1", ClientId: 1, ExpectedTotal: 551, PromoCode: "ONCE", Discount: 5}); err != nil {
+			t.Fatalf("CreateCheckoutSession() error = %v", err)
+		}
+		confirmed, err := s.repo.ConfirmCheckoutSession(ctx, ConfirmCheckoutSessionRequest{ID: "s1", ClientId: 1, PaymentReference: "ref-1"})
+		if err != nil {
+			t.Fatalf("ConfirmCheckoutSession() error = %v", err)
+		}
+		// A unit added meanwhile is merged with the returned line.
+		mustAdd(t, s.repo, 1, 2, 1)
+
+		failed, err := s.repo.FailOrder(ctx, FailOrderRequest{OrderID: confirmed.OrderID})
+		if err != nil || !failed.Changed {
+			t.Fatalf("FailOrder() = %+v, %v, want a change", failed, err)
+		}
+
+		wantCart(t, s.repo, 1, []CartItem{
+			{ProductID: 1, ProductQuantity: 1, ProductPrice: 500},
+			{ProductID: 2, ProductQuantity: 3, ProductPrice: 25.5},
+		}, "576.50")
+		if got := s.stock(t, 1); got != 2 {
+			t.Errorf("stock = %d, want the returned unit to stay reserved", got)
+		}
+		if code, err := s.repo.GetCartPromoCode(ctx, GetCartPromoCodeRequest{ClientId: 1}); err != nil || code.Code != "ONCE" {
+			t.Errorf("cart promo code = %q, %v, want ONCE back", code.Code, err)
+		}
+		if n, err := s.repo.CountPromoRedemptions(ctx, CountPromoRedemptionsRequest{Code: "ONCE", ClientId: 1}); err != nil || n.Count != 0 {
+			t.Errorf("redemptions = %d, %v, want 0", n.Count, err)
+		}
+
+		again, err := s.repo.FailOrder(ctx, FailOrderRequest{OrderID: confirmed.OrderID})
+		if err != nil || again.Changed {
+			t.Errorf("FailOrder() again = %+v, %v, want no change", again, err)
+		}
+		wantCart(t, s.repo, 1, []CartItem{
+			{ProductID: 1, ProductQuantity: 1, ProductPrice: 500},
+			{ProductID: 2, ProductQuantity: 3, ProductPrice: 25.5},
+		}, "576.50")
+
+		_, err = s.repo.SettleOrderPayment(ctx, SettleOrderPaymentRequest{EventID: "e1", PaymentReference: "ref-1", Status: OrderStatusPaid})
+		wantReason(t, err, "ORDER_STATUS_CONFLICT")
+		_, err = s.repo.FailOrder(ctx, FailOrderRequest{OrderID: confirmed.OrderID + 100})
+		wantReason(t, err, "ORDER_NOT_FOUND")
+	})
+
+	t.Run("SetOrderStatus", func(t *testing.T) {
+		s := newStore(t, contractData())
+		orderID := mustOrder(t, s.repo, 1, "ref-1")
+
+		if _, err := s.repo.SetOrderStatus(ctx, SetOrderStatusRequest{OrderID: orderID, Status: OrderStatusPaid}); err != nil {
+			t.Fatalf("SetOrderStatus() error = %v", err)
+		}
+		settled, err := s.repo.SettleOrderPayment(ctx, SettleOrderPaymentRequest{EventID: "e1", PaymentReference: "ref-1", Status: OrderStatusPaid})
+		if err != nil || settled.Changed {
+			t.Errorf("SettleOrderPayment() = %+v, %v, want the order paid already", settled, err)
+		}
+
+		_, err = s.repo.SetOrderStatus(ctx, SetOrderStatusRequest{OrderID: orderID + 100, Status: OrderStatusPaid})
+		wantReason(t, err, "ORDER_NOT_FOUND")
+	})
+
+	t.Run("addresses", func(t *testing.T) {
+		s := newStore(t, contractData())
+		address := Address{ClientId: 1, Recipient: "Alice", Line1: "Main st 1", City: "Moscow", PostalCode: "101000", Country: "RU"}
+
+		added, err := s.repo.AddAddress(ctx, AddAddressRequest{Address: address})
+		if err != nil {
+			t.Fatalf("AddAddress() error = %v", err)
+		}
+		address.AddressID = added.AddressID
+
+		got, err := s.repo.GetAddress(ctx, GetAddressRequest{ClientId: 1, AddressID: added.AddressID})
+		if err != nil || got.Address != address {
+			t.Errorf("GetAddress() = %+v, %v, want %+v", got.Address, err, address)
+		}
+		_, err = s.repo.GetAddress(ctx, GetAddressRequest{ClientId: 2, AddressID: added.AddressID})
+		wantReason(t, err, "ADDRESS_NOT_FOUND")
+
+		if _, err := s.repo.DeleteAddress(ctx, DeleteAddressRequest{ClientId: 1, AddressID: added.AddressID}); err != nil {
+			t.Fatalf("DeleteAddress() error = %v", err)
+		}
+		listed, err := s.repo.ListAddresses(ctx, ListAddressesRequest{ClientId: 1})
+		if err != nil || len(listed.Addresses) != 0 {
+			t.Errorf("ListAddresses() = %+v, %v, want none", listed.Addresses, err)
+		}
+		_, err = s.repo.DeleteAddress(ctx, DeleteAddressRequest{ClientId: 1, AddressID: added.AddressID})
+		wantReason(t, err, "ADDRESS_NOT_FOUND")
+	})
+
+	t.Run("promotions", func(t *testing.T) {
+		s := newStore(t, contractData())
+
+		got, err := s.repo.GetPromotion(ctx, GetPromotionRequest{Code: "ONCE"})
+		if err != nil || got.Promotion.Amount != 5 || got.Promotion.UsageLimitPerClient != 1 {
+			t.Errorf("GetPromotion() = %+v, %v", got.Promotion, err)
+		}
+		_, err = s.repo.GetPromotion(ctx, GetPromotionRequest{Code: "NOPE"})
+		wantReason(t, err, "PROMO_NOT_FOUND")
+
+		_, err = s.repo.SetCartPromoCode(ctx, SetCartPromoCodeRequest{ClientId: 1, Code: "ONCE"})
+		wantReason(t, err, "CART_NOT_FOUND")
+	})
+
+	t.Run("InTx rolls back every change", func(t *testing.T) {
+		s := newStore(t, contractData())
+		mustAdd(t, s.repo, 1, 2, 1)
+
+		boom := errors.New("boom")
+		err := s.repo.InTx(ctx, func(ctx context.Context) error {
+			if _, err := s.repo.AddItemToCart(ctx, AddItemToCartRequest{ClientId: 1, ProductID: 1, Quantity: 2}); err != nil {
+				return err
+			}
+			if _, err := s.repo.DeleteItemFromCart(ctx, DeleteItemFromCartRequest{ClientId: 1, ProductID: 2}); err != nil {
+				return err
+			}
+			return boom
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("InTx() error = %v, want %v", err, boom)
+		}
+
+		if got := s.stock(t, 1); got != 3 {
+			t.Errorf("stock = %d, want 3", got)
+		}
+		wantCart(t, s.repo, 1, []CartItem{{ProductID: 2, ProductQuantity: 1, ProductPrice: 25.5}}, "25.50")
+	})
+
+	t.Run("payments", func(t *testing.T) {
+		s := newStore(t, contractData())
+
+		_, err := s.payments.Authorize(ctx, payment.AuthorizeRequest{ClientId: 2, Amount: 11})
+		if !errors.Is(err, payment.ErrInsufficientFunds) {
+			t.Errorf("Authorize() error = %v, want %v", err, payment.ErrInsufficientFunds)
+		}
+
+		auth, err := s.payments.Authorize(ctx, payment.AuthorizeRequest{ClientId: 1, Amount: 300, Reference: "s1"})
+		if err != nil {
+			t.Fatalf("Authorize() error = %v", err)
+		}
+		if got := s.balance(t, 1); got != 700 {
+			t.Errorf("balance after Authorize = %.2f, want 700", got)
+		}
+
+		err = s.payments.Capture(ctx, payment.CaptureRequest{AuthorizationID: auth.ID, Amount: 301})
+		wantReason(t, err, "PAYMENT_INVALID_STATE")
+		if err := s.payments.Capture(ctx, payment.CaptureRequest{AuthorizationID: auth.ID, Amount: 250}); err != nil {
+			t.Fatalf("Capture() error = %v", err)
+		}
+		if got, market := s.balance(t, 1), s.market(t); got != 750 || market != 350 {
+			t.Errorf("balance, market after Capture = %.2f, %.2f, want 750, 350", got, market)
+		}
+
+		err = s.payments.Void(ctx, payment.VoidRequest{AuthorizationID: auth.ID})
+		wantReason(t, err, "PAYMENT_INVALID_STATE")
+
+		if err := s.payments.Refund(ctx, payment.RefundRequest{AuthorizationID: auth.ID, Amount: 200}); err != nil {
+			t.Fatalf("Refund() error = %v", err)
+		}
+		err = s.payments.Refund(ctx, payment.RefundRequest{AuthorizationID: auth.ID, Amount: 51})
+		wantReason(t, err, "PAYMENT_INVALID_STATE")
+		if got, market := s.balance(t, 1), s.market(t); got != 950 || market != 150 {
+			t.Errorf("balance, market after Refund = %.2f, %.2f, want 950, 150", got, market)
+		}
+
+		held, err := s.payments.Authorize(ctx, payment.AuthorizeRequest{ClientId: 1, Amount: 50})
+		if err != nil {
+			t.Fatalf("Authorize() error = %v", err)
+		}
+		if err := s.payments.Void(ctx, payment.VoidRequest{AuthorizationID: held.ID}); err != nil {
+			t.Fatalf("Void() error = %v", err)
+		}
+		if got := s.balance(t, 1); got != 950 {
+			t.Errorf("balance after Void = %.2f, want 950", got)
+		}
+
+		err = s.payments.Capture(ctx, payment.CaptureRequest{AuthorizationID: "bal_unknown", Amount: 1})
+		wantReason(t, err, "PAYMENT_AUTHORIZATION_NOT_FOUND")
+	})
+}
+
+func mustAdd(t *testing.T, repo Interface, clientID, productID, quantity int32) {
+	t.Helper()
+	_, err := repo.AddItemToCart(context.Background(), AddItemToCartRequest{ClientId: clientID, ProductID: productID, Quantity: quantity})
+	if err != nil {
+		t.Fatalf("AddItemToCart(client %d, product %d, %d) error = %v", clientID, productID, quantity, err)
+	}
+}
+
+// mustOrder checks out a mouse for the client and returns the pending order.
+func mustOrder(t *testing.T, repo Interface, clientID int32, reference string) int64 {
+	t.Helper()
+	ctx := context.Background()
+	mustAdd(t, repo, clientID, 2, 1)
+	if _, err := repo.CreateCheckoutSession(ctx, CreateCheckoutSessionRequest{ID: reference, ClientId: clientID, ExpectedTotal: 25.5}); err != nil {
+		t.Fatalf("CreateCheckoutSession() error = %v", err)
+	}
+	confirmed, err := repo.ConfirmCheckoutSession(ctx, ConfirmCheckoutSessionRequest{
+		ID: reference, ClientId: clientID, PaymentProvider: payment.ProviderBalance, PaymentReference: reference,
+	})
+	if err != nil {
+		t.Fatalf("ConfirmCheckoutSession() error = %v", err)
+	}
+	return confirmed.OrderID
+}
+
+func wantCart(t *testing.T, repo Interface, clientID int32, want []CartItem, total string) {
+	t.Helper()
+	cart, err := repo.GetCart(context.Background(), GetCartRequest{ClientId: clientID})
+	if err != nil {
+		t.Fatalf("GetCart() error = %v", err)
+	}
+	items := slices.Clone(cart.CartItems)
+	slices.SortFunc(items, func(a, b CartItem) int { return int(a.ProductID - b.ProductID) })
+	if !slices.Equal(items, want) {
+		t.Errorf("cart items = %+v, want %+v", items, want)
+	}
+	if cart.TotalPrice != total {
+		t.Errorf("cart total = %s, want %s", cart.TotalPrice, total)
+	}
+}
+
+func wantReason(t *testing.T, err error, reason string) {
+	t.Helper()
+	e, ok := domain.As(err)
+	if !ok || e.Reason != reason {
+		t.Fatalf("error = %v, want reason %s", err, reason)
+	}
+}
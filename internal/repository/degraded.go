@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"github.com/Dmitrij-bot/marketserv/pkg/redis"
+	redis2 "github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	redisDegradedGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "marketserv_redis_degraded",
+		Help: "1 while cart operations bypass Redis because it is unreachable.",
+	})
+	redisDirtyCartsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "marketserv_redis_dirty_carts",
+		Help: "Carts changed in Postgres only that must be re-warmed in Redis.",
+	})
+)
+
+// cacheState tracks whether Redis can be used for carts and which carts were
+// changed while it could not.
+type cacheState struct {
+	mu    sync.Mutex
+	down  bool
+	dirty map[int32]struct{}
+	log   *slog.Logger
+}
+
+func newCacheState(log *slog.Logger) *cacheState {
+	return &cacheState{dirty: make(map[int32]struct{}), log: log}
+}
+
+func (s *cacheState) isDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.down
+}
+
+func (s *cacheState) markDown(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.down {
+		s.log.Warn("Redis is unavailable, cart operations switch to Postgres only", logger.Err(err))
+		s.down = true
+		redisDegradedGauge.Set(1)
+	}
+}
+
+// skip reports whether the cart cache must be bypassed and, if so, remembers
+// the cart for re-warming.
+func (s *cacheState) skip(clientID int32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		s.addDirtyLocked(clientID)
+	}
+	return s.down
+}
+
+func (s *cacheState) markDirty(clientID int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addDirtyLocked(clientID)
+}
+
+func (s *cacheState) addDirtyLocked(clientID int32) {
+	s.dirty[clientID] = struct{}{}
+	redisDirtyCartsGauge.Set(float64(len(s.dirty)))
+}
+
+func (s *cacheState) clean(clientID int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.dirty, clientID)
+	redisDirtyCartsGauge.Set(float64(len(s.dirty)))
+}
+
+func (s *cacheState) dirtyCarts() []int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]int32, 0, len(s.dirty))
+	for id := range s.dirty {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// markUp leaves degraded mode unless more carts became dirty meanwhile.
+func (s *cacheState) markUp() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.dirty) > 0 {
+		return false
+	}
+	if s.down {
+		s.log.Info("Redis is available again, cart cache re-warmed")
+		s.down = false
+		redisDegradedGauge.Set(0)
+	}
+	return true
+}
+
+// isRedisUnavailable reports whether err means Redis could not be reached as
+// opposed to a miss or an error reply. A cancelled or expired ctx is the
+// caller giving up, only a timeout of the Redis client itself while ctx is
+// still live means Redis is down.
+func isRedisUnavailable(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, redis2.Nil) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ctx.Err() == nil
+	}
+	var replyErr redis2.Error
+	return !errors.As(err, &replyErr)
+}
+
+// cartCacheError switches the repository to degraded mode when err means
+// Redis is unreachable. The error is swallowed in that case since Postgres
+// holds the cart.
+func (r *UserRepository) cartCacheError(ctx context.Context, clientID int32, err error) error {
+	if !isRedisUnavailable(ctx, err) {
+		return err
+	}
+	r.cacheState.markDown(err)
+	r.cacheState.markDirty(clientID)
+	return nil
+}
+
+// staleCart deals with a cached cart that could not be updated after Postgres
+// committed a change to it. The change stands, so the call must not fail:
+// the cached cart is dropped for the next read to reload it, or, if even that
+// fails, the cart is left to the re-warming of degraded mode.
+func (r *UserRepository) staleCart(ctx context.Context, clientID int32, err error) {
+	r.log.WarnContext(ctx, "failed to update cached cart", slog.Int("client_id", int(clientID)), logger.Err(err))
+	if r.cartCacheError(ctx, clientID, err) == nil {
+		return
+	}
+	if err := r.dropCart(ctx, clientID); err != nil {
+		r.cacheState.markDown(err)
+		r.cacheState.markDirty(clientID)
+	}
+}
+
+func (r *UserRepository) rewarmCart(ctx context.Context, clientID int32) error {
+	_, items, err := r.loadCartFromDB(ctx, clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.dropCart(ctx, clientID)
+	}
+	if err != nil {
+		return err
+	}
+	return r.storeCart(ctx, clientID, items)
+}
+
+// RedisMonitor probes Redis and rebuilds the carts changed while it was down.
+type RedisMonitor struct {
+	repo        *UserRepository
+	redisClient *redis.RedisDB
+	interval    time.Duration
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+func NewRedisMonitor(cfg Config, repo *UserRepository, redisClient *redis.RedisDB) *RedisMonitor {
+	interval := time.Duration(cfg.RedisCheckIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &RedisMonitor{
+		repo:        repo,
+		redisClient: redisClient,
+		interval:    interval,
+	}
+}
+
+func (m *RedisMonitor) Start(ctx context.Context) error {
+	m.done = make(chan struct{})
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-ticker.C:
+				m.check()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (m *RedisMonitor) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+	defer cancel()
+
+	if err := m.redisClient.Client.Ping(ctx).Err(); err != nil {
+		m.repo.cacheState.markDown(err)
+		return
+	}
+	if !m.repo.cacheState.isDown() {
+		return
+	}
+
+	for {
+		for _, clientID := range m.repo.cacheState.dirtyCarts() {
+			if err := m.repo.rewarmCart(ctx, clientID); err != nil {
+				m.repo.log.Warn("failed to re-warm cart", slog.Int("client_id", int(clientID)), logger.Err(err))
+				return
+			}
+			m.repo.cacheState.clean(clientID)
+		}
+		if m.repo.cacheState.markUp() || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (m *RedisMonitor) Stop(ctx context.Context) error {
+	if m.done == nil {
+		return nil
+	}
+	close(m.done)
+	m.wg.Wait()
+	return nil
+}
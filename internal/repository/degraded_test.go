@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	redis2 "github.com/go-redis/redis/v8"
+	"testing"
+)
+
+func TestIsRedisUnavailable(t *testing.T) {
+	live := context.Background()
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"no error", live, nil, false},
+		{"miss", live, redis2.Nil, false},
+		{"connection refused", live, errors.New("dial tcp: connection refused"), true},
+		{"client timeout", live, fmt.Errorf("read: %w", context.DeadlineExceeded), true},
+		{"caller deadline", expired, context.DeadlineExceeded, false},
+		{"caller cancelled", expired, context.Canceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRedisUnavailable(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("isRedisUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
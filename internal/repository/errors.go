@@ -0,0 +1,39 @@
+package repository
+
+import "github.com/Dmitrij-bot/marketserv/internal/domain"
+
+// ErrPriceChanged is returned by CreateCheckoutSession when the cart total at
+// current prices differs from the total the client agreed to pay.
+var ErrPriceChanged = domain.Conflict("PRICE_CHANGED", "cart prices have changed")
+
+// ErrPromoUsageLimit is returned by ConfirmCheckoutSession when the client
+// has already used the promo code as many times as allowed.
+var ErrPromoUsageLimit = domain.Conflict("PROMO_USAGE_LIMIT", "promo code usage limit reached")
+
+var (
+	// ErrCheckoutNotFound is returned for an unknown checkout session.
+	ErrCheckoutNotFound = domain.NotFound("CHECKOUT_NOT_FOUND", "checkout session not found")
+	// ErrCheckoutClosed is returned when a checkout session was already
+	// confirmed or replaced by a newer one.
+	ErrCheckoutClosed = domain.Conflict("CHECKOUT_CLOSED", "checkout session is closed")
+	// ErrCheckoutExpired is returned when a checkout session outlived its quote.
+	ErrCheckoutExpired = domain.Conflict("CHECKOUT_EXPIRED", "checkout session has expired")
+	// ErrCheckoutStale is returned when the cart changed after the checkout
+	// session was created.
+	ErrCheckoutStale = domain.Conflict("CHECKOUT_STALE", "cart has changed since checkout was created")
+)
+
+var (
+	// ErrOrderNotFound is returned when no order matches a payment reference.
+	ErrOrderNotFound = domain.NotFound("ORDER_NOT_FOUND", "order not found")
+	// ErrOrderStatusConflict is returned when a settlement contradicts the
+	// final status the order already has.
+	ErrOrderStatusConflict = domain.Conflict("ORDER_STATUS_CONFLICT", "order status conflict")
+)
+
+// ErrOutOfStock is returned when a cart line asks for more than is in stock.
+var ErrOutOfStock = domain.OutOfStock("not enough quantity in stock")
+
+func cartNotFound(clientID int32) error {
+	return domain.NotFound("CART_NOT_FOUND", "cart not found for user_id %d", clientID)
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	redis2 "github.com/go-redis/redis/v8"
+)
+
+// Guest carts live in Redis only, under guestcart:<session_token> with the
+// same hash layout as client carts. They do not reserve stock; stock is taken
+// when the cart is merged into a client cart.
+
+func guestCartKey(token string) string {
+	return "guestcart:" + token
+}
+
+func (r *UserRepository) guestCartTTL() time.Duration {
+	if ttl := r.config().GuestCartTTLSeconds; ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return 72 * time.Hour
+}
+
+func (r *UserRepository) AddItemToGuestCart(ctx context.Context, req AddItemToGuestCartRequest) (resp AddItemToGuestCartResponse, err error) {
+	key := guestCartKey(req.SessionToken)
+
+	var (
+		price float64
+		stock int32
+	)
+	err = r.conn(ctx).QueryRowContext(ctx, GetProductStockSQL, req.ProductID).Scan(&price, &stock)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return AddItemToGuestCartResponse{Success: false}, domain.NotFound("PRODUCT_NOT_FOUND", "product %d not found", req.ProductID)
+		}
+		return AddItemToGuestCartResponse{Success: false}, fmt.Errorf("failed to retrieve product stock: %w", err)
+	}
+
+	inCart, err := r.redisClient.Client.HGet(ctx, key, cartQtyField(req.ProductID)).Int()
+	if err != nil && !errors.Is(err, redis2.Nil) {
+		return AddItemToGuestCartResponse{Success: false}, fmt.Errorf("failed to get guest cart from Redis: %w", err)
+	}
+	if int32(inCart)+req.Quantity > stock {
+		return AddItemToGuestCartResponse{Success: false}, ErrOutOfStock
+	}
+
+	_, err = r.redisClient.Client.TxPipelined(ctx, func(pipe redis2.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, cartQtyField(req.ProductID), int64(req.Quantity))
+		pipe.HSetNX(ctx, key, cartPriceField(req.ProductID), price)
+		pipe.Expire(ctx, key, r.guestCartTTL())
+		return nil
+	})
+	if err != nil {
+		return AddItemToGuestCartResponse{Success: false}, fmt.Errorf("failed to save guest cart to Redis: %w", err)
+	}
+
+	return AddItemToGuestCartResponse{Success: true}, nil
+}
+
+func (r *UserRepository) DeleteItemFromGuestCart(ctx context.Context, req DeleteItemFromGuestCartRequest) (resp DeleteItemFromGuestCartResponse, err error) {
+	key := guestCartKey(req.SessionToken)
+	args := []interface{}{cartQtyField(req.ProductID), cartPriceField(req.ProductID), int(r.guestCartTTL().Seconds())}
+
+	res, err := decrCartItemScript.Run(ctx, r.redisClient.Client, []string{key}, args...).Int()
+	if err != nil {
+		return DeleteItemFromGuestCartResponse{Success: false}, fmt.Errorf("failed to update guest cart in Redis: %w", err)
+	}
+	if res != cartUpdated {
+		return DeleteItemFromGuestCartResponse{Success: false}, domain.NotFound("CART_ITEM_NOT_FOUND", "item not found in cart")
+	}
+
+	return DeleteItemFromGuestCartResponse{Success: true}, nil
+}
+
+func (r *UserRepository) GetGuestCart(ctx context.Context, req GetGuestCartRequest) (resp GetCartResponse, err error) {
+	fields, err := r.redisClient.Client.HGetAll(ctx, guestCartKey(req.SessionToken)).Result()
+	if err != nil {
+		return GetCartResponse{}, fmt.Errorf("failed to get guest cart from Redis: %w", err)
+	}
+	if len(fields) == 0 {
+		return GetCartResponse{}, domain.NotFound("GUEST_CART_NOT_FOUND", "guest cart not found")
+	}
+
+	resp.CartItems, err = parseCartHash(fields)
+	if err != nil {
+		return GetCartResponse{}, err
+	}
+
+	totalPrice := 0.0
+	for _, item := range resp.CartItems {
+		totalPrice += item.ProductPrice * float64(item.ProductQuantity)
+	}
+
+	resp.TotalPrice = FormatPrice(totalPrice)
+	return resp, nil
+}
+
+// MergeCart moves the guest cart into the client cart. Every line is clamped
+// to the stock available at merge time. The lines are merged in one
+// transaction that also records the token, and the guest cart is deleted once
+// it commits. A guest cart that outlives its merge, because the delete
+// failed, is reported as not found instead of being merged again.
+func (r *UserRepository) MergeCart(ctx context.Context, req MergeCartRequest) (resp MergeCartResponse, err error) {
+	guestCart, err := r.GetGuestCart(ctx, GetGuestCartRequest{SessionToken: req.SessionToken})
+	if err != nil {
+		return MergeCartResponse{}, err
+	}
+
+	merged := true
+	err = r.InTx(ctx, func(ctx context.Context) error {
+		result, err := r.conn(ctx).ExecContext(ctx, RecordGuestCartMergeSQL, req.SessionToken, req.ClientId)
+		if err != nil {
+			return fmt.Errorf("failed to record guest cart merge: %w", err)
+		}
+		recorded, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		}
+		if merged = recorded > 0; !merged {
+			return r.afterCommit(ctx, func(ctx context.Context) error {
+				r.dropGuestCart(ctx, req.SessionToken)
+				return nil
+			})
+		}
+
+		cartResp, err := r.CreateCartIfNotExists(ctx, CreateCartIfNotExistsRequest{
+			ClientId: req.ClientId,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create or retrieve cart: %w", err)
+		}
+
+		resp.Items = []MergedItem{}
+		prices := make(map[int32]float64, len(guestCart.CartItems))
+
+		for _, item := range guestCart.CartItems {
+			merged := MergedItem{
+				ProductID:         item.ProductID,
+				RequestedQuantity: item.ProductQuantity,
+			}
+
+			var price float64
+			err := r.conn(ctx).QueryRowContext(ctx, MergeCartItemSQL, cartResp.CartId, item.ProductID, item.ProductQuantity).
+				Scan(&merged.MergedQuantity, &price)
+			if err != nil && err != sql.ErrNoRows {
+				return fmt.Errorf("failed to merge product %d into cart: %w", item.ProductID, err)
+			}
+
+			resp.Items = append(resp.Items, merged)
+			prices[item.ProductID] = price
+		}
+
+		return r.afterCommit(ctx, func(ctx context.Context) error {
+			r.dropGuestCart(ctx, req.SessionToken)
+
+			for _, merged := range resp.Items {
+				if merged.MergedQuantity == 0 || r.cacheState.skip(req.ClientId) {
+					continue
+				}
+				if err := r.incrCartItem(ctx, req.ClientId, merged.ProductID, merged.MergedQuantity, prices[merged.ProductID]); err != nil {
+					r.staleCart(ctx, req.ClientId, err)
+					break
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return resp, err
+	}
+	if !merged {
+		return MergeCartResponse{}, domain.NotFound("GUEST_CART_NOT_FOUND", "guest cart not found")
+	}
+
+	return resp, nil
+}
+
+// dropGuestCart deletes a merged guest cart. The merge is recorded already,
+// so a guest cart left behind is only logged.
+func (r *UserRepository) dropGuestCart(ctx context.Context, token string) {
+	if err := r.redisClient.Client.Del(ctx, guestCartKey(token)).Err(); err != nil {
+		r.log.WarnContext(ctx, "failed to delete merged guest cart", logger.Err(err))
+	}
+}
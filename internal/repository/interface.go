@@ -3,11 +3,34 @@ package repository
 import "context"
 
 type Interface interface {
+	// InTx runs fn in a transaction that the calls made with its context
+	// join, see UserRepository.InTx.
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+
 	FindClientByUsername(ctx context.Context, req FindClientByUsernameRequest) (resp FindClientByUsernameResponse, err error)
 	SearchProductByName(ctx context.Context, req SearchProductByNameRequest) (resp SearchProductByNameResponse, err error)
 	CreateCartIfNotExists(ctx context.Context, req CreateCartIfNotExistsRequest) (resp CreateCartIfNotExistsResponse, err error)
 	AddItemToCart(ctx context.Context, req AddItemToCartRequest) (resp AddItemToCartResponse, err error)
 	DeleteItemFromCart(ctx context.Context, req DeleteItemFromCartRequest) (resp DeleteItemFromCartResponse, err error)
 	GetCart(ctx context.Context, req GetCartRequest) (resp GetCartResponse, err error)
-	SimulatePayment(ctx context.Context, req PaymentRequest) (resp PaymentResponse, err error)
+	CreateCheckoutSession(ctx context.Context, req CreateCheckoutSessionRequest) (resp CreateCheckoutSessionResponse, err error)
+	GetCheckoutSession(ctx context.Context, req GetCheckoutSessionRequest) (resp GetCheckoutSessionResponse, err error)
+	ConfirmCheckoutSession(ctx context.Context, req ConfirmCheckoutSessionRequest) (resp ConfirmCheckoutSessionResponse, err error)
+	SetOrderStatus(ctx context.Context, req SetOrderStatusRequest) (resp SetOrderStatusResponse, err error)
+	FailOrder(ctx context.Context, req FailOrderRequest) (resp FailOrderResponse, err error)
+	SettleOrderPayment(ctx context.Context, req SettleOrderPaymentRequest) (resp SettleOrderPaymentResponse, err error)
+	GetProductPrices(ctx context.Context, req GetProductPricesRequest) (resp GetProductPricesResponse, err error)
+	GetClientRegion(ctx context.Context, req GetClientRegionRequest) (resp GetClientRegionResponse, err error)
+	AddAddress(ctx context.Context, req AddAddressRequest) (resp AddAddressResponse, err error)
+	ListAddresses(ctx context.Context, req ListAddressesRequest) (resp ListAddressesResponse, err error)
+	GetAddress(ctx context.Context, req GetAddressRequest) (resp GetAddressResponse, err error)
+	DeleteAddress(ctx context.Context, req DeleteAddressRequest) (resp DeleteAddressResponse, err error)
+	GetPromotion(ctx context.Context, req GetPromotionRequest) (resp GetPromotionResponse, err error)
+	CountPromoRedemptions(ctx context.Context, req CountPromoRedemptionsRequest) (resp CountPromoRedemptionsResponse, err error)
+	SetCartPromoCode(ctx context.Context, req SetCartPromoCodeRequest) (resp SetCartPromoCodeResponse, err error)
+	GetCartPromoCode(ctx context.Context, req GetCartPromoCodeRequest) (resp GetCartPromoCodeResponse, err error)
+	AddItemToGuestCart(ctx context.Context, req AddItemToGuestCartRequest) (resp AddItemToGuestCartResponse, err error)
+	DeleteItemFromGuestCart(ctx context.Context, req DeleteItemFromGuestCartRequest) (resp DeleteItemFromGuestCartResponse, err error)
+	GetGuestCart(ctx context.Context, req GetGuestCartRequest) (resp GetCartResponse, err error)
+	MergeCart(ctx context.Context, req MergeCartRequest) (resp MergeCartResponse, err error)
 }
@@ -0,0 +1,559 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MemoryClient is a client of the memory storage together with its balance.
+type MemoryClient struct {
+	ID       int32
+	Username string
+	Role     string
+	Region   string
+	Balance  float64
+}
+
+// MemoryProduct is a product of the memory storage together with its stock.
+type MemoryProduct struct {
+	ID          int32
+	Name        string
+	Description string
+	Price       float64
+	Quantity    int32 // in stock, not reserved by carts
+	TaxClass    string
+	WeightGrams int
+}
+
+// MemoryData is what the memory storage starts with.
+type MemoryData struct {
+	Clients       []MemoryClient
+	Products      []MemoryProduct
+	Promotions    []Promotion
+	MarketBalance float64
+}
+
+// LoadMemoryData reads MemoryData from a JSON file.
+func LoadMemoryData(path string) (data MemoryData, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return data, fmt.Errorf("cannot read memory data: %w", err)
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return data, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// DemoMemoryData is a small catalog with a few clients to try the service
+// with.
+func DemoMemoryData() MemoryData {
+	return MemoryData{
+		Clients: []MemoryClient{
+			{ID: 1, Username: "alice", Role: "user", Region: "RU", Balance: 100000},
+			{ID: 2, Username: "bob", Role: "user", Region: "BY", Balance: 5000},
+			{ID: 3, Username: "admin", Role: "admin", Region: "RU", Balance: 0},
+		},
+		Products: []MemoryProduct{
+			{ID: 1, Name: "Laptop", Description: "14-inch laptop", Price: 79990, Quantity: 10, TaxClass: "standard", WeightGrams: 1500},
+			{ID: 2, Name: "Wireless mouse", Description: "Bluetooth mouse", Price: 1490, Quantity: 50, TaxClass: "standard", WeightGrams: 100},
+			{ID: 3, Name: "USB-C cable", Description: "1 m charging cable", Price: 390, Quantity: 200, TaxClass: "standard", WeightGrams: 50},
+			{ID: 4, Name: "Cookbook", Description: "Recipes for every day", Price: 990, Quantity: 30, TaxClass: "reduced", WeightGrams: 600},
+			{ID: 5, Name: "Desk lamp", Description: "LED desk lamp", Price: 2490, Quantity: 0, TaxClass: "standard", WeightGrams: 900},
+		},
+		Promotions: []Promotion{
+			{Code: "WELCOME10", Description: "10% off the first order", RuleType: "percentage", Percent: 10, UsageLimitPerClient: 1},
+			{Code: "MINUS500", Description: "500 off orders from 5000", RuleType: "minimum_spend", Amount: 500, MinSpend: 5000},
+		},
+	}
+}
+
+type memoryCart struct {
+	id        int32
+	items     map[int32]CartItem
+	promoCode string
+}
+
+type memoryGuestCart struct {
+	items     map[int32]CartItem
+	expiresAt time.Time
+}
+
+type memoryAddress struct {
+	Address
+	deleted bool
+}
+
+type memoryOrder struct {
+	id               int64
+	clientID         int32
+	status           string
+	total            float64
+	paymentReference string
+	promoCode        string
+	items            []CheckoutItem
+}
+
+type memoryAuthorization struct {
+	clientID  int32
+	reference string
+	status    string
+	amount    float64
+	captured  float64
+	refunded  float64
+}
+
+type redemptionKey struct {
+	code     string
+	clientID int32
+}
+
+// memoryState is everything the memory storage holds. It is copied by InTx
+// so that a failed transaction can be rolled back.
+type memoryState struct {
+	clients        map[int32]MemoryClient
+	products       map[int32]MemoryProduct
+	carts          map[int32]memoryCart // by client
+	guestCarts     map[string]memoryGuestCart
+	mergedGuests   map[string]int32 // merged guest cart token -> client
+	promotions     map[string]Promotion
+	redemptions    map[redemptionKey]int
+	addresses      map[int32]memoryAddress
+	sessions       map[string]CheckoutSession
+	orders         map[int64]memoryOrder
+	paymentEvents  map[string]struct{}
+	authorizations map[string]memoryAuthorization
+	marketBalance  float64
+
+	lastCartID    int32
+	lastAddressID int32
+	lastOrderID   int64
+}
+
+func (s *memoryState) clone() *memoryState {
+	c := *s
+	c.clients = maps.Clone(s.clients)
+	c.products = maps.Clone(s.products)
+	c.carts = make(map[int32]memoryCart, len(s.carts))
+	for id, cart := range s.carts {
+		cart.items = maps.Clone(cart.items)
+		c.carts[id] = cart
+	}
+	c.guestCarts = make(map[string]memoryGuestCart, len(s.guestCarts))
+	for token, cart := range s.guestCarts {
+		cart.items = maps.Clone(cart.items)
+		c.guestCarts[token] = cart
+	}
+	c.mergedGuests = maps.Clone(s.mergedGuests)
+	c.promotions = maps.Clone(s.promotions)
+	c.redemptions = maps.Clone(s.redemptions)
+	c.addresses = maps.Clone(s.addresses)
+	c.sessions = maps.Clone(s.sessions)
+	c.orders = maps.Clone(s.orders)
+	c.paymentEvents = maps.Clone(s.paymentEvents)
+	c.authorizations = maps.Clone(s.authorizations)
+	return &c
+}
+
+type memoryTxKey struct{}
+
+// MemoryRepository keeps everything UserRepository keeps in Postgres and
+// Redis in process memory, with the same stock, cart, checkout and payment
+// rules. Operations run one at a time. It is meant for tests and demos: the
+// data is lost on restart.
+type MemoryRepository struct {
+	cfg   atomic.Pointer[Config]
+	mu    sync.Mutex
+	state *memoryState
+	now   func() time.Time
+	log   *slog.Logger
+}
+
+func NewMemoryRepository(cfg Config, data MemoryData, log *slog.Logger) *MemoryRepository {
+	s := &memoryState{
+		clients:        make(map[int32]MemoryClient, len(data.Clients)),
+		products:       make(map[int32]MemoryProduct, len(data.Products)),
+		carts:          make(map[int32]memoryCart),
+		guestCarts:     make(map[string]memoryGuestCart),
+		mergedGuests:   make(map[string]int32),
+		promotions:     make(map[string]Promotion, len(data.Promotions)),
+		redemptions:    make(map[redemptionKey]int),
+		addresses:      make(map[int32]memoryAddress),
+		sessions:       make(map[string]CheckoutSession),
+		orders:         make(map[int64]memoryOrder),
+		paymentEvents:  make(map[string]struct{}),
+		authorizations: make(map[string]memoryAuthorization),
+		marketBalance:  data.MarketBalance,
+	}
+	for _, c := range data.Clients {
+		s.clients[c.ID] = c
+	}
+	for _, p := range data.Products {
+		s.products[p.ID] = p
+	}
+	for _, p := range data.Promotions {
+		s.promotions[p.Code] = p
+	}
+
+	m := &MemoryRepository{state: s, now: time.Now, log: log}
+	m.cfg.Store(&cfg)
+	return m
+}
+
+// Reload applies the guest cart and checkout lifetimes of cfg.
+func (m *MemoryRepository) Reload(cfg Config) {
+	m.cfg.Store(&cfg)
+}
+
+func (m *MemoryRepository) config() *Config {
+	return m.cfg.Load()
+}
+
+// lock serializes an operation with the others. Inside InTx the lock is
+// already held and the returned unlock does nothing.
+func (m *MemoryRepository) lock(ctx context.Context) (unlock func()) {
+	if ctx.Value(memoryTxKey{}) == m {
+		return func() {}
+	}
+	m.mu.Lock()
+	return m.mu.Unlock
+}
+
+// InTx runs fn with every other operation held off and undoes the changes
+// fn made when it fails. Calls made with the context passed to fn, including
+// nested InTx calls, join the transaction.
+func (m *MemoryRepository) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTxKey{}) == m {
+		return fn(ctx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := m.state.clone()
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, m)); err != nil {
+		m.state = snapshot
+		return err
+	}
+	return nil
+}
+
+func (m *MemoryRepository) FindClientByUsername(ctx context.Context, req FindClientByUsernameRequest) (resp FindClientByUsernameResponse, err error) {
+	defer m.lock(ctx)()
+
+	c, ok := m.state.clients[int32(req.ClientID)]
+	if !ok {
+		return resp, domain.NotFound("CLIENT_NOT_FOUND", "client %d not found", req.ClientID)
+	}
+	return FindClientByUsernameResponse{ClientID: int(c.ID), Username: c.Username, Role: c.Role}, nil
+}
+
+func (m *MemoryRepository) SearchProductByName(ctx context.Context, req SearchProductByNameRequest) (resp SearchProductByNameResponse, err error) {
+	if req.ProductName == "" {
+		return SearchProductByNameResponse{}, domain.InvalidArgument("product_name", "product name cannot be empty")
+	}
+
+	defer m.lock(ctx)()
+
+	name := strings.ToLower(req.ProductName)
+	resp.Products = []Product{}
+	for _, id := range sortedKeys(m.state.products) {
+		p := m.state.products[id]
+		if strings.Contains(strings.ToLower(p.Name), name) {
+			resp.Products = append(resp.Products, p.product())
+		}
+	}
+	return resp, nil
+}
+
+func (p MemoryProduct) product() Product {
+	return Product{
+		ProductID:          p.ID,
+		ProductName:        p.Name,
+		ProductDescription: p.Description,
+		ProductPrice:       FormatPrice(p.Price),
+	}
+}
+
+func (m *MemoryRepository) CreateCartIfNotExists(ctx context.Context, req CreateCartIfNotExistsRequest) (resp CreateCartIfNotExistsResponse, err error) {
+	defer m.lock(ctx)()
+
+	return CreateCartIfNotExistsResponse{CartId: m.cart(req.ClientId).id}, nil
+}
+
+// cart returns the cart of a client, creating it if needed.
+func (m *MemoryRepository) cart(clientID int32) memoryCart {
+	cart, ok := m.state.carts[clientID]
+	if !ok {
+		m.state.lastCartID++
+		cart = memoryCart{id: m.state.lastCartID, items: make(map[int32]CartItem)}
+		m.state.carts[clientID] = cart
+	}
+	return cart
+}
+
+// AddItemToCart reserves the stock of the item and adds it to the cart at
+// the current product price; a line already in the cart keeps its price.
+func (m *MemoryRepository) AddItemToCart(ctx context.Context, req AddItemToCartRequest) (resp AddItemToCartResponse, err error) {
+	defer m.lock(ctx)()
+
+	var cart memoryCart
+	if req.CartId == 0 {
+		cart = m.cart(req.ClientId)
+	} else {
+		var ok bool
+		if cart, ok = m.cartByID(req.CartId); !ok {
+			return AddItemToCartResponse{Success: false}, fmt.Errorf("failed to add item to cart: cart %d does not exist", req.CartId)
+		}
+	}
+
+	product, ok := m.state.products[req.ProductID]
+	if !ok {
+		return AddItemToCartResponse{Success: false}, fmt.Errorf("failed to retrieve product price: %w",
+			domain.NotFound("PRODUCT_NOT_FOUND", "product %d not found", req.ProductID))
+	}
+	if product.Quantity < req.Quantity {
+		return AddItemToCartResponse{Success: false}, ErrOutOfStock
+	}
+
+	product.Quantity -= req.Quantity
+	m.state.products[product.ID] = product
+
+	item, ok := cart.items[req.ProductID]
+	if !ok {
+		item = CartItem{ProductID: req.ProductID, ProductPrice: product.Price}
+	}
+	item.ProductQuantity += req.Quantity
+	cart.items[req.ProductID] = item
+
+	m.log.DebugContext(ctx, "item added to cart",
+		slog.Int("client_id", int(req.ClientId)),
+		slog.Int("product_id", int(req.ProductID)),
+		slog.Int("quantity", int(req.Quantity)),
+	)
+	return AddItemToCartResponse{Success: true}, nil
+}
+
+func (m *MemoryRepository) cartByID(cartID int32) (memoryCart, bool) {
+	for _, cart := range m.state.carts {
+		if cart.id == cartID {
+			return cart, true
+		}
+	}
+	return memoryCart{}, false
+}
+
+// DeleteItemFromCart removes one unit of the item and returns it to stock.
+func (m *MemoryRepository) DeleteItemFromCart(ctx context.Context, req DeleteItemFromCartRequest) (resp DeleteItemFromCartResponse, err error) {
+	defer m.lock(ctx)()
+
+	cart, ok := m.state.carts[req.ClientId]
+	if !ok {
+		return DeleteItemFromCartResponse{Success: false}, cartNotFound(req.ClientId)
+	}
+	item, ok := cart.items[req.ProductID]
+	if !ok {
+		return DeleteItemFromCartResponse{Success: false}, domain.NotFound("CART_ITEM_NOT_FOUND", "no items were updated or deleted")
+	}
+
+	if item.ProductQuantity--; item.ProductQuantity > 0 {
+		cart.items[req.ProductID] = item
+	} else {
+		delete(cart.items, req.ProductID)
+	}
+	if product, ok := m.state.products[req.ProductID]; ok {
+		product.Quantity++
+		m.state.products[product.ID] = product
+	}
+
+	return DeleteItemFromCartResponse{Success: true}, nil
+}
+
+func (m *MemoryRepository) GetCart(ctx context.Context, req GetCartRequest) (resp GetCartResponse, err error) {
+	defer m.lock(ctx)()
+
+	cart, ok := m.state.carts[req.ClientId]
+	if !ok {
+		return GetCartResponse{}, cartNotFound(req.ClientId)
+	}
+	return cartResponse(cart.items), nil
+}
+
+func cartResponse(items map[int32]CartItem) (resp GetCartResponse) {
+	resp.CartItems = []CartItem{}
+	totalPrice := 0.0
+	for _, id := range sortedKeys(items) {
+		item := items[id]
+		resp.CartItems = append(resp.CartItems, item)
+		totalPrice += item.ProductPrice * float64(item.ProductQuantity)
+	}
+	resp.TotalPrice = FormatPrice(totalPrice)
+	return resp
+}
+
+func (m *MemoryRepository) GetProductPrices(ctx context.Context, req GetProductPricesRequest) (resp GetProductPricesResponse, err error) {
+	defer m.lock(ctx)()
+
+	resp.Prices = make(map[int32]float64, len(req.ProductIDs))
+	resp.TaxClasses = make(map[int32]string, len(req.ProductIDs))
+	resp.WeightGrams = make(map[int32]int, len(req.ProductIDs))
+	for _, id := range req.ProductIDs {
+		if p, ok := m.state.products[id]; ok {
+			resp.Prices[id] = p.Price
+			resp.TaxClasses[id] = p.TaxClass
+			resp.WeightGrams[id] = p.WeightGrams
+		}
+	}
+	return resp, nil
+}
+
+func (m *MemoryRepository) GetClientRegion(ctx context.Context, req GetClientRegionRequest) (resp GetClientRegionResponse, err error) {
+	defer m.lock(ctx)()
+
+	c, ok := m.state.clients[req.ClientId]
+	if !ok {
+		return resp, domain.NotFound("CLIENT_NOT_FOUND", "client %d not found", req.ClientId)
+	}
+	return GetClientRegionResponse{Region: c.Region}, nil
+}
+
+func (m *MemoryRepository) guestCartTTL() time.Duration {
+	if ttl := m.config().GuestCartTTLSeconds; ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return 72 * time.Hour
+}
+
+// guestCart returns an unexpired guest cart, dropping an expired one.
+func (m *MemoryRepository) guestCart(token string) (memoryGuestCart, bool) {
+	cart, ok := m.state.guestCarts[token]
+	if ok && !m.now().Before(cart.expiresAt) {
+		delete(m.state.guestCarts, token)
+		return memoryGuestCart{}, false
+	}
+	return cart, ok
+}
+
+// AddItemToGuestCart checks the stock of the item without reserving it, like
+// the Redis guest carts of UserRepository.
+func (m *MemoryRepository) AddItemToGuestCart(ctx context.Context, req AddItemToGuestCartRequest) (resp AddItemToGuestCartResponse, err error) {
+	defer m.lock(ctx)()
+
+	product, ok := m.state.products[req.ProductID]
+	if !ok {
+		return AddItemToGuestCartResponse{Success: false}, domain.NotFound("PRODUCT_NOT_FOUND", "product %d not found", req.ProductID)
+	}
+
+	cart, ok := m.guestCart(req.SessionToken)
+	if !ok {
+		cart = memoryGuestCart{items: make(map[int32]CartItem)}
+	}
+	item, ok := cart.items[req.ProductID]
+	if !ok {
+		item = CartItem{ProductID: req.ProductID, ProductPrice: product.Price}
+	}
+	if item.ProductQuantity+req.Quantity > product.Quantity {
+		return AddItemToGuestCartResponse{Success: false}, ErrOutOfStock
+	}
+
+	item.ProductQuantity += req.Quantity
+	cart.items[req.ProductID] = item
+	cart.expiresAt = m.now().Add(m.guestCartTTL())
+	m.state.guestCarts[req.SessionToken] = cart
+
+	return AddItemToGuestCartResponse{Success: true}, nil
+}
+
+func (m *MemoryRepository) DeleteItemFromGuestCart(ctx context.Context, req DeleteItemFromGuestCartRequest) (resp DeleteItemFromGuestCartResponse, err error) {
+	defer m.lock(ctx)()
+
+	cart, ok := m.guestCart(req.SessionToken)
+	if !ok {
+		return DeleteItemFromGuestCartResponse{Success: false}, domain.NotFound("CART_ITEM_NOT_FOUND", "item not found in cart")
+	}
+	item, ok := cart.items[req.ProductID]
+	if !ok {
+		return DeleteItemFromGuestCartResponse{Success: false}, domain.NotFound("CART_ITEM_NOT_FOUND", "item not found in cart")
+	}
+
+	if item.ProductQuantity--; item.ProductQuantity > 0 {
+		cart.items[req.ProductID] = item
+	} else {
+		delete(cart.items, req.ProductID)
+	}
+	if len(cart.items) == 0 {
+		delete(m.state.guestCarts, req.SessionToken)
+		return DeleteItemFromGuestCartResponse{Success: true}, nil
+	}
+	cart.expiresAt = m.now().Add(m.guestCartTTL())
+	m.state.guestCarts[req.SessionToken] = cart
+
+	return DeleteItemFromGuestCartResponse{Success: true}, nil
+}
+
+func (m *MemoryRepository) GetGuestCart(ctx context.Context, req GetGuestCartRequest) (resp GetCartResponse, err error) {
+	defer m.lock(ctx)()
+
+	cart, ok := m.guestCart(req.SessionToken)
+	if !ok {
+		return GetCartResponse{}, domain.NotFound("GUEST_CART_NOT_FOUND", "guest cart not found")
+	}
+	return cartResponse(cart.items), nil
+}
+
+// MergeCart moves the guest cart into the client cart, clamping every line
+// to the stock available and reserving it at the current product price.
+func (m *MemoryRepository) MergeCart(ctx context.Context, req MergeCartRequest) (resp MergeCartResponse, err error) {
+	defer m.lock(ctx)()
+
+	guestCart, ok := m.guestCart(req.SessionToken)
+	if _, merged := m.state.mergedGuests[req.SessionToken]; merged {
+		delete(m.state.guestCarts, req.SessionToken)
+		ok = false
+	}
+	if !ok {
+		return MergeCartResponse{}, domain.NotFound("GUEST_CART_NOT_FOUND", "guest cart not found")
+	}
+
+	cart := m.cart(req.ClientId)
+	resp.Items = []MergedItem{}
+	for _, id := range sortedKeys(guestCart.items) {
+		requested := guestCart.items[id].ProductQuantity
+		merged := MergedItem{ProductID: id, RequestedQuantity: requested}
+
+		if product, ok := m.state.products[id]; ok && product.Quantity > 0 {
+			merged.MergedQuantity = min(product.Quantity, requested)
+			product.Quantity -= merged.MergedQuantity
+			m.state.products[id] = product
+
+			item, ok := cart.items[id]
+			if !ok {
+				item = CartItem{ProductID: id, ProductPrice: product.Price}
+			}
+			item.ProductQuantity += merged.MergedQuantity
+			cart.items[id] = item
+		}
+
+		resp.Items = append(resp.Items, merged)
+	}
+	delete(m.state.guestCarts, req.SessionToken)
+	m.state.mergedGuests[req.SessionToken] = req.ClientId
+
+	return resp, nil
+}
+
+func sortedKeys[K int32 | int64, V any](m map[K]V) []K {
+	keys := slices.Collect(maps.Keys(m))
+	slices.Sort(keys)
+	return keys
+}
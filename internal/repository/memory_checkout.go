@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"time"
+)
+
+func (m *MemoryRepository) checkoutTTL() time.Duration {
+	if ttl := m.config().CheckoutTTLSeconds; ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return 15 * time.Minute
+}
+
+// CreateCheckoutSession freezes the cart contents at current product prices,
+// see UserRepository.CreateCheckoutSession.
+func (m *MemoryRepository) CreateCheckoutSession(ctx context.Context, req CreateCheckoutSessionRequest) (resp CreateCheckoutSessionResponse, err error) {
+	defer m.lock(ctx)()
+
+	cart, ok := m.state.carts[req.ClientId]
+	if !ok {
+		return resp, cartNotFound(req.ClientId)
+	}
+
+	var (
+		itemsTotal float64
+		items      []CheckoutItem
+	)
+	for _, id := range sortedKeys(cart.items) {
+		price := m.state.products[id].Price
+		items = append(items, CheckoutItem{ProductID: id, ProductQuantity: cart.items[id].ProductQuantity, ProductPrice: price})
+		itemsTotal += price * float64(cart.items[id].ProductQuantity)
+	}
+	if itemsTotal == 0 {
+		return resp, domain.Conflict("CART_EMPTY", "cart is empty")
+	}
+	if FormatPrice(itemsTotal) != FormatPrice(req.ExpectedTotal) {
+		return resp, fmt.Errorf("%w: expected %s, actual %s", ErrPriceChanged, FormatPrice(req.ExpectedTotal), FormatPrice(itemsTotal))
+	}
+	if _, ok := m.state.sessions[req.ID]; ok {
+		return resp, fmt.Errorf("failed to create checkout session: session %s already exists", req.ID)
+	}
+
+	for _, item := range items {
+		line := cart.items[item.ProductID]
+		line.ProductPrice = item.ProductPrice
+		cart.items[item.ProductID] = line
+	}
+
+	for id, s := range m.state.sessions {
+		if s.ClientId == req.ClientId && s.Status == CheckoutStatusOpen {
+			s.Status = CheckoutStatusSuperseded
+			m.state.sessions[id] = s
+		}
+	}
+
+	total := itemsTotal - req.Discount
+	if total < 0 {
+		total = 0
+	}
+	total += req.Tax + req.ShippingCost
+
+	s := CheckoutSession{
+		ID:             req.ID,
+		ClientId:       req.ClientId,
+		CartId:         cart.id,
+		Status:         CheckoutStatusOpen,
+		ItemsTotal:     itemsTotal,
+		Discount:       req.Discount,
+		Tax:            req.Tax,
+		ShippingCost:   req.ShippingCost,
+		Total:          total,
+		PromoCode:      req.PromoCode,
+		AddressID:      req.AddressID,
+		ShippingOption: req.ShippingOption,
+		ExpiresAt:      m.now().Add(m.checkoutTTL()),
+		Items:          items,
+	}
+	m.state.sessions[s.ID] = s
+
+	return CreateCheckoutSessionResponse{Session: s}, nil
+}
+
+// GetCheckoutSession returns an open, unexpired checkout session.
+func (m *MemoryRepository) GetCheckoutSession(ctx context.Context, req GetCheckoutSessionRequest) (resp GetCheckoutSessionResponse, err error) {
+	defer m.lock(ctx)()
+
+	s, err := m.openCheckoutSession(req.ID, req.ClientId)
+	if err != nil {
+		return resp, err
+	}
+	return GetCheckoutSessionResponse{Session: s}, nil
+}
+
+func (m *MemoryRepository) openCheckoutSession(id string, clientID int32) (CheckoutSession, error) {
+	s, ok := m.state.sessions[id]
+	if !ok || s.ClientId != clientID {
+		return CheckoutSession{}, ErrCheckoutNotFound
+	}
+	if s.Status != CheckoutStatusOpen {
+		return s, fmt.Errorf("%w: status %s", ErrCheckoutClosed, s.Status)
+	}
+	if !m.now().Before(s.ExpiresAt) {
+		return s, fmt.Errorf("%w at %s", ErrCheckoutExpired, s.ExpiresAt)
+	}
+	return s, nil
+}
+
+// ConfirmCheckoutSession turns the frozen items of an open session into a
+// pending order, see UserRepository.ConfirmCheckoutSession.
+func (m *MemoryRepository) ConfirmCheckoutSession(ctx context.Context, req ConfirmCheckoutSessionRequest) (resp ConfirmCheckoutSessionResponse, err error) {
+	defer m.lock(ctx)()
+
+	s, err := m.openCheckoutSession(req.ID, req.ClientId)
+	if err != nil {
+		return resp, err
+	}
+
+	cart, ok := m.state.carts[req.ClientId]
+	if !ok {
+		return resp, cartNotFound(req.ClientId)
+	}
+	if len(cart.items) != len(s.Items) {
+		return resp, ErrCheckoutStale
+	}
+	for _, item := range s.Items {
+		if cart.items[item.ProductID].ProductQuantity != item.ProductQuantity {
+			return resp, ErrCheckoutStale
+		}
+	}
+
+	if s.PromoCode != "" {
+		p, ok := m.state.promotions[s.PromoCode]
+		key := redemptionKey{code: s.PromoCode, clientID: req.ClientId}
+		if !ok || p.UsageLimitPerClient != 0 && m.state.redemptions[key] >= p.UsageLimitPerClient {
+			return resp, ErrPromoUsageLimit
+		}
+		m.state.redemptions[key]++
+	}
+
+	m.state.lastOrderID++
+	orderID := m.state.lastOrderID
+	m.state.orders[orderID] = memoryOrder{
+		id:               orderID,
+		clientID:         req.ClientId,
+		status:           OrderStatusPending,
+		total:            s.Total,
+		paymentReference: req.PaymentReference,
+		promoCode:        s.PromoCode,
+		items:            s.Items,
+	}
+
+	cart.promoCode = ""
+	cart.items = make(map[int32]CartItem)
+	m.state.carts[req.ClientId] = cart
+
+	s.Status = CheckoutStatusConfirmed
+	m.state.sessions[s.ID] = s
+
+	return ConfirmCheckoutSessionResponse{
+		Success:      true,
+		OrderID:      orderID,
+		ChargedTotal: s.Total,
+	}, nil
+}
+
+// SetOrderStatus moves an order to a new payment status.
+func (m *MemoryRepository) SetOrderStatus(ctx context.Context, req SetOrderStatusRequest) (resp SetOrderStatusResponse, err error) {
+	defer m.lock(ctx)()
+
+	o, ok := m.state.orders[req.OrderID]
+	if !ok {
+		return resp, domain.NotFound("ORDER_NOT_FOUND", "order %d not found", req.OrderID)
+	}
+	o.status = req.Status
+	m.state.orders[o.id] = o
+
+	return SetOrderStatusResponse{Success: true}, nil
+}
+
+// FailOrder marks a pending order failed and undoes the checkout that
+// created it, see UserRepository.FailOrder.
+func (m *MemoryRepository) FailOrder(ctx context.Context, req FailOrderRequest) (resp FailOrderResponse, err error) {
+	defer m.lock(ctx)()
+
+	o, ok := m.state.orders[req.OrderID]
+	if !ok {
+		return resp, domain.NotFound("ORDER_NOT_FOUND", "order %d not found", req.OrderID)
+	}
+	switch o.status {
+	case OrderStatusFailed:
+		return resp, nil
+	case OrderStatusPending:
+	default:
+		return resp, fmt.Errorf("%w: order %d is %s", ErrOrderStatusConflict, o.id, o.status)
+	}
+
+	o.status = OrderStatusFailed
+	m.state.orders[o.id] = o
+	m.restoreOrder(o)
+
+	return FailOrderResponse{Changed: true}, nil
+}
+
+// restoreOrder returns the items of an order to the client cart, where they
+// hold their stock again, and releases its promo code redemption.
+func (m *MemoryRepository) restoreOrder(o memoryOrder) {
+	cart := m.cart(o.clientID)
+	for _, item := range o.items {
+		line, ok := cart.items[item.ProductID]
+		if !ok {
+			line = CartItem{ProductID: item.ProductID, ProductPrice: item.ProductPrice}
+		}
+		line.ProductQuantity += item.ProductQuantity
+		cart.items[item.ProductID] = line
+	}
+
+	if o.promoCode != "" {
+		key := redemptionKey{code: o.promoCode, clientID: o.clientID}
+		if m.state.redemptions[key]--; m.state.redemptions[key] <= 0 {
+			delete(m.state.redemptions, key)
+		}
+		if cart.promoCode == "" {
+			cart.promoCode = o.promoCode
+		}
+	}
+	m.state.carts[o.clientID] = cart
+}
+
+// SettleOrderPayment applies an asynchronous payment result, see
+// UserRepository.SettleOrderPayment.
+func (m *MemoryRepository) SettleOrderPayment(ctx context.Context, req SettleOrderPaymentRequest) (resp SettleOrderPaymentResponse, err error) {
+	defer m.lock(ctx)()
+
+	var (
+		o     memoryOrder
+		found bool
+	)
+	for _, id := range sortedKeys(m.state.orders) {
+		if m.state.orders[id].paymentReference == req.PaymentReference {
+			o, found = m.state.orders[id], true
+			break
+		}
+	}
+	if !found {
+		return resp, fmt.Errorf("%w: payment reference %s", ErrOrderNotFound, req.PaymentReference)
+	}
+	resp = SettleOrderPaymentResponse{OrderID: o.id, ClientId: o.clientID, Status: o.status, Total: o.total}
+
+	_, redelivered := m.state.paymentEvents[req.EventID]
+	switch {
+	case redelivered, o.status == req.Status:
+		// Redelivery: the order already reflects this result.
+	case o.status != OrderStatusPending:
+		return resp, fmt.Errorf("%w: order %d is %s, got %s", ErrOrderStatusConflict, o.id, o.status, req.Status)
+	default:
+		o.status = req.Status
+		m.state.orders[o.id] = o
+		if o.status == OrderStatusFailed {
+			m.restoreOrder(o)
+		}
+		resp.Status = req.Status
+		resp.Changed = true
+	}
+	m.state.paymentEvents[req.EventID] = struct{}{}
+
+	return resp, nil
+}
+
+func (m *MemoryRepository) GetPromotion(ctx context.Context, req GetPromotionRequest) (resp GetPromotionResponse, err error) {
+	defer m.lock(ctx)()
+
+	p, ok := m.state.promotions[req.Code]
+	if !ok {
+		return resp, domain.NotFound("PROMO_NOT_FOUND", "promo code %q not found", req.Code)
+	}
+	return GetPromotionResponse{Promotion: p}, nil
+}
+
+func (m *MemoryRepository) CountPromoRedemptions(ctx context.Context, req CountPromoRedemptionsRequest) (resp CountPromoRedemptionsResponse, err error) {
+	defer m.lock(ctx)()
+
+	return CountPromoRedemptionsResponse{Count: m.state.redemptions[redemptionKey{code: req.Code, clientID: req.ClientId}]}, nil
+}
+
+// SetCartPromoCode attaches a promo code to the client cart, an empty code
+// detaches it.
+func (m *MemoryRepository) SetCartPromoCode(ctx context.Context, req SetCartPromoCodeRequest) (resp SetCartPromoCodeResponse, err error) {
+	defer m.lock(ctx)()
+
+	cart, ok := m.state.carts[req.ClientId]
+	if !ok {
+		return SetCartPromoCodeResponse{Success: false}, cartNotFound(req.ClientId)
+	}
+	cart.promoCode = req.Code
+	m.state.carts[req.ClientId] = cart
+
+	return SetCartPromoCodeResponse{Success: true}, nil
+}
+
+func (m *MemoryRepository) GetCartPromoCode(ctx context.Context, req GetCartPromoCodeRequest) (resp GetCartPromoCodeResponse, err error) {
+	defer m.lock(ctx)()
+
+	cart, ok := m.state.carts[req.ClientId]
+	if !ok {
+		return resp, cartNotFound(req.ClientId)
+	}
+	return GetCartPromoCodeResponse{Code: cart.promoCode}, nil
+}
+
+func (m *MemoryRepository) AddAddress(ctx context.Context, req AddAddressRequest) (resp AddAddressResponse, err error) {
+	defer m.lock(ctx)()
+
+	m.state.lastAddressID++
+	a := req.Address
+	a.AddressID = m.state.lastAddressID
+	m.state.addresses[a.AddressID] = memoryAddress{Address: a}
+
+	return AddAddressResponse{AddressID: a.AddressID}, nil
+}
+
+func (m *MemoryRepository) ListAddresses(ctx context.Context, req ListAddressesRequest) (resp ListAddressesResponse, err error) {
+	defer m.lock(ctx)()
+
+	resp.Addresses = []Address{}
+	for _, id := range sortedKeys(m.state.addresses) {
+		if a := m.state.addresses[id]; !a.deleted && a.ClientId == req.ClientId {
+			resp.Addresses = append(resp.Addresses, a.Address)
+		}
+	}
+	return resp, nil
+}
+
+func (m *MemoryRepository) GetAddress(ctx context.Context, req GetAddressRequest) (resp GetAddressResponse, err error) {
+	defer m.lock(ctx)()
+
+	a, ok := m.state.addresses[req.AddressID]
+	if !ok || a.deleted || a.ClientId != req.ClientId {
+		return resp, domain.NotFound("ADDRESS_NOT_FOUND", "address %d not found for user_id %d", req.AddressID, req.ClientId)
+	}
+	return GetAddressResponse{Address: a.Address}, nil
+}
+
+func (m *MemoryRepository) DeleteAddress(ctx context.Context, req DeleteAddressRequest) (resp DeleteAddressResponse, err error) {
+	defer m.lock(ctx)()
+
+	a, ok := m.state.addresses[req.AddressID]
+	if !ok || a.deleted || a.ClientId != req.ClientId {
+		return DeleteAddressResponse{Success: false}, domain.NotFound("ADDRESS_NOT_FOUND", "address %d not found for user_id %d", req.AddressID, req.ClientId)
+	}
+	a.deleted = true
+	m.state.addresses[a.AddressID] = a
+
+	return DeleteAddressResponse{Success: true}, nil
+}
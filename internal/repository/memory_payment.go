@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
+)
+
+// MemoryBalanceProvider is BalanceProvider for the memory storage: it pays
+// from the client balances of a MemoryRepository.
+type MemoryBalanceProvider struct {
+	m *MemoryRepository
+}
+
+func NewMemoryBalanceProvider(m *MemoryRepository) *MemoryBalanceProvider {
+	return &MemoryBalanceProvider{m: m}
+}
+
+func (p *MemoryBalanceProvider) Name() string {
+	return payment.ProviderBalance
+}
+
+func (p *MemoryBalanceProvider) Authorize(ctx context.Context, req payment.AuthorizeRequest) (auth payment.Authorization, err error) {
+	if req.Amount <= 0 {
+		return auth, domain.InvalidArgument("amount", "invalid amount %.2f", req.Amount)
+	}
+
+	id, err := payment.NewAuthorizationID("bal")
+	if err != nil {
+		return auth, err
+	}
+
+	defer p.m.lock(ctx)()
+	s := p.m.state
+
+	c, ok := s.clients[req.ClientId]
+	if !ok || c.Balance < req.Amount {
+		return auth, payment.ErrInsufficientFunds
+	}
+	c.Balance -= req.Amount
+	s.clients[c.ID] = c
+
+	s.authorizations[id] = memoryAuthorization{
+		clientID:  req.ClientId,
+		reference: req.Reference,
+		status:    payment.StatusAuthorized,
+		amount:    req.Amount,
+	}
+
+	return payment.Authorization{ID: id, Amount: req.Amount}, nil
+}
+
+func (p *MemoryBalanceProvider) Capture(ctx context.Context, req payment.CaptureRequest) error {
+	if req.Amount <= 0 {
+		return domain.InvalidArgument("amount", "invalid capture amount %.2f", req.Amount)
+	}
+
+	defer p.m.lock(ctx)()
+	s := p.m.state
+
+	a, ok := s.authorizations[req.AuthorizationID]
+	if !ok {
+		return payment.ErrAuthorizationNotFound
+	}
+	if a.status != payment.StatusAuthorized || a.amount < req.Amount {
+		return invalidAuthorizationState(req.AuthorizationID, "capture")
+	}
+	a.status = payment.StatusCaptured
+	a.captured = req.Amount
+	s.authorizations[req.AuthorizationID] = a
+
+	s.marketBalance += req.Amount
+	if rest := a.amount - req.Amount; rest > 0 {
+		p.credit(a.clientID, rest)
+	}
+	return nil
+}
+
+func (p *MemoryBalanceProvider) Void(ctx context.Context, req payment.VoidRequest) error {
+	defer p.m.lock(ctx)()
+	s := p.m.state
+
+	a, ok := s.authorizations[req.AuthorizationID]
+	if !ok {
+		return payment.ErrAuthorizationNotFound
+	}
+	if a.status != payment.StatusAuthorized {
+		return invalidAuthorizationState(req.AuthorizationID, "void")
+	}
+	a.status = payment.StatusVoided
+	s.authorizations[req.AuthorizationID] = a
+
+	p.credit(a.clientID, a.amount)
+	return nil
+}
+
+func (p *MemoryBalanceProvider) Refund(ctx context.Context, req payment.RefundRequest) error {
+	if req.Amount <= 0 {
+		return domain.InvalidArgument("amount", "invalid refund amount %.2f", req.Amount)
+	}
+
+	defer p.m.lock(ctx)()
+	s := p.m.state
+
+	a, ok := s.authorizations[req.AuthorizationID]
+	if !ok {
+		return payment.ErrAuthorizationNotFound
+	}
+	if a.status != payment.StatusCaptured || a.refunded+req.Amount > a.captured {
+		return invalidAuthorizationState(req.AuthorizationID, "refund")
+	}
+	if s.marketBalance < req.Amount {
+		return domain.InsufficientFunds("недостаточно средств на кошельке магазина для возврата")
+	}
+
+	a.refunded += req.Amount
+	if a.refunded >= a.captured {
+		a.status = payment.StatusRefunded
+	}
+	s.authorizations[req.AuthorizationID] = a
+
+	s.marketBalance -= req.Amount
+	p.credit(a.clientID, req.Amount)
+	return nil
+}
+
+func (p *MemoryBalanceProvider) credit(clientID int32, amount float64) {
+	if c, ok := p.m.state.clients[clientID]; ok {
+		c.Balance += amount
+		p.m.state.clients[clientID] = c
+	}
+}
+
+func invalidAuthorizationState(id, op string) error {
+	return fmt.Errorf("%w: cannot %s authorization %s", payment.ErrInvalidState, op, id)
+}
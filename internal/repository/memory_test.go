@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func newMemoryContractStore(t *testing.T, data MemoryData) contractStore {
+	m := NewMemoryRepository(Config{}, data, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return contractStore{
+		repo:     m,
+		payments: NewMemoryBalanceProvider(m),
+		stock: func(t *testing.T, productID int32) int32 {
+			defer m.lock(context.Background())()
+			return m.state.products[productID].Quantity
+		},
+		balance: func(t *testing.T, clientID int32) float64 {
+			defer m.lock(context.Background())()
+			return m.state.clients[clientID].Balance
+		},
+		market: func(t *testing.T) float64 {
+			defer m.lock(context.Background())()
+			return m.state.marketBalance
+		},
+		setPrice: func(t *testing.T, productID int32, price float64) {
+			defer m.lock(context.Background())()
+			product := m.state.products[productID]
+			product.Price = price
+			m.state.products[productID] = product
+		},
+	}
+}
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	testRepositoryContract(t, newMemoryContractStore)
+}
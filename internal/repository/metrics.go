@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	cartCacheReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "marketserv_cart_cache_reads_total",
+		Help: "Reads of cart:* hashes in Redis by result: hit, miss or error.",
+	}, []string{"result"})
+	cartsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "marketserv_carts_created_total",
+		Help: "Carts created in Postgres.",
+	})
+	txRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "marketserv_repository_tx_retries_total",
+		Help: "Serializable transactions run again after a serialization failure.",
+	})
+)
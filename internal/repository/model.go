@@ -1,5 +1,10 @@
 package repository
 
+import (
+	"database/sql"
+	"time"
+)
+
 type FindClientByUsernameRequest struct {
 	ClientID int
 }
@@ -71,11 +76,244 @@ type GetCartResponse struct {
 	TotalPrice string
 }
 
-type PaymentRequest struct {
+type GetProductPricesRequest struct {
+	ProductIDs []int32 `json:"product_ids"`
+}
+
+type GetProductPricesResponse struct {
+	Prices      map[int32]float64
+	TaxClasses  map[int32]string
+	WeightGrams map[int32]int
+}
+
+type GetClientRegionRequest struct {
+	ClientId int32 `json:"client_id" db:"client_id"`
+}
+
+type GetClientRegionResponse struct {
+	Region string `json:"region" db:"region"`
+}
+
+type AddItemToGuestCartRequest struct {
+	SessionToken string `json:"session_token"`
+	ProductID    int32  `json:"product_id" db:"product_id"`
+	Quantity     int32  `json:"quantity" db:"quantity"`
+}
+
+type AddItemToGuestCartResponse struct {
+	Success bool `json:"add success"`
+}
+
+type DeleteItemFromGuestCartRequest struct {
+	SessionToken string `json:"session_token"`
+	ProductID    int32  `json:"product_id" db:"product_id"`
+}
+
+type DeleteItemFromGuestCartResponse struct {
+	Success bool `json:"delete success"`
+}
+
+type GetGuestCartRequest struct {
+	SessionToken string `json:"session_token"`
+}
+
+type MergeCartRequest struct {
+	SessionToken string `json:"session_token"`
+	ClientId     int32  `json:"client_id" db:"client_id"`
+}
+
+type MergedItem struct {
+	ProductID         int32 `json:"product_id" db:"product_id"`
+	RequestedQuantity int32 `json:"requested_quantity"`
+	MergedQuantity    int32 `json:"merged_quantity"`
+}
+
+type MergeCartResponse struct {
+	Items []MergedItem
+}
+
+type Promotion struct {
+	Code                string       `json:"code" db:"code"`
+	Description         string       `json:"description" db:"description"`
+	RuleType            string       `json:"rule_type" db:"rule_type"`
+	Percent             float64      `json:"percent" db:"percent"`
+	Amount              float64      `json:"amount" db:"amount"`
+	ProductID           int32        `json:"product_id" db:"product_id"`
+	BuyQuantity         int32        `json:"buy_quantity" db:"buy_quantity"`
+	FreeQuantity        int32        `json:"free_quantity" db:"free_quantity"`
+	MinSpend            float64      `json:"min_spend" db:"min_spend"`
+	ValidFrom           sql.NullTime `json:"valid_from" db:"valid_from"`
+	ValidUntil          sql.NullTime `json:"valid_until" db:"valid_until"`
+	UsageLimitPerClient int          `json:"usage_limit_per_client" db:"usage_limit_per_client"`
+}
+
+type GetPromotionRequest struct {
+	Code string `json:"code" db:"code"`
+}
+
+type GetPromotionResponse struct {
+	Promotion Promotion
+}
+
+type CountPromoRedemptionsRequest struct {
+	Code     string `json:"code" db:"code"`
+	ClientId int32  `json:"client_id" db:"client_id"`
+}
+
+type CountPromoRedemptionsResponse struct {
+	Count int `json:"count"`
+}
+
+type SetCartPromoCodeRequest struct {
+	ClientId int32  `json:"client_id" db:"client_id"`
+	Code     string `json:"code" db:"promo_code"`
+}
+
+type SetCartPromoCodeResponse struct {
+	Success bool `json:"set success"`
+}
+
+type GetCartPromoCodeRequest struct {
+	ClientId int32 `json:"client_id" db:"client_id"`
+}
+
+type GetCartPromoCodeResponse struct {
+	Code string `json:"code" db:"promo_code"`
+}
+
+type Address struct {
+	AddressID  int32  `json:"id" db:"id"`
+	ClientId   int32  `json:"client_id" db:"client_id"`
+	Recipient  string `json:"recipient" db:"recipient"`
+	Line1      string `json:"line1" db:"line1"`
+	Line2      string `json:"line2" db:"line2"`
+	City       string `json:"city" db:"city"`
+	PostalCode string `json:"postal_code" db:"postal_code"`
+	Country    string `json:"country" db:"country"`
+}
+
+type AddAddressRequest struct {
+	Address Address
+}
+
+type AddAddressResponse struct {
+	AddressID int32 `json:"id" db:"id"`
+}
+
+type ListAddressesRequest struct {
 	ClientId int32 `json:"client_id" db:"client_id"`
 }
 
-type PaymentResponse struct {
-	Success bool `json:"payment success"`
-	Message string
+type ListAddressesResponse struct {
+	Addresses []Address
+}
+
+type GetAddressRequest struct {
+	ClientId  int32 `json:"client_id" db:"client_id"`
+	AddressID int32 `json:"address_id" db:"id"`
+}
+
+type GetAddressResponse struct {
+	Address Address
+}
+
+type DeleteAddressRequest struct {
+	ClientId  int32 `json:"client_id" db:"client_id"`
+	AddressID int32 `json:"address_id" db:"id"`
+}
+
+type DeleteAddressResponse struct {
+	Success bool `json:"delete success"`
+}
+
+type CheckoutItem struct {
+	ProductID       int32   `json:"product_id" db:"product_id"`
+	ProductQuantity int32   `json:"quantity" db:"quantity"`
+	ProductPrice    float64 `json:"price" db:"price"`
+}
+
+type CheckoutSession struct {
+	ID             string    `json:"id" db:"id"`
+	ClientId       int32     `json:"client_id" db:"client_id"`
+	CartId         int32     `json:"cart_id" db:"cart_id"`
+	Status         string    `json:"status" db:"status"`
+	ItemsTotal     float64   `json:"items_total" db:"items_total"`
+	Discount       float64   `json:"discount" db:"discount"`
+	Tax            float64   `json:"tax" db:"tax"` // added on top of the discounted items total
+	ShippingCost   float64   `json:"shipping_cost" db:"shipping_cost"`
+	Total          float64   `json:"total" db:"total"`
+	PromoCode      string    `json:"promo_code" db:"promo_code"`
+	AddressID      int32     `json:"address_id" db:"address_id"`
+	ShippingOption string    `json:"shipping_option" db:"shipping_option"`
+	ExpiresAt      time.Time `json:"expires_at" db:"expires_at"`
+	Items          []CheckoutItem
+}
+
+type CreateCheckoutSessionRequest struct {
+	ID             string  `json:"id" db:"id"`
+	ClientId       int32   `json:"client_id" db:"client_id"`
+	ExpectedTotal  float64 `json:"expected_total"`
+	PromoCode      string  `json:"promo_code" db:"promo_code"`
+	Discount       float64 `json:"discount" db:"discount"`
+	Tax            float64 `json:"tax" db:"tax"`
+	ShippingCost   float64 `json:"shipping_cost" db:"shipping_cost"`
+	AddressID      int32   `json:"address_id" db:"address_id"`
+	ShippingOption string  `json:"shipping_option" db:"shipping_option"`
+}
+
+type CreateCheckoutSessionResponse struct {
+	Session CheckoutSession
+}
+
+type GetCheckoutSessionRequest struct {
+	ID       string `json:"id" db:"id"`
+	ClientId int32  `json:"client_id" db:"client_id"`
+}
+
+type GetCheckoutSessionResponse struct {
+	Session CheckoutSession `json:"session"`
+}
+
+type ConfirmCheckoutSessionRequest struct {
+	ID               string `json:"id" db:"id"`
+	ClientId         int32  `json:"client_id" db:"client_id"`
+	PaymentProvider  string `json:"payment_provider" db:"payment_provider"`
+	PaymentReference string `json:"payment_reference" db:"payment_reference"`
+}
+
+type ConfirmCheckoutSessionResponse struct {
+	Success      bool    `json:"confirm success"`
+	OrderID      int64   `json:"order_id" db:"order_id"`
+	ChargedTotal float64 `json:"charged_total"`
+}
+
+type SetOrderStatusRequest struct {
+	OrderID int64  `json:"order_id" db:"order_id"`
+	Status  string `json:"status" db:"status"`
+}
+
+type SetOrderStatusResponse struct {
+	Success bool `json:"set status success"`
+}
+
+type FailOrderRequest struct {
+	OrderID int64 `json:"order_id" db:"order_id"`
+}
+
+type FailOrderResponse struct {
+	Changed bool `json:"changed"` // false for an order failed already
+}
+
+type SettleOrderPaymentRequest struct {
+	EventID          string `json:"event_id" db:"event_id"`
+	PaymentReference string `json:"payment_reference" db:"payment_reference"`
+	Status           string `json:"status" db:"status"` // OrderStatusPaid or OrderStatusFailed
+}
+
+type SettleOrderPaymentResponse struct {
+	OrderID  int64   `json:"order_id" db:"order_id"`
+	ClientId int32   `json:"client_id" db:"client_id"`
+	Status   string  `json:"status" db:"status"`
+	Total    float64 `json:"total" db:"total"`
+	Changed  bool    `json:"changed"` // false for a redelivered or repeated settlement
 }
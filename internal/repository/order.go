@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+)
+
+const (
+	OrderStatusPending = "pending"
+	OrderStatusPaid    = "paid"
+	OrderStatusFailed  = "failed"
+)
+
+const (
+	CheckoutStatusOpen       = "open"
+	CheckoutStatusConfirmed  = "confirmed"
+	CheckoutStatusSuperseded = "superseded"
+)
+
+// SettleOrderPayment applies an asynchronous payment result to the order
+// paid through req.PaymentReference. Only pending orders change status; a
+// failed payment undoes the checkout like FailOrder. Redelivered events and
+// repeated results are acknowledged without changes.
+func (r *UserRepository) SettleOrderPayment(ctx context.Context, req SettleOrderPaymentRequest) (resp SettleOrderPaymentResponse, err error) {
+	err = r.InTx(ctx, func(ctx context.Context) error {
+		resp = SettleOrderPaymentResponse{} // a retried attempt starts over
+		var promoCode string
+		err := r.conn(ctx).QueryRowContext(ctx, LockOrderByPaymentReferenceSQL, req.PaymentReference).
+			Scan(&resp.OrderID, &resp.ClientId, &resp.Status, &resp.Total, &promoCode)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: payment reference %s", ErrOrderNotFound, req.PaymentReference)
+			}
+			return fmt.Errorf("failed to lock order: %w", err)
+		}
+
+		result, err := r.conn(ctx).ExecContext(ctx, RecordPaymentEventSQL, req.EventID, req.PaymentReference, req.Status)
+		if err != nil {
+			return fmt.Errorf("failed to record payment event: %w", err)
+		}
+		recorded, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		}
+
+		switch {
+		case recorded == 0, resp.Status == req.Status:
+			// Redelivery: the order already reflects this result.
+			return nil
+		case resp.Status != OrderStatusPending:
+			return fmt.Errorf("%w: order %d is %s, got %s", ErrOrderStatusConflict, resp.OrderID, resp.Status, req.Status)
+		}
+
+		if _, err := r.conn(ctx).ExecContext(ctx, SetOrderStatusSQL, resp.OrderID, req.Status); err != nil {
+			return fmt.Errorf("failed to set order status: %w", err)
+		}
+		if req.Status == OrderStatusFailed {
+			if err := r.restoreOrder(ctx, resp.OrderID, resp.ClientId, promoCode); err != nil {
+				return err
+			}
+		}
+		resp.Status = req.Status
+		resp.Changed = true
+		return nil
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// FailOrder marks a pending order failed and undoes the checkout that
+// created it: the items go back to the client cart together with the stock
+// they hold, and the promo code is released and put back on the cart. An
+// order failed already is left as is.
+func (r *UserRepository) FailOrder(ctx context.Context, req FailOrderRequest) (resp FailOrderResponse, err error) {
+	err = r.InTx(ctx, func(ctx context.Context) error {
+		resp = FailOrderResponse{} // a retried attempt starts over
+		var (
+			clientID          int32
+			status, promoCode string
+		)
+		err := r.conn(ctx).QueryRowContext(ctx, LockOrderSQL, req.OrderID).Scan(&clientID, &status, &promoCode)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return domain.NotFound("ORDER_NOT_FOUND", "order %d not found", req.OrderID)
+			}
+			return fmt.Errorf("failed to lock order: %w", err)
+		}
+
+		switch status {
+		case OrderStatusFailed:
+			return nil
+		case OrderStatusPending:
+		default:
+			return fmt.Errorf("%w: order %d is %s", ErrOrderStatusConflict, req.OrderID, status)
+		}
+
+		if _, err := r.conn(ctx).ExecContext(ctx, SetOrderStatusSQL, req.OrderID, OrderStatusFailed); err != nil {
+			return fmt.Errorf("failed to set order status: %w", err)
+		}
+		resp.Changed = true
+		return r.restoreOrder(ctx, req.OrderID, clientID, promoCode)
+	})
+	if err != nil {
+		return FailOrderResponse{}, err
+	}
+
+	return resp, nil
+}
+
+// restoreOrder returns the items of an order to the client cart and releases
+// its promo code redemption. The stock of the items was never returned, so
+// the cart holds it again.
+func (r *UserRepository) restoreOrder(ctx context.Context, orderID int64, clientID int32, promoCode string) error {
+	cart, err := r.CreateCartIfNotExists(ctx, CreateCartIfNotExistsRequest{ClientId: clientID})
+	if err != nil {
+		return err
+	}
+	if _, err := r.conn(ctx).ExecContext(ctx, RestoreOrderItemsSQL, cart.CartId, orderID); err != nil {
+		return fmt.Errorf("failed to restore order items: %w", err)
+	}
+
+	if promoCode != "" {
+		if _, err := r.conn(ctx).ExecContext(ctx, CancelPromoRedemptionSQL, promoCode, clientID); err != nil {
+			return fmt.Errorf("failed to cancel promo code redemption: %w", err)
+		}
+		if _, err := r.conn(ctx).ExecContext(ctx, RestoreCartPromoCodeSQL, clientID, promoCode); err != nil {
+			return fmt.Errorf("failed to restore cart promo code: %w", err)
+		}
+	}
+
+	return r.afterCommit(ctx, func(ctx context.Context) error {
+		if r.cacheState.skip(clientID) {
+			return nil
+		}
+		if err := r.dropCart(ctx, clientID); err != nil {
+			r.staleCart(ctx, clientID, err)
+		}
+		return nil
+	})
+}
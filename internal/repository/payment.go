@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
+	"github.com/Dmitrij-bot/marketserv/pkg/postgres"
+)
+
+// BalanceProvider pays from the internal client balance. Authorize moves the
+// amount from clients_table.invoice into a hold, Capture credits the market
+// wallet and returns what was not captured, Void returns the whole hold.
+type BalanceProvider struct {
+	db *postgres.DB
+}
+
+func NewBalanceProvider(db *postgres.DB) *BalanceProvider {
+	db.PrepareOnStart(PaymentAuthorizationExistsSQL)
+	return &BalanceProvider{db: db}
+}
+
+func (p *BalanceProvider) Name() string {
+	return payment.ProviderBalance
+}
+
+func (p *BalanceProvider) Authorize(ctx context.Context, req payment.AuthorizeRequest) (auth payment.Authorization, err error) {
+	if req.Amount <= 0 {
+		return auth, domain.InvalidArgument("amount", "invalid amount %.2f", req.Amount)
+	}
+
+	id, err := payment.NewAuthorizationID("bal")
+	if err != nil {
+		return auth, err
+	}
+
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return auth, fmt.Errorf("ошибка начала транзакции платежа: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, ChargeClientSQL, req.ClientId, req.Amount)
+	if err != nil {
+		return auth, fmt.Errorf("ошибка обработки платежа: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return auth, fmt.Errorf("ошибка проверки затронутых строк: %v", err)
+	}
+	if rowsAffected == 0 {
+		return auth, payment.ErrInsufficientFunds
+	}
+
+	_, err = tx.ExecContext(ctx, CreatePaymentAuthorizationSQL, id, req.ClientId, req.Reference, payment.StatusAuthorized, req.Amount)
+	if err != nil {
+		return auth, fmt.Errorf("ошибка сохранения авторизации платежа: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return auth, fmt.Errorf("ошибка фиксации платежа: %v", err)
+	}
+
+	return payment.Authorization{ID: id, Amount: req.Amount}, nil
+}
+
+func (p *BalanceProvider) Capture(ctx context.Context, req payment.CaptureRequest) (err error) {
+	if req.Amount <= 0 {
+		return domain.InvalidArgument("amount", "invalid capture amount %.2f", req.Amount)
+	}
+
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции платежа: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var (
+		clientID   int32
+		authorized float64
+	)
+	err = tx.QueryRowContext(ctx, CapturePaymentAuthorizationSQL,
+		req.AuthorizationID, req.Amount, payment.StatusCaptured, payment.StatusAuthorized,
+	).Scan(&clientID, &authorized)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = p.missingAuthorization(ctx, req.AuthorizationID, "capture")
+		}
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, CreditMarketSQL, req.Amount); err != nil {
+		return fmt.Errorf("ошибка зачисления платежа: %v", err)
+	}
+
+	if rest := authorized - req.Amount; rest > 0 {
+		if _, err = tx.ExecContext(ctx, CreditClientSQL, clientID, rest); err != nil {
+			return fmt.Errorf("ошибка возврата остатка: %v", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации платежа: %v", err)
+	}
+	return nil
+}
+
+func (p *BalanceProvider) Void(ctx context.Context, req payment.VoidRequest) (err error) {
+
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции платежа: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var (
+		clientID int32
+		amount   float64
+	)
+	err = tx.QueryRowContext(ctx, VoidPaymentAuthorizationSQL,
+		req.AuthorizationID, payment.StatusVoided, payment.StatusAuthorized,
+	).Scan(&clientID, &amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = p.missingAuthorization(ctx, req.AuthorizationID, "void")
+		}
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, CreditClientSQL, clientID, amount); err != nil {
+		return fmt.Errorf("ошибка возврата средств: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации платежа: %v", err)
+	}
+	return nil
+}
+
+func (p *BalanceProvider) Refund(ctx context.Context, req payment.RefundRequest) (err error) {
+	if req.Amount <= 0 {
+		return domain.InvalidArgument("amount", "invalid refund amount %.2f", req.Amount)
+	}
+
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции платежа: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var clientID int32
+	err = tx.QueryRowContext(ctx, RefundPaymentAuthorizationSQL,
+		req.AuthorizationID, req.Amount, payment.StatusRefunded, payment.StatusCaptured,
+	).Scan(&clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = p.missingAuthorization(ctx, req.AuthorizationID, "refund")
+		}
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, DebitMarketSQL, req.Amount)
+	if err != nil {
+		return fmt.Errorf("ошибка списания с кошелька магазина: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка проверки затронутых строк: %v", err)
+	}
+	if rowsAffected == 0 {
+		return domain.InsufficientFunds("недостаточно средств на кошельке магазина для возврата")
+	}
+
+	if _, err = tx.ExecContext(ctx, CreditClientSQL, clientID, req.Amount); err != nil {
+		return fmt.Errorf("ошибка возврата средств: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации платежа: %v", err)
+	}
+	return nil
+}
+
+// missingAuthorization tells an unknown authorization apart from one whose
+// state does not allow the operation.
+func (p *BalanceProvider) missingAuthorization(ctx context.Context, id, op string) error {
+	var exists bool
+	if err := p.db.QueryRowContext(ctx, PaymentAuthorizationExistsSQL, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up payment authorization: %w", err)
+	}
+	if !exists {
+		return payment.ErrAuthorizationNotFound
+	}
+	return fmt.Errorf("%w: cannot %s authorization %s", payment.ErrInvalidState, op, id)
+}
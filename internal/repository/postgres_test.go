@@ -0,0 +1,162 @@
+//go:build postgres
+
+// The contract tests against Postgres and Redis need a disposable database
+// and Redis server, everything in them is deleted:
+//
+//	MARKETSERV_TEST_POSTGRES_HOST=localhost MARKETSERV_TEST_POSTGRES_PORT=5432 \
+//	MARKETSERV_TEST_POSTGRES_USER=postgres MARKETSERV_TEST_POSTGRES_PASSWORD=secret \
+//	MARKETSERV_TEST_POSTGRES_DBNAME=marketserv_test \
+//	MARKETSERV_TEST_REDIS_HOST=localhost MARKETSERV_TEST_REDIS_PORT=6379 \
+//	go test -tags postgres ./internal/repository/
+
+package repository
+
+import (
+	"context"
+	"github.com/Dmitrij-bot/marketserv/pkg/postgres"
+	"github.com/Dmitrij-bot/marketserv/pkg/redis"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+var (
+	testStoreOnce  sync.Once
+	testDB         *postgres.DB
+	testRedis      *redis.RedisDB
+	testStoreError error
+)
+
+// startTestStore connects to the test database, creates the schema and
+// applies the migrations once per test binary.
+func startTestStore(t *testing.T) (*postgres.DB, *redis.RedisDB) {
+	t.Helper()
+	host, redisHost := os.Getenv("MARKETSERV_TEST_POSTGRES_HOST"), os.Getenv("MARKETSERV_TEST_REDIS_HOST")
+	if host == "" || redisHost == "" {
+		t.Skip("MARKETSERV_TEST_POSTGRES_HOST and MARKETSERV_TEST_REDIS_HOST are not set")
+	}
+
+	testStoreOnce.Do(func() {
+		ctx := context.Background()
+		log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		testDB = postgres.NewDB(postgres.Config{
+			DBHost:     host,
+			DBPort:     os.Getenv("MARKETSERV_TEST_POSTGRES_PORT"),
+			DBUser:     os.Getenv("MARKETSERV_TEST_POSTGRES_USER"),
+			DBPassword: os.Getenv("MARKETSERV_TEST_POSTGRES_PASSWORD"),
+			DBName:     os.Getenv("MARKETSERV_TEST_POSTGRES_DBNAME"),
+			SSLMode:    "disable",
+		}, log)
+		if testStoreError = testDB.Start(ctx); testStoreError != nil {
+			return
+		}
+
+		migrations, err := filepath.Glob("../../migrations/*.sql")
+		if err != nil {
+			testStoreError = err
+			return
+		}
+		sort.Strings(migrations)
+		for _, path := range append([]string{"testdata/base_schema.sql"}, migrations...) {
+			schema, err := os.ReadFile(path)
+			if err != nil {
+				testStoreError = err
+				return
+			}
+			if _, err := testDB.DB.ExecContext(ctx, string(schema)); err != nil {
+				testStoreError = err
+				return
+			}
+		}
+
+		testRedis = redis.NewRedisDB(redis.Config{Host: redisHost, Port: os.Getenv("MARKETSERV_TEST_REDIS_PORT")})
+		testStoreError = testRedis.Start(ctx)
+	})
+	if testStoreError != nil {
+		t.Fatalf("failed to start the test store: %v", testStoreError)
+	}
+	return testDB, testRedis
+}
+
+const truncateTestTablesSQL = `
+    TRUNCATE clients_table, products, carts, cart_items, wallet_market, promotions, promo_redemptions,
+             client_addresses, orders, order_items, checkout_sessions, checkout_session_items,
+             payment_authorizations, payment_events, merged_guest_carts
+    RESTART IDENTITY CASCADE`
+
+// seedTestStore replaces everything in the test store with data.
+func seedTestStore(t *testing.T, db *postgres.DB, redisDB *redis.RedisDB, data MemoryData) {
+	t.Helper()
+	ctx := context.Background()
+
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.DB.ExecContext(ctx, query, args...); err != nil {
+			t.Fatalf("failed to seed the test store: %v", err)
+		}
+	}
+	exec(truncateTestTablesSQL)
+	for _, c := range data.Clients {
+		exec("INSERT INTO clients_table (id, username, role, region, invoice) VALUES ($1, $2, $3, $4, $5)",
+			c.ID, c.Username, c.Role, c.Region, c.Balance)
+	}
+	for _, p := range data.Products {
+		exec("INSERT INTO products (id, name, description, price, quantity, tax_class, weight_grams) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			p.ID, p.Name, p.Description, p.Price, p.Quantity, p.TaxClass, p.WeightGrams)
+	}
+	for _, p := range data.Promotions {
+		exec(`INSERT INTO promotions (code, description, rule_type, percent, amount, product_id, buy_quantity,
+                                      free_quantity, min_spend, valid_from, valid_until, usage_limit_per_client)
+              VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7, $8, $9, $10, $11, $12)`,
+			p.Code, p.Description, p.RuleType, p.Percent, p.Amount, p.ProductID, p.BuyQuantity,
+			p.FreeQuantity, p.MinSpend, p.ValidFrom, p.ValidUntil, p.UsageLimitPerClient)
+	}
+	exec("INSERT INTO wallet_market (id, balance) VALUES (1, $1)", data.MarketBalance)
+
+	if err := redisDB.Client.FlushDB(ctx).Err(); err != nil {
+		t.Fatalf("failed to flush Redis: %v", err)
+	}
+}
+
+func newPostgresContractStore(t *testing.T, data MemoryData) contractStore {
+	db, redisDB := startTestStore(t)
+	seedTestStore(t, db, redisDB, data)
+
+	r := NewUserRepository(Config{}, db, redisDB, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	query := func(t *testing.T, dest any, query string, args ...any) {
+		t.Helper()
+		if err := db.DB.QueryRowContext(context.Background(), query, args...).Scan(dest); err != nil {
+			t.Fatalf("failed to query the test store: %v", err)
+		}
+	}
+	return contractStore{
+		repo:     r,
+		payments: NewBalanceProvider(db),
+		stock: func(t *testing.T, productID int32) (quantity int32) {
+			query(t, &quantity, "SELECT quantity FROM products WHERE id = $1", productID)
+			return quantity
+		},
+		balance: func(t *testing.T, clientID int32) (balance float64) {
+			query(t, &balance, "SELECT invoice FROM clients_table WHERE id = $1", clientID)
+			return balance
+		},
+		market: func(t *testing.T) (balance float64) {
+			query(t, &balance, "SELECT balance FROM wallet_market WHERE id = 1")
+			return balance
+		},
+		setPrice: func(t *testing.T, productID int32, price float64) {
+			if _, err := db.DB.ExecContext(context.Background(), "UPDATE products SET price = $2 WHERE id = $1", productID, price); err != nil {
+				t.Fatalf("failed to update the test store: %v", err)
+			}
+		},
+	}
+}
+
+func TestPostgresRepositoryContract(t *testing.T) {
+	testRepositoryContract(t, newPostgresContractStore)
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"github.com/Dmitrij-bot/marketserv/pkg/postgres"
+	"github.com/lib/pq"
+)
+
+// ProductUpdatesChannel is the Postgres NOTIFY channel carrying the id of a
+// changed product, see migrations/001_products_notify.sql.
+const ProductUpdatesChannel = "products_updated"
+
+func (r *UserRepository) productByID(ctx context.Context, productID int32) (Product, error) {
+	return r.products.Get(ctx, strconv.Itoa(int(productID)), func(ctx context.Context) (product Product, err error) {
+		err = r.replica(ctx).QueryRowContext(ctx, GetProductByIdSQL, productID).
+			Scan(&product.ProductID, &product.ProductName, &product.ProductDescription, &product.ProductPrice)
+		if err == sql.ErrNoRows {
+			return product, domain.NotFound("PRODUCT_NOT_FOUND", "product %d not found", productID)
+		}
+		return product, err
+	})
+}
+
+// InvalidateProduct drops the cached product and every cached search result,
+// since any of them may contain the product.
+func (r *UserRepository) InvalidateProduct(ctx context.Context, productID int32) error {
+	if err := r.products.Invalidate(ctx, strconv.Itoa(int(productID))); err != nil {
+		return fmt.Errorf("failed to invalidate product %d: %w", productID, err)
+	}
+	if err := r.searches.InvalidateAll(ctx); err != nil {
+		return fmt.Errorf("failed to invalidate product searches: %w", err)
+	}
+	return nil
+}
+
+// InvalidateAllProducts drops every cached product and search result.
+func (r *UserRepository) InvalidateAllProducts(ctx context.Context) error {
+	if err := r.products.InvalidateAll(ctx); err != nil {
+		return fmt.Errorf("failed to invalidate products: %w", err)
+	}
+	if err := r.searches.InvalidateAll(ctx); err != nil {
+		return fmt.Errorf("failed to invalidate product searches: %w", err)
+	}
+	return nil
+}
+
+func searchCacheKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// ProductInvalidator listens for product change notifications from Postgres
+// and invalidates the product caches of the repository.
+type ProductInvalidator struct {
+	db       *postgres.DB
+	repo     *UserRepository
+	listener *pq.Listener
+	wg       sync.WaitGroup
+}
+
+func NewProductInvalidator(db *postgres.DB, repo *UserRepository) *ProductInvalidator {
+	return &ProductInvalidator{
+		db:   db,
+		repo: repo,
+	}
+}
+
+func (p *ProductInvalidator) Start(ctx context.Context) error {
+	p.listener = pq.NewListener(p.db.ConnString(), 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			p.repo.log.Warn("product updates listener failed", logger.Err(err))
+		}
+	})
+
+	if err := p.listener.Listen(ProductUpdatesChannel); err != nil {
+		p.listener.Close()
+		return fmt.Errorf("failed to listen on %s: %w", ProductUpdatesChannel, err)
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for n := range p.listener.Notify {
+			p.handle(n)
+		}
+	}()
+
+	return nil
+}
+
+// handle invalidates the caches a notification affects. With read replicas
+// the caches are invalidated again once the replicas have caught up, since
+// a read in between may have cached the old product from a replica.
+func (p *ProductInvalidator) handle(n *pq.Notification) {
+	p.invalidate(n)
+
+	if lag := p.db.MaxReplicaLag(); lag > 0 {
+		p.wg.Add(1)
+		time.AfterFunc(lag, func() {
+			defer p.wg.Done()
+			p.invalidate(n)
+		})
+	}
+}
+
+func (p *ProductInvalidator) invalidate(n *pq.Notification) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A nil notification means the connection was re-established and
+	// notifications may have been lost.
+	if n == nil {
+		if err := p.repo.InvalidateAllProducts(ctx); err != nil {
+			p.repo.log.Warn("product cache invalidation failed", logger.Err(err))
+		}
+		return
+	}
+
+	productID, err := strconv.Atoi(n.Extra)
+	if err != nil {
+		p.repo.log.Warn("invalid product update payload", slog.String("payload", n.Extra), logger.Err(err))
+		return
+	}
+
+	if err := p.repo.InvalidateProduct(ctx, int32(productID)); err != nil {
+		p.repo.log.Warn("product cache invalidation failed", slog.Int("product_id", productID), logger.Err(err))
+	}
+}
+
+func (p *ProductInvalidator) Stop(ctx context.Context) error {
+	if p.listener == nil {
+		return nil
+	}
+	err := p.listener.Close()
+	p.wg.Wait()
+	return err
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+)
+
+func (r *UserRepository) GetPromotion(ctx context.Context, req GetPromotionRequest) (resp GetPromotionResponse, err error) {
+	p := &resp.Promotion
+	err = r.conn(ctx).QueryRowContext(ctx, GetPromotionSQL, req.Code).Scan(
+		&p.Code, &p.Description, &p.RuleType, &p.Percent, &p.Amount, &p.ProductID,
+		&p.BuyQuantity, &p.FreeQuantity, &p.MinSpend, &p.ValidFrom, &p.ValidUntil, &p.UsageLimitPerClient,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return resp, domain.NotFound("PROMO_NOT_FOUND", "promo code %q not found", req.Code)
+		}
+		return resp, fmt.Errorf("failed to get promotion: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *UserRepository) CountPromoRedemptions(ctx context.Context, req CountPromoRedemptionsRequest) (resp CountPromoRedemptionsResponse, err error) {
+	err = r.conn(ctx).QueryRowContext(ctx, CountPromoRedemptionsSQL, req.Code, req.ClientId).Scan(&resp.Count)
+	if err != nil {
+		return resp, fmt.Errorf("failed to count promo redemptions: %w", err)
+	}
+	return resp, nil
+}
+
+// SetCartPromoCode attaches a promo code to the client cart, an empty code
+// detaches it.
+func (r *UserRepository) SetCartPromoCode(ctx context.Context, req SetCartPromoCodeRequest) (resp SetCartPromoCodeResponse, err error) {
+	result, err := r.conn(ctx).ExecContext(ctx, SetCartPromoCodeSQL, req.ClientId, req.Code)
+	if err != nil {
+		return SetCartPromoCodeResponse{Success: false}, fmt.Errorf("failed to set cart promo code: %w", err)
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return SetCartPromoCodeResponse{Success: false}, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if affectedRows == 0 {
+		return SetCartPromoCodeResponse{Success: false}, cartNotFound(req.ClientId)
+	}
+
+	return SetCartPromoCodeResponse{Success: true}, nil
+}
+
+func (r *UserRepository) GetCartPromoCode(ctx context.Context, req GetCartPromoCodeRequest) (resp GetCartPromoCodeResponse, err error) {
+	err = r.conn(ctx).QueryRowContext(ctx, GetCartPromoCodeSQL, req.ClientId).Scan(&resp.Code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return resp, cartNotFound(req.ClientId)
+		}
+		return resp, fmt.Errorf("failed to get cart promo code: %w", err)
+	}
+	return resp, nil
+}
@@ -3,29 +3,58 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
-	"errors"
 	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
 	"github.com/Dmitrij-bot/marketserv/pkg/postgres"
 	"github.com/Dmitrij-bot/marketserv/pkg/redis"
-	redis2 "github.com/go-redis/redis/v8"
-	"log"
+	"github.com/lib/pq"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 type UserRepository struct {
+	cfg         atomic.Pointer[Config]
 	db          *postgres.DB
 	redisClient *redis.RedisDB
+	products    *redis.Cache[Product]
+	searches    *redis.Cache[[]Product]
+	cacheState  *cacheState
+	log         *slog.Logger
 }
 
-func NewUserRepository(db *postgres.DB, redisClient *redis.RedisDB) *UserRepository {
-	return &UserRepository{
+func NewUserRepository(cfg Config, db *postgres.DB, redisClient *redis.RedisDB, log *slog.Logger) *UserRepository {
+	db.PrepareOnStart(preparedSQL...)
+	r := &UserRepository{
 		db:          db,
 		redisClient: redisClient,
+		products:    redis.NewCache[Product](redisClient, "product", time.Duration(cfg.ProductTTLSeconds)*time.Second, log),
+		searches:    redis.NewCache[[]Product](redisClient, "products:search", time.Duration(cfg.SearchTTLSeconds)*time.Second, log),
+		cacheState:  newCacheState(log),
+		log:         log,
 	}
+	r.cfg.Store(&cfg)
+	return r
+}
+
+// Reload applies the cache lifetimes of cfg to values cached from now on.
+// Keys already in Redis keep the expiry they were written with.
+func (r *UserRepository) Reload(cfg Config) {
+	r.cfg.Store(&cfg)
+	r.products.SetTTL(time.Duration(cfg.ProductTTLSeconds) * time.Second)
+	r.searches.SetTTL(time.Duration(cfg.SearchTTLSeconds) * time.Second)
+}
+
+func (r *UserRepository) config() *Config {
+	return r.cfg.Load()
 }
 
 func (r *UserRepository) FindClientByUsername(ctx context.Context, req FindClientByUsernameRequest) (resp FindClientByUsernameResponse, err error) {
-	err = r.db.QueryRowContext(ctx, FindClientByUserNameSql, req.ClientID).Scan(&resp.ClientID, &resp.Username, &resp.Role)
+	err = r.conn(ctx).QueryRowContext(ctx, FindClientByUserNameSql, req.ClientID).Scan(&resp.ClientID, &resp.Username, &resp.Role)
+	if err == sql.ErrNoRows {
+		return resp, domain.NotFound("CLIENT_NOT_FOUND", "client %d not found", req.ClientID)
+	}
 	if err != nil {
 		return resp, err
 	}
@@ -35,12 +64,24 @@ func (r *UserRepository) FindClientByUsername(ctx context.Context, req FindClien
 func (r *UserRepository) SearchProductByName(ctx context.Context, req SearchProductByNameRequest) (resp SearchProductByNameResponse, err error) {
 
 	if req.ProductName == "" {
-		return SearchProductByNameResponse{}, errors.New("product name cannot be empty")
+		return SearchProductByNameResponse{}, domain.InvalidArgument("product_name", "product name cannot be empty")
 	}
 
-	rows, err := r.db.QueryContext(ctx, SearchProductByNameSQL, req.ProductName)
+	resp.Products, err = r.searches.Get(ctx, searchCacheKey(req.ProductName), func(ctx context.Context) ([]Product, error) {
+		return r.searchProducts(ctx, req.ProductName)
+	})
 	if err != nil {
-		return SearchProductByNameResponse{}, fmt.Errorf("failed to query products: %w", err)
+		return SearchProductByNameResponse{}, err
+	}
+
+	return resp, nil
+}
+
+func (r *UserRepository) searchProducts(ctx context.Context, name string) (products []Product, err error) {
+	// Search results are cached anyway, so a replica's staleness is fine.
+	rows, err := r.replica(ctx).QueryContext(ctx, SearchProductByNameSQL, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
 	}
 	defer func() {
 		if closeErr := rows.Close(); closeErr != nil {
@@ -48,34 +89,35 @@ func (r *UserRepository) SearchProductByName(ctx context.Context, req SearchProd
 		}
 	}()
 
-	resp.Products = []Product{}
+	products = []Product{}
 
 	for rows.Next() {
 		var product Product
 		if err := rows.Scan(&product.ProductID, &product.ProductName, &product.ProductDescription, &product.ProductPrice); err != nil {
-			return resp, fmt.Errorf("failed to scan product: %w", err)
+			return products, fmt.Errorf("failed to scan product: %w", err)
 		}
 
-		resp.Products = append(resp.Products, product)
+		products = append(products, product)
 	}
 
 	if err := rows.Err(); err != nil {
-		return resp, fmt.Errorf("error occurred during row iteration: %w", err)
+		return products, fmt.Errorf("error occurred during row iteration: %w", err)
 	}
 
-	return resp, nil
+	return products, nil
 }
 
 func (r *UserRepository) CreateCartIfNotExists(ctx context.Context, req CreateCartIfNotExistsRequest) (resp CreateCartIfNotExistsResponse, err error) {
 
-	err = r.db.QueryRowContext(ctx, GetCartSQL, req.ClientId).Scan(&resp.CartId)
+	err = r.conn(ctx).QueryRowContext(ctx, GetCartSQL, req.ClientId).Scan(&resp.CartId)
 
 	if err == sql.ErrNoRows {
-		err := r.db.QueryRowContext(ctx, CreateCartIfNotExistsSQL, req.ClientId).Scan(&resp.CartId)
+		err := r.conn(ctx).QueryRowContext(ctx, CreateCartIfNotExistsSQL, req.ClientId).Scan(&resp.CartId)
 
 		if err != nil {
 			return resp, fmt.Errorf("failed to create cart: %w", err)
 		}
+		cartsCreated.Inc()
 	} else if err != nil {
 		return resp, fmt.Errorf("failed to retrieve cart: %w", err)
 	}
@@ -83,272 +125,233 @@ func (r *UserRepository) CreateCartIfNotExists(ctx context.Context, req CreateCa
 	return resp, nil
 }
 
+// AddItemToCart creates the cart if needed and reserves the stock of the item
+// in one transaction, joining the transaction of ctx if there is one.
 func (r *UserRepository) AddItemToCart(ctx context.Context, req AddItemToCartRequest) (resp AddItemToCartResponse, err error) {
-	log.Printf("Received request: ProductID=%d, Quantity=%d, Price=%f", req.ProductID, req.Quantity, req.Price)
-	redisKey := fmt.Sprintf("cart:%d", req.ClientId)
-	var cartItems []CartItem
+	err = r.InTx(ctx, func(ctx context.Context) error {
+		if req.CartId == 0 {
+			cartResp, err := r.CreateCartIfNotExists(ctx, CreateCartIfNotExistsRequest{
+				ClientId: req.ClientId,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create or retrieve cart: %w", err)
+			}
+			req.CartId = cartResp.CartId
+		}
 
-	cartData, err := r.redisClient.Client.Get(ctx, redisKey).Result()
-	if err != nil {
-		if errors.Is(err, redis2.Nil) {
-			log.Printf("Cart not found for Client ID: %d", req.ClientId)
-			cartItems = []CartItem{}
-		} else {
-			return AddItemToCartResponse{Success: false}, fmt.Errorf("failed to get cart from Redis: %w", err)
+		product, err := r.productByID(ctx, req.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve product price: %w", err)
 		}
-	} else {
-		if err := json.Unmarshal([]byte(cartData), &cartItems); err != nil {
-			return AddItemToCartResponse{Success: false}, fmt.Errorf("failed to parse cart data: %w", err)
+		productPrice, err := strconv.ParseFloat(product.ProductPrice, 64)
+		if err != nil {
+			return fmt.Errorf("invalid product price %q: %w", product.ProductPrice, err)
 		}
-	}
 
-	if req.CartId == 0 {
-		log.Printf("Creating cart for Client ID: %d", req.ClientId)
-		cartResp, err := r.CreateCartIfNotExists(ctx, CreateCartIfNotExistsRequest{
-			ClientId: req.ClientId,
-		})
+		result, err := r.conn(ctx).ExecContext(ctx, AddItemToCartSQL, req.CartId, req.ProductID, req.Quantity)
 		if err != nil {
-			return AddItemToCartResponse{Success: false}, fmt.Errorf("failed to create or retrieve cart: %w", err)
+			return fmt.Errorf("failed to add item to cart: %w", err)
 		}
-		req.CartId = cartResp.CartId
-	}
-
-	var productPrice float64
-	err = r.db.QueryRowContext(ctx, "SELECT price FROM products WHERE id = $1", req.ProductID).Scan(&productPrice)
-	if err != nil {
-		return AddItemToCartResponse{Success: false}, fmt.Errorf("failed to retrieve product price: %w", err)
-	}
 
-	log.Printf("Adding item to cart: ProductID=%d, Quantity=%d, Price=%f", req.ProductID, req.Quantity, productPrice)
-	updateOrAddItem(&cartItems, req.ProductID, req.Quantity, productPrice)
-
-	updateCartData, err := json.Marshal(cartItems)
-	if err != nil {
-		return AddItemToCartResponse{Success: false}, fmt.Errorf("failed to marshal cart items: %w", err)
-	}
-	log.Printf("Updating cart data in Redis: %s", updateCartData)
-	result1 := r.redisClient.Client.Set(ctx, redisKey, updateCartData, 0)
-	if err := result1.Err(); err != nil {
-		return AddItemToCartResponse{Success: false}, fmt.Errorf("failed to save cart to Redis: %w", err)
-	}
+		affectedRows, _ := result.RowsAffected()
+		if affectedRows == 0 {
+			return ErrOutOfStock
+		}
 
-	result, err := r.db.ExecContext(ctx, AddItemToCartSQL, req.CartId, req.ProductID, req.Quantity)
+		return r.afterCommit(ctx, func(ctx context.Context) error {
+			if r.cacheState.skip(req.ClientId) {
+				return nil
+			}
+			if err := r.incrCartItem(ctx, req.ClientId, req.ProductID, req.Quantity, productPrice); err != nil {
+				r.staleCart(ctx, req.ClientId, err)
+			}
+			return nil
+		})
+	})
 	if err != nil {
-		return AddItemToCartResponse{Success: false}, fmt.Errorf("failed to add item to cart: %w", err)
+		return AddItemToCartResponse{Success: false}, err
 	}
 
-	log.Printf("Successfully saved cart to Redis for Client ID: %d with key: %s", req.ClientId, redisKey)
-
-	affectedRows, _ := result.RowsAffected()
-	if affectedRows == 0 {
-		return AddItemToCartResponse{Success: false}, fmt.Errorf("not enough quantity in stock")
-	}
-
-	log.Printf("Item successfully added to cart for Client ID: %d", req.ClientId)
+	r.log.DebugContext(ctx, "item added to cart",
+		slog.Int("client_id", int(req.ClientId)),
+		slog.Int("product_id", int(req.ProductID)),
+		slog.Int("quantity", int(req.Quantity)),
+	)
 	return AddItemToCartResponse{Success: true}, nil
 }
 
-func updateOrAddItem(cartItems *[]CartItem, productID int32, quantity int32, price float64) {
-	for i, item := range *cartItems {
-		if item.ProductID == productID {
-			(*cartItems)[i].ProductQuantity += quantity
-			return
-		}
-	}
-	*cartItems = append(*cartItems, CartItem{
-		ProductID:       productID,
-		ProductQuantity: quantity,
-		ProductPrice:    price,
-	})
-}
-
+// DeleteItemFromCart removes one unit of the item and returns it to stock in
+// one transaction, joining the transaction of ctx if there is one.
 func (r *UserRepository) DeleteItemFromCart(ctx context.Context, req DeleteItemFromCartRequest) (resp DeleteItemFromCartResponse, err error) {
-	log.Printf("Initial Client ID: %d", req.ClientId)
-	redisKey := fmt.Sprintf("cart:%d", req.ClientId)
-	var cartItems []CartItem
-
-	cartData, err := r.redisClient.Client.Get(ctx, redisKey).Result()
-	if err != nil {
-		if errors.Is(err, redis2.Nil) {
-			log.Printf("Cart not found for Client ID: %d", req.ClientId)
-			cartItems = []CartItem{}
-		} else {
-			return DeleteItemFromCartResponse{Success: false}, fmt.Errorf("failed to get cart from Redis: %w", err)
-		}
-	} else {
-		if err := json.Unmarshal([]byte(cartData), &cartItems); err != nil {
-			return DeleteItemFromCartResponse{Success: false}, fmt.Errorf("failed to parse cart data: %w", err)
+	err = r.InTx(ctx, func(ctx context.Context) error {
+		err := r.conn(ctx).QueryRowContext(ctx, GetCartSQL, req.ClientId).Scan(&req.CartId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return cartNotFound(req.ClientId)
+			}
+			return fmt.Errorf("failed to find cart_id: %w", err)
 		}
-	}
-
-	updatedItems, itemFound := decreaseItemQuantity(cartItems, req.ProductID)
 
-	if !itemFound {
-		return DeleteItemFromCartResponse{Success: false}, fmt.Errorf("item not found in cart")
-	}
-
-	updateCartData, err := json.Marshal(updatedItems)
-	if err != nil {
-		return DeleteItemFromCartResponse{Success: false}, fmt.Errorf("failed to marshal cart items: %w", err)
-	}
-	result1 := r.redisClient.Client.Set(ctx, redisKey, updateCartData, 0)
-	if err := result1.Err(); err != nil {
-		return DeleteItemFromCartResponse{Success: false}, fmt.Errorf("failed to save cart to Redis: %w", err)
-	}
-
-	err = r.db.QueryRowContext(ctx, GetCartSQL, req.ClientId).Scan(&req.CartId)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return DeleteItemFromCartResponse{Success: false}, fmt.Errorf("cart not found for user_id %d", req.ClientId)
+		result, err := r.conn(ctx).ExecContext(ctx, DeleteItemFromCartSQL2, req.CartId, req.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to delete item from cart: %w", err)
 		}
-		return DeleteItemFromCartResponse{Success: false}, fmt.Errorf("failed to find cart_id: %v", err)
-	}
 
-	result, err := r.db.ExecContext(context.Background(), DeleteItemFromCartSQL2, req.CartId, req.ProductID)
-	if err != nil {
-		return DeleteItemFromCartResponse{Success: false}, fmt.Errorf("failed to delete item from cart: %w", err)
-	}
+		affectedRows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		}
+		if affectedRows == 0 {
+			return domain.NotFound("CART_ITEM_NOT_FOUND", "no items were updated or deleted")
+		}
 
-	affectedRows, err := result.RowsAffected()
+		return r.afterCommit(ctx, func(ctx context.Context) error {
+			if r.cacheState.skip(req.ClientId) {
+				return nil
+			}
+			if err := r.decrCartItem(ctx, req.ClientId, req.ProductID); err != nil {
+				r.staleCart(ctx, req.ClientId, err)
+			}
+			return nil
+		})
+	})
 	if err != nil {
-		return DeleteItemFromCartResponse{Success: false}, fmt.Errorf("failed to check affected rows: %w", err)
-	}
-	if affectedRows == 0 {
-		return DeleteItemFromCartResponse{Success: false}, fmt.Errorf("no items were updated or deleted")
+		return DeleteItemFromCartResponse{Success: false}, err
 	}
 
 	return DeleteItemFromCartResponse{Success: true}, nil
 }
 
-func decreaseItemQuantity(cartItems []CartItem, productID int32) ([]CartItem, bool) {
-	for i, item := range cartItems {
-		if item.ProductID == productID {
-			if item.ProductQuantity > 1 {
-				item.ProductQuantity--
-				cartItems[i] = item
-				return cartItems, true
-			} else {
-				return append(cartItems[:i], cartItems[i+1:]...), true
-			}
-		}
-	}
-	return cartItems, false
-}
-
 func (r *UserRepository) GetCart(ctx context.Context, req GetCartRequest) (resp GetCartResponse, err error) {
-	redisKey := fmt.Sprintf("cart:%d", req.ClientId)
+	var found bool
 
-	log.Printf("Attempting to fetch cart from Redis with key: %s", redisKey)
-	cartData, err := r.redisClient.Client.Get(ctx, redisKey).Result()
-	if err != nil {
-		if errors.Is(err, redis2.Nil) {
-			log.Printf("Cart not found for Client ID: %d", req.ClientId)
-		} else {
-			return GetCartResponse{}, fmt.Errorf("failed to get cart from Redis: %w", err)
-		}
-	} else {
-		log.Printf("Cart data retrieved from Redis for Client ID %d: %s", req.ClientId, cartData)
-		if err := json.Unmarshal([]byte(cartData), &resp.CartItems); err != nil {
-			return GetCartResponse{}, fmt.Errorf("failed to parse cart data: %w", err)
+	if !r.cacheState.isDown() {
+		resp.CartItems, found, err = r.loadCart(ctx, req.ClientId)
+		if err != nil {
+			if err := r.cartCacheError(ctx, req.ClientId, err); err != nil {
+				return GetCartResponse{}, err
+			}
 		}
 	}
 
-	log.Printf("Cart items after unmarshal: %+v", resp.CartItems)
-
-	totalPrice := 0.0
-
-	if len(resp.CartItems) == 0 {
-		log.Printf("Cart not found in Redis, fetching from database for Client ID: %d", req.ClientId)
+	if !found {
+		r.log.DebugContext(ctx, "cart cache miss", slog.Int("client_id", int(req.ClientId)))
 
-		err = r.db.QueryRowContext(ctx, GetCartSQL, req.ClientId).Scan(&req.CartId)
+		req.CartId, resp.CartItems, err = r.loadCartFromDB(ctx, req.ClientId)
 		if err != nil {
 			if err == sql.ErrNoRows {
-				return GetCartResponse{}, fmt.Errorf("cart not found for user_id %d", req.ClientId)
+				return GetCartResponse{}, cartNotFound(req.ClientId)
 			}
-			return GetCartResponse{}, fmt.Errorf("failed to find cart_id for user_id %d: %v", req.ClientId, err)
+			return GetCartResponse{}, err
 		}
 
-		log.Printf("Found CartId for user_id %d: %d", req.ClientId, req.CartId)
-
-		rows, err := r.db.QueryContext(ctx, GetCartItemSQL, req.CartId)
-		if err != nil {
-			return GetCartResponse{}, fmt.Errorf("failed to get cart items for cart_id %d: %w", req.CartId, err)
-		}
-		defer func() {
-			if closeErr := rows.Close(); closeErr != nil {
-				err = fmt.Errorf("failed to close rows: %w", closeErr)
+		if !r.cacheState.skip(req.ClientId) {
+			if err := r.storeCart(ctx, req.ClientId, resp.CartItems); err != nil {
+				if err := r.cartCacheError(ctx, req.ClientId, err); err != nil {
+					return GetCartResponse{}, err
+				}
 			}
-		}()
-
-		log.Printf("Fetched rows for cart_id %d", req.CartId)
-
-		resp.CartItems = []CartItem{}
+		}
+	}
 
-		for rows.Next() {
-			var cartItem CartItem
-			if err := rows.Scan(&cartItem.ProductID, &cartItem.ProductQuantity, &cartItem.ProductPrice); err != nil {
-				return resp, fmt.Errorf("failed to scan product for cart_id %d: %w", req.CartId, err)
-			}
+	totalPrice := 0.0
+	for _, item := range resp.CartItems {
+		totalPrice += item.ProductPrice * float64(item.ProductQuantity)
+	}
 
-			productPrice := cartItem.ProductPrice
-			log.Printf("Scanned item: ProductID=%d, Quantity=%d, Price=%.2f", cartItem.ProductID, cartItem.ProductQuantity, productPrice)
+	resp.TotalPrice = FormatPrice(totalPrice)
+	return resp, nil
+}
 
-			resp.CartItems = append(resp.CartItems, cartItem)
-			totalPrice += productPrice * float64(cartItem.ProductQuantity)
+// loadCartFromDB returns the cart of a client from Postgres. sql.ErrNoRows is
+// returned as is when the client has no cart.
+func (r *UserRepository) loadCartFromDB(ctx context.Context, clientID int32) (cartID int32, cartItems []CartItem, err error) {
+	err = r.conn(ctx).QueryRowContext(ctx, GetCartSQL, clientID).Scan(&cartID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil, err
 		}
+		return 0, nil, fmt.Errorf("failed to find cart_id for user_id %d: %v", clientID, err)
+	}
 
-		if err := rows.Err(); err != nil {
-			return resp, fmt.Errorf("error occurred during row iteration for cart_id %d: %w", req.CartId, err)
+	rows, err := r.conn(ctx).QueryContext(ctx, GetCartItemSQL, cartID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get cart items for cart_id %d: %w", cartID, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = fmt.Errorf("failed to close rows: %w", closeErr)
 		}
+	}()
 
-		updateCartData, err := json.Marshal(resp.CartItems)
-		if err != nil {
-			return GetCartResponse{}, fmt.Errorf("failed to marshal cart items: %w", err)
-		}
-		if err := r.redisClient.Client.Set(ctx, redisKey, updateCartData, 0).Err(); err != nil {
-			return GetCartResponse{}, fmt.Errorf("failed to save cart to Redis: %w", err)
-		}
-	} else {
-		for _, item := range resp.CartItems {
-			productPrice := float64(item.ProductPrice)
-			totalPrice += productPrice * float64(item.ProductQuantity)
+	cartItems = []CartItem{}
+
+	for rows.Next() {
+		var cartItem CartItem
+		if err := rows.Scan(&cartItem.ProductID, &cartItem.ProductQuantity, &cartItem.ProductPrice); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan product for cart_id %d: %w", cartID, err)
 		}
-	}
 
-	resp.TotalPrice = fmt.Sprintf("%.2f", totalPrice)
-	return resp, nil
-}
+		cartItems = append(cartItems, cartItem)
+	}
 
-func (r *UserRepository) SimulatePayment(ctx context.Context, req PaymentRequest) (resp PaymentResponse, err error) {
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("error occurred during row iteration for cart_id %d: %w", cartID, err)
+	}
 
-	result, err := r.db.ExecContext(ctx, PaymentSQL, req.ClientId)
+	return cartID, cartItems, nil
+}
 
+func (r *UserRepository) GetProductPrices(ctx context.Context, req GetProductPricesRequest) (resp GetProductPricesResponse, err error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, GetProductPricesSQL, pq.Array(req.ProductIDs))
 	if err != nil {
-		return resp, fmt.Errorf("ошибка обработки платежа: %v", err)
+		return resp, fmt.Errorf("failed to query product prices: %w", err)
 	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = fmt.Errorf("failed to close rows: %w", closeErr)
+		}
+	}()
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return resp, fmt.Errorf("ошибка проверки затронутых строк: %v", err)
-	}
-	if rowsAffected == 0 {
-		return resp, fmt.Errorf("платёж не выполнен: возможно, недостаточно средств на счёте")
+	resp.Prices = make(map[int32]float64, len(req.ProductIDs))
+	resp.TaxClasses = make(map[int32]string, len(req.ProductIDs))
+	resp.WeightGrams = make(map[int32]int, len(req.ProductIDs))
+
+	for rows.Next() {
+		var (
+			productID int32
+			price     float64
+			taxClass  string
+			weight    int
+		)
+		if err := rows.Scan(&productID, &price, &taxClass, &weight); err != nil {
+			return resp, fmt.Errorf("failed to scan product price: %w", err)
+		}
+		resp.Prices[productID] = price
+		resp.TaxClasses[productID] = taxClass
+		resp.WeightGrams[productID] = weight
 	}
 
-	redisKey := fmt.Sprintf("cart:%d", req.ClientId)
-	emptyCartData, err := json.Marshal([]CartItem{})
-	if err != nil {
-		return resp, fmt.Errorf("ошибка сериализации пустой корзины: %v", err)
+	if err := rows.Err(); err != nil {
+		return resp, fmt.Errorf("error occurred during row iteration: %w", err)
 	}
 
-	err = r.redisClient.Client.Set(ctx, redisKey, emptyCartData, 0).Err()
+	return resp, nil
+}
+
+func (r *UserRepository) GetClientRegion(ctx context.Context, req GetClientRegionRequest) (resp GetClientRegionResponse, err error) {
+	err = r.conn(ctx).QueryRowContext(ctx, GetClientRegionSQL, req.ClientId).Scan(&resp.Region)
 	if err != nil {
-		log.Printf("Ошибка очистки содержимого корзины в Redis для Client ID %d: %v", req.ClientId, err)
-	} else {
-		log.Printf("Содержимое корзины успешно очищено в Redis для Client ID %d", req.ClientId)
+		if err == sql.ErrNoRows {
+			return resp, domain.NotFound("CLIENT_NOT_FOUND", "client %d not found", req.ClientId)
+		}
+		return resp, fmt.Errorf("failed to get client region: %w", err)
 	}
-
-	resp = PaymentResponse{Success: true}
 	return resp, nil
+}
 
+// FormatPrice renders an amount the way carts, checkouts and the API show
+// prices, with two decimals.
+func FormatPrice(price float64) string {
+	return fmt.Sprintf("%.2f", price)
 }
@@ -3,6 +3,7 @@ package repository
 const (
 	FindClientByUserNameSql = "SELECT id,username,role FROM clients_table WHERE id = $1"
 	SearchProductByNameSQL  = "SELECT id, name, description, price FROM products WHERE name ILIKE '%' || $1 || '%'"
+	GetProductByIdSQL       = "SELECT id, name, description, price FROM products WHERE id = $1"
 	GetCartSQL              = "SELECT cart_id FROM carts WHERE user_id = $1"
 
 	CreateCartIfNotExistsSQL = `
@@ -16,59 +17,229 @@ const (
         UPDATE products
         SET quantity = quantity - $3
         WHERE id = $2 AND quantity >= $3
-        RETURNING id
+        RETURNING id, price
     )
     INSERT INTO cart_items (cart_id, product_id, quantity, price, added_at)
-    VALUES (
-        $1, -- cart_id
-        $2, -- product_id
-        $3, -- quantity
-        (SELECT price FROM products WHERE id = $2 LIMIT 1), -- price
-        NOW() -- added_at
-    )
+    SELECT $1, id, $3, price, NOW() FROM updated
     ON CONFLICT (cart_id, product_id)
-    DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity
-    WHERE EXISTS (SELECT 1 FROM updated);
+    DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity;
 `
 	SearchProductByIdSQL   = "SELECT EXISTS(SELECT 1 FROM cart_items WHERE cart_id = $1 AND product_id = $2)"
 	DeleteItemFromCartSQL  = "DELETE FROM cart_items  WHERE  cart_id = $1 AND  product_id = $2"
 	DeleteItemFromCartSQL2 = `
-    WITH updated AS (
+    WITH deleted AS (
+        DELETE FROM cart_items
+        WHERE cart_id = $1 AND  product_id = $2 AND quantity <= 1
+        RETURNING quantity
+    ),
+     updated AS (
         UPDATE cart_items
         SET quantity = quantity - 1
-        WHERE cart_id = $1 AND  product_id = $2 AND quantity >0
+        WHERE cart_id = $1 AND  product_id = $2 AND quantity > 1
         RETURNING quantity
-    ),
-     deleted AS (
-        DELETE FROM cart_items
-        WHERE cart_id = $1 AND  product_id = $2 AND quantity = 0
     )
     UPDATE products
         SET quantity = quantity + 1
         WHERE id = $2
-        AND EXISTS (SELECT 1 FROM updated);
+        AND (EXISTS (SELECT 1 FROM updated) OR EXISTS (SELECT 1 FROM deleted WHERE quantity = 1));
 `
 	GetCartItemSQL = "SELECT product_id, quantity, price FROM cart_items WHERE cart_id = $1"
-	PaymentSQL     = `
-    WITH total AS (
-        SELECT SUM(ci.quantity * ci.price) AS total_price
-        FROM cart_items ci
-        JOIN carts c ON c.cart_id = ci.cart_id
-        WHERE c.user_id = $1
+	LockCartSQL    = "SELECT cart_id FROM carts WHERE user_id = $1 FOR UPDATE"
+	CartTotalSQL   = `
+    SELECT COALESCE(SUM(ci.quantity * p.price), 0)
+    FROM cart_items ci
+    JOIN products p ON p.id = ci.product_id
+    WHERE ci.cart_id = $1`
+	RepriceCartSQL = `
+    UPDATE cart_items ci
+    SET price = p.price
+    FROM products p
+    WHERE ci.cart_id = $1 AND p.id = ci.product_id AND ci.price <> p.price
+    RETURNING ci.product_id, ci.price`
+	ChargeClientSQL     = "UPDATE clients_table SET invoice = invoice - $2 WHERE id = $1 AND invoice >= $2"
+	CreditMarketSQL     = "UPDATE wallet_market SET balance = balance + $1 WHERE id = 1"
+	ClearCartSQL        = "DELETE FROM cart_items WHERE cart_id = $1"
+	GetProductPricesSQL = "SELECT id, price, tax_class, weight_grams FROM products WHERE id = ANY($1)"
+	GetClientRegionSQL  = "SELECT region FROM clients_table WHERE id = $1"
+
+	GetProductStockSQL = "SELECT price, quantity FROM products WHERE id = $1"
+	MergeCartItemSQL   = `
+    WITH stock AS (
+        SELECT id, price, LEAST(quantity, $3) AS taken
+        FROM products
+        WHERE id = $2 AND quantity > 0
+        FOR UPDATE
     ),
     updated AS (
-        UPDATE clients_table
-        SET invoice = invoice - (SELECT total_price FROM total)
-        WHERE id = $1 AND invoice >= (SELECT total_price FROM total)
-        RETURNING id
-    ),
-    wallet_update AS (
-        UPDATE wallet_market
-        SET balance = balance + (SELECT total_price FROM total)
-        WHERE id = 1 
+        UPDATE products p
+        SET quantity = p.quantity - s.taken
+        FROM stock s
+        WHERE p.id = s.id
+        RETURNING s.taken, s.price
     )
-    DELETE FROM cart_items
-    WHERE cart_id = (SELECT cart_id FROM carts WHERE user_id = $1)
-    AND EXISTS (SELECT 1 FROM updated);
-    `
+    INSERT INTO cart_items (cart_id, product_id, quantity, price, added_at)
+    SELECT $1, $2, taken, price, NOW() FROM updated
+    ON CONFLICT (cart_id, product_id)
+    DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity
+    RETURNING (SELECT taken FROM updated), (SELECT price FROM updated);
+`
+
+	RecordGuestCartMergeSQL = `
+    INSERT INTO merged_guest_carts (session_token, client_id)
+    VALUES ($1, $2)
+    ON CONFLICT (session_token) DO NOTHING`
+
+	GetPromotionSQL = `
+    SELECT code, description, rule_type, percent, amount, COALESCE(product_id, 0),
+           buy_quantity, free_quantity, min_spend, valid_from, valid_until, usage_limit_per_client
+    FROM promotions
+    WHERE code = $1`
+	CountPromoRedemptionsSQL = "SELECT COUNT(*) FROM promo_redemptions WHERE code = $1 AND client_id = $2"
+	SetCartPromoCodeSQL      = "UPDATE carts SET promo_code = NULLIF($2, ''), updated_at = NOW() WHERE user_id = $1"
+	GetCartPromoCodeSQL      = "SELECT COALESCE(promo_code, '') FROM carts WHERE user_id = $1"
+	RedeemPromoCodeSQL       = `
+    INSERT INTO promo_redemptions (code, client_id, discount, redeemed_at)
+    SELECT p.code, $2, $3, NOW()
+    FROM promotions p
+    WHERE p.code = $1
+      AND (p.usage_limit_per_client = 0
+        OR (SELECT COUNT(*) FROM promo_redemptions r WHERE r.code = $1 AND r.client_id = $2) < p.usage_limit_per_client)`
+
+	CreateOrderSQL = `
+    INSERT INTO orders (client_id, status, items_total, discount, tax, shipping_cost, total,
+                        promo_code, address_id, shipping_option, payment_provider, payment_reference, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, 0), NULLIF($10, ''), $11, $12, NOW())
+    RETURNING id`
+	CreateOrderItemsSQL = `
+    INSERT INTO order_items (order_id, product_id, quantity, price)
+    SELECT $1, product_id, quantity, price
+    FROM checkout_session_items
+    WHERE session_id = $2`
+
+	AddAddressSQL = `
+    INSERT INTO client_addresses (client_id, recipient, line1, line2, city, postal_code, country, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+    RETURNING id`
+	ListAddressesSQL = `
+    SELECT id, client_id, recipient, line1, line2, city, postal_code, country
+    FROM client_addresses
+    WHERE client_id = $1 AND deleted_at IS NULL
+    ORDER BY id`
+	GetAddressSQL = `
+    SELECT id, client_id, recipient, line1, line2, city, postal_code, country
+    FROM client_addresses
+    WHERE id = $1 AND client_id = $2 AND deleted_at IS NULL`
+	DeleteAddressSQL = "UPDATE client_addresses SET deleted_at = NOW() WHERE id = $1 AND client_id = $2 AND deleted_at IS NULL"
+
+	SupersedeCheckoutSessionsSQL = "UPDATE checkout_sessions SET status = $2 WHERE client_id = $1 AND status = 'open'"
+	CreateCheckoutSessionSQL     = `
+    INSERT INTO checkout_sessions (id, client_id, cart_id, status, items_total, discount, tax, shipping_cost, total,
+                                   promo_code, address_id, shipping_option, created_at, expires_at)
+    VALUES ($1, $2, $3, 'open', $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, 0), NULLIF($11, ''), NOW(),
+            NOW() + make_interval(secs => $12))
+    RETURNING expires_at`
+	CreateCheckoutSessionItemsSQL = `
+    INSERT INTO checkout_session_items (session_id, product_id, quantity, price)
+    SELECT $1, ci.product_id, ci.quantity, p.price
+    FROM cart_items ci
+    JOIN products p ON p.id = ci.product_id
+    WHERE ci.cart_id = $2
+    RETURNING product_id, quantity, price`
+	LockCheckoutSessionSQL = `
+    SELECT id, client_id, cart_id, status, items_total, discount, tax, shipping_cost, total,
+           COALESCE(promo_code, ''), COALESCE(address_id, 0), COALESCE(shipping_option, ''), expires_at,
+           expires_at <= NOW()
+    FROM checkout_sessions
+    WHERE id = $1 AND client_id = $2
+    FOR UPDATE`
+	CheckoutCartMismatchSQL = `
+    SELECT COUNT(*)
+    FROM (SELECT product_id, quantity FROM cart_items WHERE cart_id = $2) ci
+    FULL OUTER JOIN (SELECT product_id, quantity FROM checkout_session_items WHERE session_id = $1) si
+        ON si.product_id = ci.product_id
+    WHERE ci.quantity IS DISTINCT FROM si.quantity`
+	CloseCheckoutSessionSQL = "UPDATE checkout_sessions SET status = $2, order_id = NULLIF($3, 0) WHERE id = $1"
+	GetCheckoutSessionSQL   = `
+    SELECT id, client_id, cart_id, status, items_total, discount, tax, shipping_cost, total,
+           COALESCE(promo_code, ''), COALESCE(address_id, 0), COALESCE(shipping_option, ''), expires_at,
+           expires_at <= NOW()
+    FROM checkout_sessions
+    WHERE id = $1 AND client_id = $2`
+	SetOrderStatusSQL              = "UPDATE orders SET status = $2, updated_at = NOW() WHERE id = $1"
+	LockOrderByPaymentReferenceSQL = `
+    SELECT id, client_id, status, total, COALESCE(promo_code, '')
+    FROM orders
+    WHERE payment_reference = $1
+    FOR UPDATE`
+	LockOrderSQL = `
+    SELECT client_id, status, COALESCE(promo_code, '')
+    FROM orders
+    WHERE id = $1
+    FOR UPDATE`
+	RestoreOrderItemsSQL = `
+    INSERT INTO cart_items (cart_id, product_id, quantity, price, added_at)
+    SELECT $1, product_id, quantity, price, NOW()
+    FROM order_items
+    WHERE order_id = $2
+    ON CONFLICT (cart_id, product_id)
+    DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity`
+	RestoreCartPromoCodeSQL  = "UPDATE carts SET promo_code = $2, updated_at = NOW() WHERE user_id = $1 AND promo_code IS NULL"
+	CancelPromoRedemptionSQL = `
+    DELETE FROM promo_redemptions
+    WHERE id = (SELECT id FROM promo_redemptions WHERE code = $1 AND client_id = $2 ORDER BY id DESC LIMIT 1)`
+	RecordPaymentEventSQL = `
+    INSERT INTO payment_events (event_id, payment_reference, status)
+    VALUES ($1, $2, $3)
+    ON CONFLICT (event_id) DO NOTHING`
+
+	CreatePaymentAuthorizationSQL = `
+    INSERT INTO payment_authorizations (id, client_id, reference, status, amount)
+    VALUES ($1, $2, NULLIF($3, ''), $4, $5)`
+	CapturePaymentAuthorizationSQL = `
+    UPDATE payment_authorizations
+    SET status = $3, captured_amount = $2, updated_at = NOW()
+    WHERE id = $1 AND status = $4 AND amount >= $2
+    RETURNING client_id, amount`
+	VoidPaymentAuthorizationSQL = `
+    UPDATE payment_authorizations
+    SET status = $2, updated_at = NOW()
+    WHERE id = $1 AND status = $3
+    RETURNING client_id, amount`
+	RefundPaymentAuthorizationSQL = `
+    UPDATE payment_authorizations
+    SET refunded_amount = refunded_amount + $2,
+        status = CASE WHEN refunded_amount + $2 >= captured_amount THEN $3 ELSE status END,
+        updated_at = NOW()
+    WHERE id = $1 AND status = $4 AND refunded_amount + $2 <= captured_amount
+    RETURNING client_id`
+	PaymentAuthorizationExistsSQL = "SELECT EXISTS (SELECT 1 FROM payment_authorizations WHERE id = $1)"
+	CreditClientSQL               = "UPDATE clients_table SET invoice = invoice + $2 WHERE id = $1"
+	DebitMarketSQL                = "UPDATE wallet_market SET balance = balance - $1 WHERE id = 1 AND balance >= $1"
 )
+
+// preparedSQL are the queries UserRepository runs outside transactions; they
+// are prepared once Postgres is connected.
+var preparedSQL = []string{
+	FindClientByUserNameSql,
+	SearchProductByNameSQL,
+	GetProductByIdSQL,
+	GetCartSQL,
+	CreateCartIfNotExistsSQL,
+	AddItemToCartSQL,
+	DeleteItemFromCartSQL2,
+	GetCartItemSQL,
+	GetProductPricesSQL,
+	GetClientRegionSQL,
+	GetProductStockSQL,
+	MergeCartItemSQL,
+	GetPromotionSQL,
+	CountPromoRedemptionsSQL,
+	SetCartPromoCodeSQL,
+	GetCartPromoCodeSQL,
+	AddAddressSQL,
+	ListAddressesSQL,
+	GetAddressSQL,
+	DeleteAddressSQL,
+	GetCheckoutSessionSQL,
+	SetOrderStatusSQL,
+}
@@ -0,0 +1,43 @@
+-- Tables the service was deployed with before migrations/ existed. The
+-- postgres contract tests create them in an empty database and then apply
+-- the migrations on top.
+CREATE TABLE IF NOT EXISTS clients_table
+(
+    id       SERIAL PRIMARY KEY,
+    username TEXT           NOT NULL,
+    role     TEXT           NOT NULL DEFAULT 'user',
+    invoice  NUMERIC(12, 2) NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS products
+(
+    id          SERIAL PRIMARY KEY,
+    name        TEXT           NOT NULL,
+    description TEXT           NOT NULL DEFAULT '',
+    price       NUMERIC(12, 2) NOT NULL,
+    quantity    INT            NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS carts
+(
+    cart_id    SERIAL PRIMARY KEY,
+    user_id    INT         NOT NULL UNIQUE REFERENCES clients_table (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS cart_items
+(
+    cart_id    INT            NOT NULL REFERENCES carts (cart_id),
+    product_id INT            NOT NULL REFERENCES products (id),
+    quantity   INT            NOT NULL,
+    price      NUMERIC(12, 2) NOT NULL,
+    added_at   TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (cart_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS wallet_market
+(
+    id      INT PRIMARY KEY,
+    balance NUMERIC(12, 2) NOT NULL DEFAULT 0
+);
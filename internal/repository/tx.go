@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"github.com/Dmitrij-bot/marketserv/pkg/postgres"
+	"github.com/lib/pq"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// serializationFailure is the SQLSTATE of a serializable transaction that
+// conflicted with a concurrent one and can be retried.
+const serializationFailure = "40001"
+
+// querier runs queries on the database or on a transaction.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
+type txKey struct{}
+
+// unitOfWork is the transaction InTx runs in, carried by the context.
+type unitOfWork struct {
+	tx          *postgres.Tx
+	afterCommit []func(ctx context.Context) error
+}
+
+func currentTx(ctx context.Context) *unitOfWork {
+	uow, _ := ctx.Value(txKey{}).(*unitOfWork)
+	return uow
+}
+
+// InTx runs fn in a serializable transaction. Repository calls made with the
+// context passed to fn join the transaction, so they commit or roll back
+// together. fn is run again, up to Config.TxAttempts times, when the
+// transaction fails to serialize, and must not have side effects outside the
+// repository. Redis updates of the calls are applied after the commit and
+// cannot fail it: a cart that could not be updated is reloaded later. Calls
+// to InTx inside fn join the outer transaction. The checkout and settlement
+// methods lock rows in a transaction of their own and must not be called
+// from fn.
+func (r *UserRepository) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if currentTx(ctx) != nil {
+		return fn(ctx)
+	}
+
+	attempts := r.txAttempts()
+	for attempt := 1; ; attempt++ {
+		err := r.runTx(ctx, fn)
+		if attempt >= attempts || !isSerializationFailure(err) {
+			return err
+		}
+
+		txRetries.Inc()
+		r.log.DebugContext(ctx, "retrying serializable transaction", slog.Int("attempt", attempt), logger.Err(err))
+
+		// A short random pause keeps the conflicting transactions from
+		// colliding again.
+		backoff := time.Duration(attempt) * (10*time.Millisecond + rand.N(10*time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (r *UserRepository) runTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	uow := &unitOfWork{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, uow)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.runAfterCommit(ctx, uow.afterCommit...)
+	return nil
+}
+
+// runAfterCommit runs hooks of a committed change. The change stands whatever
+// they do, so their errors are logged instead of failing the call, and they
+// run to the end even if the caller gives up.
+func (r *UserRepository) runAfterCommit(ctx context.Context, hooks ...func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			r.log.WarnContext(ctx, "failed to apply a committed change", logger.Err(err))
+		}
+	}
+}
+
+func (r *UserRepository) txAttempts() int {
+	if n := r.config().TxAttempts; n > 0 {
+		return n
+	}
+	return 3
+}
+
+// conn returns the transaction of ctx, or the primary outside InTx.
+func (r *UserRepository) conn(ctx context.Context) querier {
+	if uow := currentTx(ctx); uow != nil {
+		return uow.tx
+	}
+	return r.db
+}
+
+// replica returns the transaction of ctx, or a read replica outside InTx.
+func (r *UserRepository) replica(ctx context.Context) querier {
+	if uow := currentTx(ctx); uow != nil {
+		return uow.tx
+	}
+	return r.db.Replica()
+}
+
+// afterCommit runs fn once the transaction of ctx commits, or right away
+// outside InTx. It is how cache updates avoid exposing rolled back writes.
+// Errors of fn are logged, see runAfterCommit.
+func (r *UserRepository) afterCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	if uow := currentTx(ctx); uow != nil {
+		uow.afterCommit = append(uow.afterCommit, fn)
+		return nil
+	}
+	r.runAfterCommit(ctx, fn)
+	return nil
+}
+
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == serializationFailure
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/Dmitrij-bot/marketserv/pkg/postgres"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// fakeDB is a database/sql driver that records the statements it runs and
+// which of them were committed.
+type fakeDB struct {
+	mu         sync.Mutex
+	committed  []string
+	begins     int
+	commits    int
+	rollbacks  int
+	isolations []driver.IsolationLevel
+	fail       map[string]error // statements failing with the error
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: d}, nil }
+func (d *fakeDB) Driver() driver.Driver                        { return nil }
+
+func (d *fakeDB) state() (committed []string, begins, commits, rollbacks int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.committed), d.begins, d.commits, d.rollbacks
+}
+
+type fakeConn struct {
+	db      *fakeDB
+	inTx    bool
+	pending []string
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakeConn: prepared statements are not supported")
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.begins++
+	c.db.isolations = append(c.db.isolations, opts.Isolation)
+	c.inTx, c.pending = true, nil
+	return c, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	if err := c.db.fail[query]; err != nil {
+		return nil, err
+	}
+	if c.inTx {
+		c.pending = append(c.pending, query)
+	} else {
+		c.db.committed = append(c.db.committed, query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.commits++
+	c.db.committed = append(c.db.committed, c.pending...)
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.rollbacks++
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func newTxTestRepository(t *testing.T, fake *fakeDB) *UserRepository {
+	t.Helper()
+	sqlDB := sql.OpenDB(fake)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	r := &UserRepository{
+		db:  &postgres.DB{DB: sqlx.NewDb(sqlDB, "postgres")},
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	r.cfg.Store(&Config{TxAttempts: 3})
+	return r
+}
+
+func exec(ctx context.Context, r *UserRepository, query string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, query)
+	return err
+}
+
+func TestInTxRollsBackPartialFailure(t *testing.T) {
+	boom := errors.New("boom")
+	fake := &fakeDB{fail: map[string]error{"second write": boom}}
+	r := newTxTestRepository(t, fake)
+
+	hookRan := false
+	err := r.InTx(context.Background(), func(ctx context.Context) error {
+		if err := exec(ctx, r, "first write"); err != nil {
+			return err
+		}
+		if err := r.afterCommit(ctx, func(context.Context) error {
+			hookRan = true
+			return nil
+		}); err != nil {
+			return err
+		}
+		return exec(ctx, r, "second write")
+	})
+
+	if !errors.Is(err, boom) {
+		t.Fatalf("InTx() error = %v, want %v", err, boom)
+	}
+	committed, begins, commits, rollbacks := fake.state()
+	if len(committed) != 0 {
+		t.Errorf("committed %q, want the first write rolled back", committed)
+	}
+	if begins != 1 || commits != 0 || rollbacks != 1 {
+		t.Errorf("begins, commits, rollbacks = %d, %d, %d, want 1, 0, 1", begins, commits, rollbacks)
+	}
+	if hookRan {
+		t.Error("after-commit hook ran for a rolled back transaction")
+	}
+}
+
+func TestInTxCommitsAndRunsHooks(t *testing.T) {
+	fake := &fakeDB{}
+	r := newTxTestRepository(t, fake)
+
+	var hookSaw []string
+	err := r.InTx(context.Background(), func(ctx context.Context) error {
+		if err := r.afterCommit(ctx, func(context.Context) error {
+			hookSaw, _, _, _ = fake.state()
+			return errors.New("cache update failed")
+		}); err != nil {
+			return err
+		}
+		return exec(ctx, r, "write")
+	})
+
+	if err != nil {
+		t.Fatalf("InTx() error = %v, want nil even though a hook failed", err)
+	}
+	if !slices.Equal(hookSaw, []string{"write"}) {
+		t.Errorf("hook saw committed %q, want it to run after the commit", hookSaw)
+	}
+	if fake.isolations[0] != driver.IsolationLevel(sql.LevelSerializable) {
+		t.Errorf("isolation = %v, want serializable", fake.isolations[0])
+	}
+}
+
+func TestInTxRetriesSerializationFailures(t *testing.T) {
+	serialization := &pq.Error{Code: serializationFailure}
+
+	t.Run("gives up after TxAttempts", func(t *testing.T) {
+		fake := &fakeDB{}
+		r := newTxTestRepository(t, fake)
+
+		calls := 0
+		err := r.InTx(context.Background(), func(ctx context.Context) error {
+			calls++
+			return serialization
+		})
+
+		if !errors.Is(err, serialization) {
+			t.Fatalf("InTx() error = %v, want the serialization failure", err)
+		}
+		if calls != 3 {
+			t.Errorf("fn ran %d times, want TxAttempts = 3", calls)
+		}
+		if _, begins, _, rollbacks := fake.state(); begins != 3 || rollbacks != 3 {
+			t.Errorf("begins, rollbacks = %d, %d, want 3, 3", begins, rollbacks)
+		}
+	})
+
+	t.Run("succeeds on a later attempt", func(t *testing.T) {
+		fake := &fakeDB{}
+		r := newTxTestRepository(t, fake)
+
+		calls := 0
+		err := r.InTx(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls == 1 {
+				return serialization
+			}
+			return exec(ctx, r, "write")
+		})
+
+		if err != nil {
+			t.Fatalf("InTx() error = %v", err)
+		}
+		if committed, _, commits, _ := fake.state(); calls != 2 || commits != 1 || !slices.Equal(committed, []string{"write"}) {
+			t.Errorf("calls, commits, committed = %d, %d, %q, want 2, 1, [write]", calls, commits, committed)
+		}
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		r := newTxTestRepository(t, &fakeDB{})
+
+		calls := 0
+		_ = r.InTx(context.Background(), func(ctx context.Context) error {
+			calls++
+			return &pq.Error{Code: "23505"}
+		})
+
+		if calls != 1 {
+			t.Errorf("fn ran %d times, want 1", calls)
+		}
+	})
+}
+
+func TestNestedInTxJoinsOuterTransaction(t *testing.T) {
+	fake := &fakeDB{}
+	r := newTxTestRepository(t, fake)
+
+	var hooks []string
+	err := r.InTx(context.Background(), func(ctx context.Context) error {
+		outer := currentTx(ctx)
+		if err := exec(ctx, r, "outer write"); err != nil {
+			return err
+		}
+		err := r.InTx(ctx, func(ctx context.Context) error {
+			if currentTx(ctx) != outer {
+				t.Error("nested InTx started a transaction of its own")
+			}
+			return r.afterCommit(ctx, func(context.Context) error {
+				hooks = append(hooks, "inner")
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		if len(hooks) != 0 {
+			t.Error("inner hook ran before the outer transaction committed")
+		}
+		return exec(ctx, r, "inner write")
+	})
+
+	if err != nil {
+		t.Fatalf("InTx() error = %v", err)
+	}
+	committed, begins, commits, _ := fake.state()
+	if begins != 1 || commits != 1 {
+		t.Errorf("begins, commits = %d, %d, want 1, 1", begins, commits)
+	}
+	if !slices.Equal(committed, []string{"outer write", "inner write"}) {
+		t.Errorf("committed %q", committed)
+	}
+	if !slices.Equal(hooks, []string{"inner"}) {
+		t.Errorf("hooks ran %q, want [inner] after the commit", hooks)
+	}
+}
+
+func TestNestedInTxFailureRollsBackOuter(t *testing.T) {
+	fake := &fakeDB{}
+	r := newTxTestRepository(t, fake)
+
+	boom := errors.New("boom")
+	err := r.InTx(context.Background(), func(ctx context.Context) error {
+		if err := exec(ctx, r, "outer write"); err != nil {
+			return err
+		}
+		return r.InTx(ctx, func(ctx context.Context) error {
+			return boom
+		})
+	})
+
+	if !errors.Is(err, boom) {
+		t.Fatalf("InTx() error = %v, want %v", err, boom)
+	}
+	if committed, _, _, rollbacks := fake.state(); len(committed) != 0 || rollbacks != 1 {
+		t.Errorf("committed %q, rollbacks %d, want nothing committed and 1 rollback", committed, rollbacks)
+	}
+}
@@ -0,0 +1,19 @@
+package shipping
+
+type Config struct {
+	Zones       map[string]string `reload:"true"` // country code -> zone
+	DefaultZone string            `reload:"true"` // zone of countries missing from Zones
+	Methods     []Method          `reload:"true"`
+}
+
+type Method struct {
+	Code         string
+	Name         string
+	DeliveryDays int
+	Rates        map[string][]WeightRate // zone -> weight bands in ascending order
+}
+
+type WeightRate struct {
+	MaxWeightGrams int // 0 means no upper limit
+	Cost           float64
+}
@@ -0,0 +1,77 @@
+package shipping
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+type Parcel struct {
+	WeightGrams int
+	Country     string
+}
+
+type Option struct {
+	Code         string
+	Name         string
+	Cost         float64
+	DeliveryDays int
+}
+
+type RateCalculator interface {
+	Options(p Parcel) ([]Option, error)
+}
+
+// Table is a RateCalculator pricing parcels by destination zone and weight.
+type Table struct {
+	cfg atomic.Pointer[Config]
+}
+
+func NewTable(cfg Config) *Table {
+	t := &Table{}
+	t.cfg.Store(&cfg)
+	return t
+}
+
+// Reload replaces the zones and methods used by later quotes.
+func (t *Table) Reload(cfg Config) {
+	t.cfg.Store(&cfg)
+}
+
+func (t *Table) Options(p Parcel) ([]Option, error) {
+	cfg := t.cfg.Load()
+	zone, ok := cfg.Zones[strings.ToUpper(p.Country)]
+	if !ok {
+		zone = cfg.DefaultZone
+	}
+	if zone == "" {
+		return nil, fmt.Errorf("no shipping to country %q", p.Country)
+	}
+
+	var options []Option
+	for _, m := range cfg.Methods {
+		for _, rate := range m.Rates[zone] {
+			if rate.MaxWeightGrams == 0 || p.WeightGrams <= rate.MaxWeightGrams {
+				options = append(options, Option{
+					Code:         m.Code,
+					Name:         m.Name,
+					Cost:         rate.Cost,
+					DeliveryDays: m.DeliveryDays,
+				})
+				break
+			}
+		}
+	}
+
+	return options, nil
+}
+
+// Find returns the option with the given code.
+func Find(options []Option, code string) (Option, bool) {
+	for _, o := range options {
+		if o.Code == code {
+			return o, true
+		}
+	}
+	return Option{}, false
+}
@@ -0,0 +1,8 @@
+package tax
+
+type Config struct {
+	PricesIncludeTax bool                          `reload:"true"` // catalog prices already contain tax
+	DefaultRegion    string                        `reload:"true"` // used for regions without rates of their own
+	DefaultClass     string                        `reload:"true"` // used for products without a tax class
+	Rates            map[string]map[string]float64 `reload:"true"` // region -> tax class -> rate in percent
+}
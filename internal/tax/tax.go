@@ -0,0 +1,99 @@
+package tax
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+type Line struct {
+	ProductID int32
+	TaxClass  string
+	Amount    float64 // line amount after discounts
+}
+
+type LineTax struct {
+	ProductID int32
+	Rate      float64
+	Tax       float64
+}
+
+type Result struct {
+	Subtotal   float64 // sum of line amounts as priced
+	Tax        float64
+	GrandTotal float64 // amount to charge
+	Lines      []LineTax
+}
+
+type TaxCalculator interface {
+	Calculate(region string, lines []Line) (Result, error)
+}
+
+// Table is a TaxCalculator looking rates up by region and product tax class.
+type Table struct {
+	cfg atomic.Pointer[Config]
+}
+
+func NewTable(cfg Config) *Table {
+	t := &Table{}
+	t.cfg.Store(&cfg)
+	return t
+}
+
+// Reload replaces the rates used by later calculations.
+func (t *Table) Reload(cfg Config) {
+	t.cfg.Store(&cfg)
+}
+
+// Calculate taxes lines at the rates of region. A region without rates of
+// its own, such as a country the shop has no tax table for, is taxed at the
+// rates of the default region.
+func (t *Table) Calculate(region string, lines []Line) (Result, error) {
+	cfg := t.cfg.Load()
+	rates, ok := cfg.Rates[region]
+	if !ok {
+		region = cfg.DefaultRegion
+		rates, ok = cfg.Rates[region]
+	}
+	if !ok && len(cfg.Rates) > 0 {
+		return Result{}, fmt.Errorf("no tax rates for default region %q", region)
+	}
+
+	var res Result
+	for _, l := range lines {
+		class := l.TaxClass
+		if class == "" {
+			class = cfg.DefaultClass
+		}
+
+		rate, ok := rates[class]
+		if !ok && len(cfg.Rates) > 0 {
+			return Result{}, fmt.Errorf("no tax rate for class %q in region %q", class, region)
+		}
+
+		var lineTax float64
+		if cfg.PricesIncludeTax {
+			lineTax = l.Amount - l.Amount/(1+rate/100)
+		} else {
+			lineTax = l.Amount * rate / 100
+		}
+		lineTax = roundCents(lineTax)
+
+		res.Subtotal += l.Amount
+		res.Tax += lineTax
+		res.Lines = append(res.Lines, LineTax{ProductID: l.ProductID, Rate: rate, Tax: lineTax})
+	}
+
+	res.Subtotal = roundCents(res.Subtotal)
+	res.Tax = roundCents(res.Tax)
+	res.GrandTotal = res.Subtotal
+	if !cfg.PricesIncludeTax {
+		res.GrandTotal = roundCents(res.Subtotal + res.Tax)
+	}
+
+	return res, nil
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
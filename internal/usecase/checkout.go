@@ -0,0 +1,342 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
+	"github.com/Dmitrij-bot/marketserv/internal/repository"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const (
+	OrderStatusPending = repository.OrderStatusPending
+	OrderStatusPaid    = repository.OrderStatusPaid
+	OrderStatusFailed  = repository.OrderStatusFailed
+)
+
+var (
+	ErrCheckoutNotFound = repository.ErrCheckoutNotFound
+	ErrCheckoutClosed   = repository.ErrCheckoutClosed
+	ErrCheckoutExpired  = repository.ErrCheckoutExpired
+	ErrCheckoutStale    = repository.ErrCheckoutStale
+
+	ErrOrderNotFound       = repository.ErrOrderNotFound
+	ErrOrderStatusConflict = repository.ErrOrderStatusConflict
+)
+
+// CreateCheckout prices the cart with discounts, taxes and shipping and
+// freezes the quote into a checkout session that ConfirmCheckout charges.
+func (u *UserUseCase) CreateCheckout(ctx context.Context, req CreateCheckoutRequest) (resp CreateCheckoutResponse, err error) {
+
+	cart, err := u.currentCart(ctx, req.ClientId)
+	if err != nil {
+		return CreateCheckoutResponse{}, err
+	}
+
+	if cart.PricesChanged && req.AcknowledgedTotal != cart.CurrentTotalPrice {
+		return CreateCheckoutResponse{}, fmt.Errorf("%w: new total %s must be acknowledged", ErrPriceChanged, cart.CurrentTotalPrice)
+	}
+
+	if cart.promoErr != nil {
+		return CreateCheckoutResponse{}, fmt.Errorf("promo code %s cannot be applied: %w", cart.PromoCode, cart.promoErr)
+	}
+
+	amounts, err := parseAmounts(cart.CurrentTotalPrice, cart.DiscountTotal, cart.Subtotal, cart.GrandTotal)
+	if err != nil {
+		return CreateCheckoutResponse{}, err
+	}
+	expectedTotal, discount, subtotal, grandTotal := amounts[0], amounts[1], amounts[2], amounts[3]
+	taxTotal := cart.TaxTotal
+
+	var shippingCost float64
+	if req.AddressID != 0 {
+		addressResp, err := u.r.GetAddress(ctx, repository.GetAddressRequest{
+			ClientId:  req.ClientId,
+			AddressID: req.AddressID,
+		})
+		if err != nil {
+			return CreateCheckoutResponse{}, err
+		}
+
+		option, err := u.shippingOption(addressResp.Address, req.ShippingOption, cart.CartItems)
+		if err != nil {
+			return CreateCheckoutResponse{}, err
+		}
+		shippingCost = option.Cost
+
+		// The goods are taxed where they are delivered, not where the
+		// client lives.
+		taxResp, err := u.calculateTax(addressResp.Address.Country, cart.CartItems, expectedTotal, discount)
+		if err != nil {
+			return CreateCheckoutResponse{}, err
+		}
+		subtotal, grandTotal, taxTotal = taxResp.Subtotal, taxResp.GrandTotal, repository.FormatPrice(taxResp.Tax)
+	} else if req.ShippingOption != "" {
+		return CreateCheckoutResponse{}, domain.InvalidArgument("address_id", "shipping option requires an address")
+	}
+
+	checkoutID, err := newSessionToken()
+	if err != nil {
+		return CreateCheckoutResponse{}, err
+	}
+
+	createResp, err := u.r.CreateCheckoutSession(
+		ctx,
+		repository.CreateCheckoutSessionRequest{
+			ID:             checkoutID,
+			ClientId:       req.ClientId,
+			ExpectedTotal:  expectedTotal,
+			PromoCode:      cart.PromoCode,
+			Discount:       discount,
+			Tax:            grandTotal - subtotal,
+			ShippingCost:   shippingCost,
+			AddressID:      req.AddressID,
+			ShippingOption: req.ShippingOption,
+		})
+	if err != nil {
+		return CreateCheckoutResponse{}, fmt.Errorf("failed to create checkout: %w", err)
+	}
+
+	session := createResp.Session
+	for _, item := range session.Items {
+		resp.Items = append(resp.Items, CartItem{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.ProductQuantity,
+			ProductPrice:    item.ProductPrice,
+			CurrentPrice:    item.ProductPrice,
+		})
+	}
+
+	resp.CheckoutID = session.ID
+	resp.Discounts = cart.Discounts
+	resp.ItemsTotal = repository.FormatPrice(session.ItemsTotal)
+	resp.DiscountTotal = repository.FormatPrice(session.Discount)
+	resp.TaxTotal = taxTotal
+	resp.ShippingCost = repository.FormatPrice(session.ShippingCost)
+	resp.Total = repository.FormatPrice(session.Total)
+	resp.ExpiresAt = session.ExpiresAt
+	return resp, nil
+}
+
+// ConfirmCheckout charges exactly the total quoted by CreateCheckout through
+// the configured payment provider. The amount is authorized before the order
+// is created and captured afterwards; the authorization is voided when the
+// order cannot be created. When the provider declines the capture the order
+// is failed and its items and promo code go back to the cart. When the
+// outcome of the capture is unknown, after a timeout or a transport error,
+// the order stays pending for the settlement webhook to decide.
+func (u *UserUseCase) ConfirmCheckout(ctx context.Context, req ConfirmCheckoutRequest) (resp ConfirmCheckoutResponse, err error) {
+
+	if req.CheckoutID == "" {
+		return ConfirmCheckoutResponse{}, domain.InvalidArgument("checkout_id", "checkout id cannot be empty")
+	}
+
+	sessionResp, err := u.r.GetCheckoutSession(
+		ctx,
+		repository.GetCheckoutSessionRequest{
+			ID:       req.CheckoutID,
+			ClientId: req.ClientId,
+		})
+	if err != nil {
+		return ConfirmCheckoutResponse{}, fmt.Errorf("ошибка подтверждения оплаты: %w", err)
+	}
+	session := sessionResp.Session
+
+	auth, err := u.payments.Authorize(ctx, payment.AuthorizeRequest{
+		ClientId:  req.ClientId,
+		Amount:    session.Total,
+		Reference: session.ID,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInsufficientFunds) {
+			message := fmt.Sprintf("Недостаточно средств для клиента %d для выполнения платежа", req.ClientId)
+			u.publish(ctx, message)
+		}
+		paymentsTotal.WithLabelValues("failed").Inc()
+		return ConfirmCheckoutResponse{}, fmt.Errorf("ошибка авторизации платежа: %w", err)
+	}
+
+	confirmResp, err := u.r.ConfirmCheckoutSession(
+		ctx,
+		repository.ConfirmCheckoutSessionRequest{
+			ID:               req.CheckoutID,
+			ClientId:         req.ClientId,
+			PaymentProvider:  u.payments.Name(),
+			PaymentReference: auth.ID,
+		})
+	if err != nil {
+		u.voidPayment(ctx, auth.ID)
+		return ConfirmCheckoutResponse{}, fmt.Errorf("ошибка подтверждения оплаты: %w", err)
+	}
+
+	// The order exists now, so a cancelled request must not abandon the
+	// capture halfway.
+	captureCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	err = u.payments.Capture(captureCtx, payment.CaptureRequest{
+		AuthorizationID: auth.ID,
+		Amount:          confirmResp.ChargedTotal,
+	})
+	cancel()
+	switch {
+	case errors.Is(err, payment.ErrSettlementPending), payment.OutcomeUnknown(err):
+		if errors.Is(err, payment.ErrSettlementPending) {
+			u.log.InfoContext(ctx, "order payment awaits provider settlement", slog.Int64("order_id", confirmResp.OrderID))
+		} else {
+			// The capture may have gone through, voiding it now could
+			// lose a payment the provider will still settle.
+			u.log.WarnContext(ctx, "order payment outcome is unknown, awaiting provider settlement",
+				slog.Int64("order_id", confirmResp.OrderID), logger.Err(err))
+		}
+		paymentsTotal.WithLabelValues("pending").Inc()
+		return ConfirmCheckoutResponse{
+			OrderID:      confirmResp.OrderID,
+			ChargedTotal: repository.FormatPrice(confirmResp.ChargedTotal),
+			Status:       repository.OrderStatusPending,
+		}, nil
+	case err != nil:
+		u.voidPayment(ctx, auth.ID)
+		u.failOrder(ctx, confirmResp.OrderID)
+		paymentsTotal.WithLabelValues("failed").Inc()
+		return ConfirmCheckoutResponse{}, fmt.Errorf("ошибка списания платежа по заказу %d: %w", confirmResp.OrderID, err)
+	}
+	u.setOrderStatus(ctx, confirmResp.OrderID, repository.OrderStatusPaid)
+	paymentsTotal.WithLabelValues("succeeded").Inc()
+	revenueTotal.Add(confirmResp.ChargedTotal)
+	u.notifyOrderPaid(ctx, req.ClientId, confirmResp.OrderID)
+
+	return ConfirmCheckoutResponse{
+		OrderID:      confirmResp.OrderID,
+		ChargedTotal: repository.FormatPrice(confirmResp.ChargedTotal),
+		Status:       repository.OrderStatusPaid,
+	}, nil
+}
+
+// SettlePayment applies a payment result delivered asynchronously by the
+// provider. Redelivered results are acknowledged without side effects.
+func (u *UserUseCase) SettlePayment(ctx context.Context, req SettlePaymentRequest) (resp SettlePaymentResponse, err error) {
+
+	if req.EventID == "" || req.PaymentReference == "" {
+		return SettlePaymentResponse{}, domain.InvalidArgument("payment_reference", "event id and payment reference are required")
+	}
+
+	var status string
+	switch req.Result {
+	case payment.SettlementSucceeded:
+		status = repository.OrderStatusPaid
+	case payment.SettlementFailed:
+		status = repository.OrderStatusFailed
+	default:
+		return SettlePaymentResponse{}, domain.InvalidArgument("result", "unknown settlement result %q", req.Result)
+	}
+
+	settleResp, err := u.r.SettleOrderPayment(
+		ctx,
+		repository.SettleOrderPaymentRequest{
+			EventID:          req.EventID,
+			PaymentReference: req.PaymentReference,
+			Status:           status,
+		})
+	if err != nil {
+		return SettlePaymentResponse{}, fmt.Errorf("failed to settle payment %s: %w", req.PaymentReference, err)
+	}
+
+	if settleResp.Changed {
+		u.log.InfoContext(ctx, "order payment settled",
+			slog.Int64("order_id", settleResp.OrderID), slog.String("status", settleResp.Status))
+		if settleResp.Status == repository.OrderStatusPaid {
+			paymentsTotal.WithLabelValues("succeeded").Inc()
+			revenueTotal.Add(settleResp.Total)
+			u.notifyOrderPaid(ctx, settleResp.ClientId, settleResp.OrderID)
+		} else {
+			paymentsTotal.WithLabelValues("failed").Inc()
+		}
+	}
+
+	return SettlePaymentResponse{
+		OrderID: settleResp.OrderID,
+		Status:  settleResp.Status,
+		Changed: settleResp.Changed,
+	}, nil
+}
+
+func (u *UserUseCase) notifyOrderPaid(ctx context.Context, clientID int32, orderID int64) {
+	message := fmt.Sprintf("Товар успешно оплачен {\"client_id\":%d,\"order_id\":%d}", clientID, orderID)
+
+	u.publish(ctx, message)
+}
+
+// Checkout pays for the cart and ships the resulting order to the address.
+func (u *UserUseCase) Checkout(ctx context.Context, req CheckoutRequest) (resp CheckoutResponse, err error) {
+
+	if req.AddressID == 0 || req.ShippingOption == "" {
+		return CheckoutResponse{}, domain.InvalidArgument("shipping_option", "address and shipping option are required")
+	}
+
+	paymentResp, err := u.SimulatePayment(ctx, PaymentRequest{
+		ClientId:          req.ClientId,
+		AcknowledgedTotal: req.AcknowledgedTotal,
+		AddressID:         req.AddressID,
+		ShippingOption:    req.ShippingOption,
+	})
+	if err != nil {
+		return CheckoutResponse{}, err
+	}
+
+	return CheckoutResponse{
+		OrderID:      paymentResp.OrderID,
+		ChargedTotal: paymentResp.ChargedTotal,
+	}, nil
+}
+
+// voidPayment releases an authorization that will not be captured. It runs
+// detached from the request's cancellation so a cancelled request does not
+// leave money on hold.
+func (u *UserUseCase) voidPayment(ctx context.Context, authorizationID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if err := u.payments.Void(ctx, payment.VoidRequest{AuthorizationID: authorizationID}); err != nil {
+		u.log.ErrorContext(ctx, "failed to void payment authorization",
+			slog.String("authorization_id", authorizationID), logger.Err(err))
+	}
+}
+
+func (u *UserUseCase) setOrderStatus(ctx context.Context, orderID int64, status string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	_, err := u.r.SetOrderStatus(ctx, repository.SetOrderStatusRequest{OrderID: orderID, Status: status})
+	if err != nil {
+		u.log.ErrorContext(ctx, "failed to update order status",
+			slog.Int64("order_id", orderID), slog.String("status", status), logger.Err(err))
+	}
+}
+
+// failOrder fails an order whose payment did not go through and returns its
+// items to the cart.
+func (u *UserUseCase) failOrder(ctx context.Context, orderID int64) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if _, err := u.r.FailOrder(ctx, repository.FailOrderRequest{OrderID: orderID}); err != nil {
+		u.log.ErrorContext(ctx, "failed to fail order",
+			slog.Int64("order_id", orderID), logger.Err(err))
+	}
+}
+
+func parseAmounts(values ...string) ([]float64, error) {
+	amounts := make([]float64, 0, len(values))
+	for _, v := range values {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount %q: %w", v, err)
+		}
+		amounts = append(amounts, amount)
+	}
+	return amounts, nil
+}
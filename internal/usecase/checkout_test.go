@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
+	"github.com/Dmitrij-bot/marketserv/internal/repository"
+	"github.com/Dmitrij-bot/marketserv/internal/shipping"
+	"github.com/Dmitrij-bot/marketserv/internal/tax"
+	"github.com/Dmitrij-bot/marketserv/pkg/kafka"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func newTestUseCase(t *testing.T, payments func(m *repository.MemoryRepository) payment.PaymentProvider) (*UserUseCase, *repository.MemoryRepository) {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := repository.NewMemoryRepository(repository.Config{}, repository.MemoryData{
+		Clients: []repository.MemoryClient{{ID: 1, Username: "alice", Role: "user", Balance: 1000}},
+		Products: []repository.MemoryProduct{
+			{ID: 1, Name: "Wireless mouse", Price: 100, Quantity: 5},
+		},
+		Promotions: []repository.Promotion{
+			{Code: "ONCE", RuleType: "fixed_amount", Amount: 10, UsageLimitPerClient: 1},
+		},
+	}, log)
+	u := New(m, tax.NewTable(tax.Config{}), shipping.NewTable(shipping.Config{}), payments(m), kafka.NewProducer(kafka.Config{}, log), log)
+	return u, m
+}
+
+func newFakeGateway(t *testing.T, outcome string) func(*repository.MemoryRepository) payment.PaymentProvider {
+	return func(*repository.MemoryRepository) payment.PaymentProvider {
+		g, err := payment.NewFakeGateway(payment.FakeConfig{Outcome: outcome})
+		if err != nil {
+			t.Fatalf("NewFakeGateway() error = %v", err)
+		}
+		return g
+	}
+}
+
+func TestConfirmCheckoutCaptureFailureRestoresCart(t *testing.T) {
+	ctx := context.Background()
+	u, m := newTestUseCase(t, newFakeGateway(t, payment.OutcomeCaptureDecline))
+
+	if _, err := u.AddItemToCart(ctx, AddItemToCartRequest{ClientId: 1, ProductID: 1, Quantity: 2}); err != nil {
+		t.Fatalf("AddItemToCart() error = %v", err)
+	}
+	if _, err := u.ApplyPromoCode(ctx, ApplyPromoCodeRequest{ClientId: 1, Code: "ONCE"}); err != nil {
+		t.Fatalf("ApplyPromoCode() error = %v", err)
+	}
+	checkout, err := u.CreateCheckout(ctx, CreateCheckoutRequest{ClientId: 1})
+	if err != nil {
+		t.Fatalf("CreateCheckout() error = %v", err)
+	}
+
+	_, err = u.ConfirmCheckout(ctx, ConfirmCheckoutRequest{ClientId: 1, CheckoutID: checkout.CheckoutID})
+	if !errors.Is(err, payment.ErrDeclined) {
+		t.Fatalf("ConfirmCheckout() error = %v, want %v", err, payment.ErrDeclined)
+	}
+
+	cart, err := u.GetCart(ctx, GetCartRequest{ClientId: 1})
+	if err != nil {
+		t.Fatalf("GetCart() error = %v", err)
+	}
+	if len(cart.CartItems) != 1 || cart.CartItems[0].ProductQuantity != 2 || cart.PromoCode != "ONCE" {
+		t.Errorf("cart = %+v, want 2 mice with promo code ONCE back", cart)
+	}
+	redeemed, err := m.CountPromoRedemptions(ctx, repository.CountPromoRedemptionsRequest{Code: "ONCE", ClientId: 1})
+	if err != nil || redeemed.Count != 0 {
+		t.Errorf("promo redemptions = %d, %v, want the redemption released", redeemed.Count, err)
+	}
+
+	// The cart holds its two mice again, so only three are left in stock.
+	if _, err := m.AddItemToGuestCart(ctx, repository.AddItemToGuestCartRequest{SessionToken: "probe", ProductID: 1, Quantity: 4}); !errors.Is(err, repository.ErrOutOfStock) {
+		t.Errorf("adding 4 of the 3 left error = %v, want %v", err, repository.ErrOutOfStock)
+	}
+
+	if resp, err := m.FailOrder(ctx, repository.FailOrderRequest{OrderID: 1}); err != nil || resp.Changed {
+		t.Errorf("failing the order again = %+v, %v, want no change", resp, err)
+	}
+
+	// The client can check out the restored cart.
+	if _, err := u.CreateCheckout(ctx, CreateCheckoutRequest{ClientId: 1}); err != nil {
+		t.Errorf("CreateCheckout() of the restored cart error = %v", err)
+	}
+}
+
+// cancellingProvider cancels the request once the payment is authorized.
+type cancellingProvider struct {
+	payment.PaymentProvider
+	cancel     context.CancelFunc
+	captureErr error
+}
+
+func (p *cancellingProvider) Authorize(ctx context.Context, req payment.AuthorizeRequest) (payment.Authorization, error) {
+	defer p.cancel()
+	return p.PaymentProvider.Authorize(ctx, req)
+}
+
+func (p *cancellingProvider) Capture(ctx context.Context, req payment.CaptureRequest) error {
+	p.captureErr = ctx.Err()
+	return p.PaymentProvider.Capture(ctx, req)
+}
+
+func TestConfirmCheckoutCapturesCancelledRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &cancellingProvider{cancel: cancel}
+	u, _ := newTestUseCase(t, func(m *repository.MemoryRepository) payment.PaymentProvider {
+		provider.PaymentProvider = repository.NewMemoryBalanceProvider(m)
+		return provider
+	})
+
+	if _, err := u.AddItemToCart(ctx, AddItemToCartRequest{ClientId: 1, ProductID: 1, Quantity: 1}); err != nil {
+		t.Fatalf("AddItemToCart() error = %v", err)
+	}
+	checkout, err := u.CreateCheckout(ctx, CreateCheckoutRequest{ClientId: 1})
+	if err != nil {
+		t.Fatalf("CreateCheckout() error = %v", err)
+	}
+
+	confirmed, err := u.ConfirmCheckout(ctx, ConfirmCheckoutRequest{ClientId: 1, CheckoutID: checkout.CheckoutID})
+	if err != nil {
+		t.Fatalf("ConfirmCheckout() error = %v", err)
+	}
+	if provider.captureErr != nil {
+		t.Errorf("Capture ran with a done context: %v", provider.captureErr)
+	}
+	if confirmed.Status != OrderStatusPaid {
+		t.Errorf("order status = %s, want %s", confirmed.Status, OrderStatusPaid)
+	}
+}
+
+// unansweredCaptureProvider fails every capture with err and counts voids.
+type unansweredCaptureProvider struct {
+	payment.PaymentProvider
+	err   error
+	voids int
+}
+
+func (p *unansweredCaptureProvider) Capture(ctx context.Context, req payment.CaptureRequest) error {
+	return p.err
+}
+
+func (p *unansweredCaptureProvider) Void(ctx context.Context, req payment.VoidRequest) error {
+	p.voids++
+	return p.PaymentProvider.Void(ctx, req)
+}
+
+func TestConfirmCheckoutLeavesUnknownCaptureOutcomePending(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"provider timeout", payment.ErrTimeout},
+		{"deadline", fmt.Errorf("capture: %w", context.DeadlineExceeded)},
+		{"unavailable", status.Error(codes.Unavailable, "connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			provider := &unansweredCaptureProvider{err: tt.err}
+			u, _ := newTestUseCase(t, func(m *repository.MemoryRepository) payment.PaymentProvider {
+				provider.PaymentProvider = repository.NewMemoryBalanceProvider(m)
+				return provider
+			})
+
+			if _, err := u.AddItemToCart(ctx, AddItemToCartRequest{ClientId: 1, ProductID: 1, Quantity: 1}); err != nil {
+				t.Fatalf("AddItemToCart() error = %v", err)
+			}
+			checkout, err := u.CreateCheckout(ctx, CreateCheckoutRequest{ClientId: 1})
+			if err != nil {
+				t.Fatalf("CreateCheckout() error = %v", err)
+			}
+
+			confirmed, err := u.ConfirmCheckout(ctx, ConfirmCheckoutRequest{ClientId: 1, CheckoutID: checkout.CheckoutID})
+			if err != nil {
+				t.Fatalf("ConfirmCheckout() error = %v", err)
+			}
+			if confirmed.Status != OrderStatusPending {
+				t.Errorf("order status = %s, want %s", confirmed.Status, OrderStatusPending)
+			}
+			if provider.voids != 0 {
+				t.Errorf("voids = %d, want the authorization kept", provider.voids)
+			}
+			if cart, err := u.GetCart(ctx, GetCartRequest{ClientId: 1}); err == nil && len(cart.CartItems) > 0 {
+				t.Errorf("cart = %+v, want the items kept in the pending order", cart.CartItems)
+			}
+		})
+	}
+}
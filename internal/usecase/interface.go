@@ -9,4 +9,18 @@ type Interface interface {
 	DeleteItemFromCart(ctx context.Context, req DeleteItemFromCartRequest) (resp DeleteItemFromCartResponse, err error)
 	GetCart(ctx context.Context, req GetCartRequest) (resp GetCartResponse, err error)
 	SimulatePayment(ctx context.Context, req PaymentRequest) (resp PaymentResponse, err error)
+	AddItemToGuestCart(ctx context.Context, req AddItemToGuestCartRequest) (resp AddItemToGuestCartResponse, err error)
+	DeleteItemFromGuestCart(ctx context.Context, req DeleteItemFromGuestCartRequest) (resp DeleteItemFromGuestCartResponse, err error)
+	GetGuestCart(ctx context.Context, req GetGuestCartRequest) (resp GetCartResponse, err error)
+	MergeCart(ctx context.Context, req MergeCartRequest) (resp MergeCartResponse, err error)
+	ApplyPromoCode(ctx context.Context, req ApplyPromoCodeRequest) (resp ApplyPromoCodeResponse, err error)
+	RemovePromoCode(ctx context.Context, req RemovePromoCodeRequest) (resp RemovePromoCodeResponse, err error)
+	AddAddress(ctx context.Context, req AddAddressRequest) (resp AddAddressResponse, err error)
+	ListAddresses(ctx context.Context, req ListAddressesRequest) (resp ListAddressesResponse, err error)
+	DeleteAddress(ctx context.Context, req DeleteAddressRequest) (resp DeleteAddressResponse, err error)
+	GetShippingOptions(ctx context.Context, req GetShippingOptionsRequest) (resp GetShippingOptionsResponse, err error)
+	Checkout(ctx context.Context, req CheckoutRequest) (resp CheckoutResponse, err error)
+	CreateCheckout(ctx context.Context, req CreateCheckoutRequest) (resp CreateCheckoutResponse, err error)
+	ConfirmCheckout(ctx context.Context, req ConfirmCheckoutRequest) (resp ConfirmCheckoutResponse, err error)
+	SettlePayment(ctx context.Context, req SettlePaymentRequest) (resp SettlePaymentResponse, err error)
 }
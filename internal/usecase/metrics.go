@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	paymentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "marketserv_payments_total",
+		Help: "Order payments by result: succeeded, failed or pending.",
+	}, []string{"result"})
+	revenueTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "marketserv_revenue_total",
+		Help: "Sum of captured order totals.",
+	})
+	kafkaMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "marketserv_kafka_messages_total",
+		Help: "Kafka publish attempts by topic and result: success or failure.",
+	}, []string{"topic", "result"})
+)
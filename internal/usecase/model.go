@@ -1,5 +1,7 @@
 package usecase
 
+import "time"
+
 type FindClientByUsernameRequest struct {
 	ClientID int
 }
@@ -64,18 +66,205 @@ type CartItem struct {
 	ProductID       int32   `json:"id" db:"id"`
 	ProductQuantity int32   `json:"quantity" db:"quantity"`
 	ProductPrice    float64 `json:"price" db:"price"`
+	CurrentPrice    float64 `json:"current_price"`
+	PriceChanged    bool    `json:"price_changed"`
+	TaxClass        string  `json:"tax_class"`
+	WeightGrams     int     `json:"weight_grams"`
 }
 
 type GetCartResponse struct {
-	CartItems  []CartItem
-	TotalPrice string
+	CartItems         []CartItem
+	TotalPrice        string
+	PricesChanged     bool
+	CurrentTotalPrice string
+	PromoCode         string
+	Discounts         []DiscountLine
+	DiscountTotal     string
+	Subtotal          string // current total minus discounts
+	TaxTotal          string
+	GrandTotal        string // amount SimulatePayment charges
+
+	promoErr error
+}
+
+type DiscountLine struct {
+	Code        string  `json:"code"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
 }
 
 type PaymentRequest struct {
-	ClientId int32 `json:"client_id" db:"client_id"`
+	ClientId          int32  `json:"client_id" db:"client_id"`
+	AcknowledgedTotal string `json:"acknowledged_total"`
+	AddressID         int32  `json:"address_id"`
+	ShippingOption    string `json:"shipping_option"`
 }
 
 type PaymentResponse struct {
-	Success bool `json:"payment success"`
-	Message string
+	Success      bool `json:"payment success"`
+	Message      string
+	OrderID      int64  `json:"order_id"`
+	ChargedTotal string `json:"charged_total"`
+}
+
+type AddItemToGuestCartRequest struct {
+	SessionToken string `json:"session_token"`
+	ProductID    int32  `json:"product_id" db:"product_id"`
+	Quantity     int32  `json:"quantity" db:"quantity"`
+}
+
+type AddItemToGuestCartResponse struct {
+	SessionToken string `json:"session_token"`
+	Success      bool   `json:"add success"`
+}
+
+type DeleteItemFromGuestCartRequest struct {
+	SessionToken string `json:"session_token"`
+	ProductID    int32  `json:"product_id" db:"product_id"`
+}
+
+type DeleteItemFromGuestCartResponse struct {
+	Success bool `json:"delete success"`
+}
+
+type GetGuestCartRequest struct {
+	SessionToken string `json:"session_token"`
+}
+
+type MergeCartRequest struct {
+	SessionToken string `json:"session_token"`
+	ClientId     int32  `json:"client_id" db:"client_id"`
+}
+
+type MergedItem struct {
+	ProductID         int32 `json:"product_id" db:"product_id"`
+	RequestedQuantity int32 `json:"requested_quantity"`
+	MergedQuantity    int32 `json:"merged_quantity"`
+}
+
+type MergeCartResponse struct {
+	Items []MergedItem
+}
+
+type ApplyPromoCodeRequest struct {
+	ClientId int32  `json:"client_id" db:"client_id"`
+	Code     string `json:"code"`
+}
+
+type ApplyPromoCodeResponse struct {
+	Discount DiscountLine
+}
+
+type RemovePromoCodeRequest struct {
+	ClientId int32 `json:"client_id" db:"client_id"`
+}
+
+type RemovePromoCodeResponse struct {
+	Success bool `json:"remove success"`
+}
+
+type Address struct {
+	AddressID  int32  `json:"id" db:"id"`
+	ClientId   int32  `json:"client_id" db:"client_id"`
+	Recipient  string `json:"recipient" db:"recipient"`
+	Line1      string `json:"line1" db:"line1"`
+	Line2      string `json:"line2" db:"line2"`
+	City       string `json:"city" db:"city"`
+	PostalCode string `json:"postal_code" db:"postal_code"`
+	Country    string `json:"country" db:"country"`
+}
+
+type AddAddressRequest struct {
+	Address Address
+}
+
+type AddAddressResponse struct {
+	Address Address
+}
+
+type ListAddressesRequest struct {
+	ClientId int32 `json:"client_id" db:"client_id"`
+}
+
+type ListAddressesResponse struct {
+	Addresses []Address
+}
+
+type DeleteAddressRequest struct {
+	ClientId  int32 `json:"client_id" db:"client_id"`
+	AddressID int32 `json:"address_id"`
+}
+
+type DeleteAddressResponse struct {
+	Success bool `json:"delete success"`
+}
+
+type GetShippingOptionsRequest struct {
+	ClientId  int32 `json:"client_id" db:"client_id"`
+	AddressID int32 `json:"address_id"`
+}
+
+type ShippingOption struct {
+	Code         string  `json:"code"`
+	Name         string  `json:"name"`
+	Cost         float64 `json:"cost"`
+	DeliveryDays int     `json:"delivery_days"`
+}
+
+type GetShippingOptionsResponse struct {
+	Options []ShippingOption
+}
+
+type CheckoutRequest struct {
+	ClientId          int32  `json:"client_id" db:"client_id"`
+	AddressID         int32  `json:"address_id"`
+	ShippingOption    string `json:"shipping_option"`
+	AcknowledgedTotal string `json:"acknowledged_total"`
+}
+
+type CheckoutResponse struct {
+	OrderID      int64  `json:"order_id"`
+	ChargedTotal string `json:"charged_total"`
+}
+
+type CreateCheckoutRequest struct {
+	ClientId          int32  `json:"client_id" db:"client_id"`
+	AddressID         int32  `json:"address_id"`
+	ShippingOption    string `json:"shipping_option"`
+	AcknowledgedTotal string `json:"acknowledged_total"`
+}
+
+type CreateCheckoutResponse struct {
+	CheckoutID    string
+	Items         []CartItem
+	Discounts     []DiscountLine
+	ItemsTotal    string
+	DiscountTotal string
+	TaxTotal      string
+	ShippingCost  string
+	Total         string
+	ExpiresAt     time.Time
+}
+
+type ConfirmCheckoutRequest struct {
+	ClientId   int32  `json:"client_id" db:"client_id"`
+	CheckoutID string `json:"checkout_id"`
+}
+
+type ConfirmCheckoutResponse struct {
+	OrderID      int64  `json:"order_id"`
+	ChargedTotal string `json:"charged_total"`
+	Status       string `json:"status"`
+}
+
+type SettlePaymentRequest struct {
+	EventID          string `json:"event_id"`
+	PaymentReference string `json:"payment_reference"`
+	Result           string `json:"result"`
+}
+
+type SettlePaymentResponse struct {
+	OrderID int64  `json:"order_id"`
+	Status  string `json:"status"`
+	Changed bool   `json:"changed"`
 }
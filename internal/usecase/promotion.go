@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"time"
+
+	"github.com/Dmitrij-bot/marketserv/internal/promotion"
+	"github.com/Dmitrij-bot/marketserv/internal/repository"
+)
+
+func (u *UserUseCase) ApplyPromoCode(ctx context.Context, req ApplyPromoCodeRequest) (resp ApplyPromoCodeResponse, err error) {
+
+	code := promotion.NormalizeCode(req.Code)
+	if code == "" {
+		return ApplyPromoCodeResponse{}, domain.InvalidArgument("code", "promo code cannot be empty")
+	}
+
+	// The cart, the redemptions and the code set on the cart are read and
+	// written in one transaction, so the discount matches what was applied.
+	var discount DiscountLine
+	err = u.r.InTx(ctx, func(ctx context.Context) error {
+		cart, err := u.currentCart(ctx, req.ClientId)
+		if err != nil {
+			return fmt.Errorf("failed to get cart for user_id %d: %w", req.ClientId, err)
+		}
+
+		discount, err = u.evaluatePromo(ctx, req.ClientId, code, cart.CartItems)
+		if err != nil {
+			return fmt.Errorf("failed to apply promo code %s: %w", code, err)
+		}
+
+		_, err = u.r.SetCartPromoCode(
+			ctx,
+			repository.SetCartPromoCodeRequest{
+				ClientId: req.ClientId,
+				Code:     code,
+			})
+		if err != nil {
+			return fmt.Errorf("failed to apply promo code %s: %w", code, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return ApplyPromoCodeResponse{}, err
+	}
+
+	message := fmt.Sprintf("Промокод применён к корзине {\"client_id\":%d,\"code\":%q,\"discount\":%.2f}",
+		req.ClientId, code, discount.Amount)
+
+	u.publish(ctx, message)
+
+	return ApplyPromoCodeResponse{Discount: discount}, nil
+}
+
+func (u *UserUseCase) RemovePromoCode(ctx context.Context, req RemovePromoCodeRequest) (resp RemovePromoCodeResponse, err error) {
+
+	setResp, err := u.r.SetCartPromoCode(
+		ctx,
+		repository.SetCartPromoCodeRequest{
+			ClientId: req.ClientId,
+		})
+	if err != nil {
+		return RemovePromoCodeResponse{Success: false}, fmt.Errorf("failed to remove promo code: %w", err)
+	}
+
+	return RemovePromoCodeResponse{Success: setResp.Success}, nil
+}
+
+// evaluatePromo returns the discount the promo code gives on items at their
+// current prices.
+func (u *UserUseCase) evaluatePromo(ctx context.Context, clientID int32, code string, items []CartItem) (DiscountLine, error) {
+
+	promoResp, err := u.r.GetPromotion(ctx, repository.GetPromotionRequest{Code: code})
+	if err != nil {
+		return DiscountLine{}, err
+	}
+
+	usesResp, err := u.r.CountPromoRedemptions(
+		ctx,
+		repository.CountPromoRedemptionsRequest{
+			Code:     code,
+			ClientId: clientID,
+		})
+	if err != nil {
+		return DiscountLine{}, err
+	}
+
+	lines := make([]promotion.Line, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, promotion.Line{
+			ProductID: item.ProductID,
+			Quantity:  item.ProductQuantity,
+			Price:     item.CurrentPrice,
+		})
+	}
+
+	discount, err := promotion.Evaluate(toPromotion(promoResp.Promotion), lines, time.Now(), usesResp.Count)
+	if err != nil {
+		return DiscountLine{}, err
+	}
+
+	return DiscountLine{
+		Code:        discount.Code,
+		Description: discount.Description,
+		Amount:      discount.Amount,
+	}, nil
+}
+
+func toPromotion(p repository.Promotion) promotion.Promotion {
+	return promotion.Promotion{
+		Code:                p.Code,
+		Description:         p.Description,
+		Type:                promotion.RuleType(p.RuleType),
+		Percent:             p.Percent,
+		Amount:              p.Amount,
+		ProductID:           p.ProductID,
+		BuyQuantity:         p.BuyQuantity,
+		FreeQuantity:        p.FreeQuantity,
+		MinSpend:            p.MinSpend,
+		ValidFrom:           p.ValidFrom.Time,
+		ValidUntil:          p.ValidUntil.Time,
+		UsageLimitPerClient: p.UsageLimitPerClient,
+	}
+}
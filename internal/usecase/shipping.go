@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"strings"
+
+	"github.com/Dmitrij-bot/marketserv/internal/repository"
+	"github.com/Dmitrij-bot/marketserv/internal/shipping"
+)
+
+func (u *UserUseCase) AddAddress(ctx context.Context, req AddAddressRequest) (resp AddAddressResponse, err error) {
+
+	a := req.Address
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	if a.ClientId == 0 || a.Recipient == "" || a.Line1 == "" || a.City == "" || a.PostalCode == "" || a.Country == "" {
+		return AddAddressResponse{}, domain.InvalidArgument("address", "invalid address: recipient, line1, city, postal code and country are required")
+	}
+
+	addResp, err := u.r.AddAddress(ctx, repository.AddAddressRequest{
+		Address: repository.Address{
+			ClientId:   a.ClientId,
+			Recipient:  a.Recipient,
+			Line1:      a.Line1,
+			Line2:      a.Line2,
+			City:       a.City,
+			PostalCode: a.PostalCode,
+			Country:    a.Country,
+		},
+	})
+	if err != nil {
+		return AddAddressResponse{}, fmt.Errorf("failed to add address: %w", err)
+	}
+
+	a.AddressID = addResp.AddressID
+	return AddAddressResponse{Address: a}, nil
+}
+
+func (u *UserUseCase) ListAddresses(ctx context.Context, req ListAddressesRequest) (resp ListAddressesResponse, err error) {
+
+	listResp, err := u.r.ListAddresses(ctx, repository.ListAddressesRequest{ClientId: req.ClientId})
+	if err != nil {
+		return ListAddressesResponse{}, fmt.Errorf("failed to list addresses: %w", err)
+	}
+
+	for _, a := range listResp.Addresses {
+		resp.Addresses = append(resp.Addresses, toAddress(a))
+	}
+	return resp, nil
+}
+
+func (u *UserUseCase) DeleteAddress(ctx context.Context, req DeleteAddressRequest) (resp DeleteAddressResponse, err error) {
+
+	deleteResp, err := u.r.DeleteAddress(ctx, repository.DeleteAddressRequest{
+		ClientId:  req.ClientId,
+		AddressID: req.AddressID,
+	})
+	if err != nil {
+		return DeleteAddressResponse{Success: false}, fmt.Errorf("failed to delete address: %w", err)
+	}
+
+	return DeleteAddressResponse{Success: deleteResp.Success}, nil
+}
+
+func (u *UserUseCase) GetShippingOptions(ctx context.Context, req GetShippingOptionsRequest) (resp GetShippingOptionsResponse, err error) {
+
+	cart, err := u.currentCart(ctx, req.ClientId)
+	if err != nil {
+		return GetShippingOptionsResponse{}, fmt.Errorf("failed to get cart for user_id %d: %w", req.ClientId, err)
+	}
+
+	addressResp, err := u.r.GetAddress(ctx, repository.GetAddressRequest{
+		ClientId:  req.ClientId,
+		AddressID: req.AddressID,
+	})
+	if err != nil {
+		return GetShippingOptionsResponse{}, err
+	}
+
+	options, err := u.shippingOptions(addressResp.Address.Country, cart.CartItems)
+	if err != nil {
+		return GetShippingOptionsResponse{}, err
+	}
+
+	for _, o := range options {
+		resp.Options = append(resp.Options, ShippingOption{
+			Code:         o.Code,
+			Name:         o.Name,
+			Cost:         o.Cost,
+			DeliveryDays: o.DeliveryDays,
+		})
+	}
+	return resp, nil
+}
+
+func (u *UserUseCase) shippingOptions(country string, items []CartItem) ([]shipping.Option, error) {
+
+	weight := 0
+	for _, item := range items {
+		weight += item.WeightGrams * int(item.ProductQuantity)
+	}
+
+	options, err := u.shipping.Options(shipping.Parcel{
+		WeightGrams: weight,
+		Country:     country,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate shipping: %w", err)
+	}
+	return options, nil
+}
+
+func (u *UserUseCase) shippingOption(address repository.Address, code string, items []CartItem) (shipping.Option, error) {
+
+	options, err := u.shippingOptions(address.Country, items)
+	if err != nil {
+		return shipping.Option{}, err
+	}
+
+	option, ok := shipping.Find(options, code)
+	if !ok {
+		return shipping.Option{}, domain.InvalidArgument("shipping_option", "shipping option %q is not available for address %d", code, address.AddressID)
+	}
+	return option, nil
+}
+
+func toAddress(a repository.Address) Address {
+	return Address{
+		AddressID:  a.AddressID,
+		ClientId:   a.ClientId,
+		Recipient:  a.Recipient,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+	}
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"github.com/Dmitrij-bot/marketserv/internal/repository"
+	"github.com/Dmitrij-bot/marketserv/internal/shipping"
+	"github.com/Dmitrij-bot/marketserv/internal/tax"
+	"github.com/Dmitrij-bot/marketserv/pkg/kafka"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+// newTaxTestUseCase taxes at 20% in RU, the default region, and at 10% in BY.
+func newTaxTestUseCase(t *testing.T, clientRegion string) *UserUseCase {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := repository.NewMemoryRepository(repository.Config{}, repository.MemoryData{
+		Clients:  []repository.MemoryClient{{ID: 1, Username: "alice", Role: "user", Region: clientRegion, Balance: 1000}},
+		Products: []repository.MemoryProduct{{ID: 1, Name: "Wireless mouse", Price: 100, Quantity: 5}},
+	}, log)
+	taxes := tax.NewTable(tax.Config{
+		DefaultRegion: "RU",
+		DefaultClass:  "standard",
+		Rates: map[string]map[string]float64{
+			"RU": {"standard": 20},
+			"BY": {"standard": 10},
+		},
+	})
+	rates := shipping.NewTable(shipping.Config{
+		DefaultZone: "all",
+		Methods: []shipping.Method{
+			{Code: "standard", Rates: map[string][]shipping.WeightRate{"all": {{Cost: 5}}}},
+		},
+	})
+	u := New(m, taxes, rates, repository.NewMemoryBalanceProvider(m), kafka.NewProducer(kafka.Config{}, log), log)
+
+	if _, err := u.AddItemToCart(context.Background(), AddItemToCartRequest{ClientId: 1, ProductID: 1, Quantity: 1}); err != nil {
+		t.Fatalf("AddItemToCart() error = %v", err)
+	}
+	return u
+}
+
+func TestGetCartTaxesUnknownRegionAtDefaultRates(t *testing.T) {
+	u := newTaxTestUseCase(t, "KZ")
+
+	cart, err := u.GetCart(context.Background(), GetCartRequest{ClientId: 1})
+	if err != nil {
+		t.Fatalf("GetCart() error = %v", err)
+	}
+	if cart.TaxTotal != "20.00" {
+		t.Errorf("tax = %s, want 20.00 at the rates of the default region", cart.TaxTotal)
+	}
+}
+
+func TestCreateCheckoutTaxesAtAddressCountry(t *testing.T) {
+	ctx := context.Background()
+	u := newTaxTestUseCase(t, "RU")
+
+	added, err := u.AddAddress(ctx, AddAddressRequest{Address: Address{
+		ClientId: 1, Recipient: "Alice", Line1: "Lenina 1", City: "Minsk", PostalCode: "220000", Country: "by",
+	}})
+	if err != nil {
+		t.Fatalf("AddAddress() error = %v", err)
+	}
+
+	checkout, err := u.CreateCheckout(ctx, CreateCheckoutRequest{ClientId: 1, AddressID: added.Address.AddressID, ShippingOption: "standard"})
+	if err != nil {
+		t.Fatalf("CreateCheckout() error = %v", err)
+	}
+	if checkout.TaxTotal != "10.00" || checkout.Total != "115.00" {
+		t.Errorf("checkout tax = %s, total = %s, want 10.00 and 115.00 taxed in BY", checkout.TaxTotal, checkout.Total)
+	}
+}
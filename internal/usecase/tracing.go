@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/Dmitrij-bot/marketserv/internal/usecase")
+
+// kafkaHeaderCarrier adapts Kafka record headers to
+// propagation.TextMapCarrier so consumers can continue the trace.
+type kafkaHeaderCarrier []sarama.RecordHeader
+
+func (c *kafkaHeaderCarrier) Get(key string) string {
+	for _, h := range *c {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c *kafkaHeaderCarrier) Set(key, value string) {
+	for i, h := range *c {
+		if string(h.Key) == key {
+			(*c)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c = append(*c, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (c *kafkaHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c))
+	for _, h := range *c {
+		keys = append(keys, string(h.Key))
+	}
+	return keys
+}
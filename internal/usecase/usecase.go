@@ -4,18 +4,42 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
 	"github.com/Dmitrij-bot/marketserv/internal/repository"
+	"github.com/Dmitrij-bot/marketserv/internal/shipping"
+	"github.com/Dmitrij-bot/marketserv/internal/tax"
+	"github.com/Dmitrij-bot/marketserv/pkg/kafka"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
 	"github.com/IBM/sarama"
-	"log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 )
 
+// ErrPriceChanged is returned by SimulatePayment while the client has not
+// acknowledged the cart total at current prices.
+var ErrPriceChanged = repository.ErrPriceChanged
+
 type UserUseCase struct {
-	r repository.Interface
+	r        repository.Interface
+	tax      tax.TaxCalculator
+	shipping shipping.RateCalculator
+	payments payment.PaymentProvider
+	producer *kafka.Producer
+	log      *slog.Logger
 }
 
-func New(r repository.Interface) *UserUseCase {
+func New(r repository.Interface, taxCalculator tax.TaxCalculator, rateCalculator shipping.RateCalculator, paymentProvider payment.PaymentProvider, producer *kafka.Producer, log *slog.Logger) *UserUseCase {
 	return &UserUseCase{
-		r: r,
+		r:        r,
+		tax:      taxCalculator,
+		shipping: rateCalculator,
+		payments: paymentProvider,
+		producer: producer,
+		log:      log,
 	}
 }
 
@@ -40,7 +64,7 @@ func (u *UserUseCase) FindClientByUsername(ctx context.Context, req FindClientBy
 func (u *UserUseCase) SearchProductByName(ctx context.Context, req SearchProductByNameRequest) (resp SearchProductByNameResponse, err error) {
 
 	if req.ProductName == "" {
-		return SearchProductByNameResponse{}, fmt.Errorf("product name cannot be empty")
+		return SearchProductByNameResponse{}, domain.InvalidArgument("product_name", "product name cannot be empty")
 	}
 
 	productsResp, err := u.r.SearchProductByName(
@@ -87,12 +111,7 @@ func (u *UserUseCase) AddItemToCart(ctx context.Context, req AddItemToCartReques
 		message := fmt.Sprintf("Товар успешно добавлен в корзину {\"client_id\":%d,\"product_id\":%d,\"quantity\":%d}",
 			req.ClientId, req.ProductID, req.Quantity)
 
-		err := u.sendKafkaMessage(message)
-		if err != nil {
-			log.Printf("Ошибка отправки сообщения в Kafka: %v", err)
-		} else {
-			log.Printf("Событие отправлено в Kafka: %v", req)
-		}
+		u.publish(ctx, message)
 	}
 
 	return AddItemToCartResponse{
@@ -117,12 +136,7 @@ func (u *UserUseCase) DeleteItemFromCart(ctx context.Context, req DeleteItemFrom
 		message := fmt.Sprintf("Товар успешно удален из корзины {\"client_id\":%d,\"product_id\":%d}",
 			req.ClientId, req.ProductID)
 
-		err := u.sendKafkaMessage(message)
-		if err != nil {
-			log.Printf("Ошибка отправки сообщения в Kafka: %v", err)
-		} else {
-			log.Printf("Событие отправлено в Kafka: %v", req)
-		}
+		u.publish(ctx, message)
 	}
 
 	return DeleteItemFromCartResponse{
@@ -133,102 +147,191 @@ func (u *UserUseCase) DeleteItemFromCart(ctx context.Context, req DeleteItemFrom
 func (u *UserUseCase) GetCart(ctx context.Context, req GetCartRequest) (resp GetCartResponse, err error) {
 
 	if req.ClientId == 0 {
-		return GetCartResponse{}, fmt.Errorf("invalid user_id: %d", req.ClientId)
+		return GetCartResponse{}, domain.InvalidArgument("user_id", "invalid user_id: %d", req.ClientId)
+	}
+
+	cart, err := u.currentCart(ctx, req.ClientId)
+	if err != nil {
+		return GetCartResponse{}, fmt.Errorf("usecase: failed to get cart for user_id %d: %w", req.ClientId, err)
 	}
-	log.Printf("Received GetCart request for user_id: %d", req.ClientId)
+
+	responseBytes, err := json.Marshal(cart)
+	if err != nil {
+		return GetCartResponse{}, fmt.Errorf("ошибка сериализации ответа: %w", err)
+	}
+
+	message := fmt.Sprintf("Данные корзины: %s", string(responseBytes))
+	u.publish(ctx, message)
+
+	return cart, nil
+}
+
+// currentCart returns the cart of a client with every line compared against
+// the current product price.
+func (u *UserUseCase) currentCart(ctx context.Context, clientID int32) (resp GetCartResponse, err error) {
 
 	getResp, err := u.r.GetCart(
 		ctx,
 		repository.GetCartRequest{
-			ClientId: req.ClientId})
+			ClientId: clientID})
 	if err != nil {
-		log.Printf("Error fetching cart for user_id %d: %v", req.ClientId, err)
-		return GetCartResponse{}, fmt.Errorf("usecase: failed to get cart for user_id %d: %v", req.ClientId, err)
+		return GetCartResponse{}, err
 	}
 
-	var cartItems []CartItem
+	productIDs := make([]int32, 0, len(getResp.CartItems))
 	for _, repoItem := range getResp.CartItems {
-		cartItems = append(cartItems, CartItem{
+		productIDs = append(productIDs, repoItem.ProductID)
+	}
+
+	pricesResp, err := u.r.GetProductPrices(
+		ctx,
+		repository.GetProductPricesRequest{
+			ProductIDs: productIDs,
+		})
+	if err != nil {
+		return GetCartResponse{}, err
+	}
+
+	currentTotal := 0.0
+	for _, repoItem := range getResp.CartItems {
+		item := CartItem{
 			ProductID:       repoItem.ProductID,
 			ProductQuantity: repoItem.ProductQuantity,
 			ProductPrice:    repoItem.ProductPrice,
-		})
-	}
+			CurrentPrice:    repoItem.ProductPrice,
+			TaxClass:        pricesResp.TaxClasses[repoItem.ProductID],
+			WeightGrams:     pricesResp.WeightGrams[repoItem.ProductID],
+		}
+		if price, ok := pricesResp.Prices[repoItem.ProductID]; ok && repository.FormatPrice(price) != repository.FormatPrice(repoItem.ProductPrice) {
+			item.CurrentPrice = price
+			item.PriceChanged = true
+			resp.PricesChanged = true
+		}
 
-	responseBytes, err := json.Marshal(GetCartResponse{
-		CartItems:  cartItems,
-		TotalPrice: getResp.TotalPrice,
-	})
-	if err != nil {
-		log.Printf("Ошибка сериализации ответа GetCartResponse: %v", err)
-		return GetCartResponse{}, fmt.Errorf("ошибка сериализации ответа: %w", err)
+		resp.CartItems = append(resp.CartItems, item)
+		currentTotal += item.CurrentPrice * float64(item.ProductQuantity)
 	}
 
-	message := fmt.Sprintf("Данные корзины: %s", string(responseBytes))
-	err = u.sendKafkaMessage(message)
+	resp.TotalPrice = getResp.TotalPrice
+	resp.CurrentTotalPrice = repository.FormatPrice(currentTotal)
+
+	promoResp, err := u.r.GetCartPromoCode(
+		ctx,
+		repository.GetCartPromoCodeRequest{
+			ClientId: clientID,
+		})
 	if err != nil {
-		log.Printf("Ошибка отправки сообщения в Kafka: %v", err)
-	} else {
-		log.Printf("Сообщение отправлено в Kafka: %s", message)
+		return GetCartResponse{}, err
 	}
 
-	log.Printf("Returning from GetCart: CartItems - %v, TotalPrice - %s", cartItems, getResp.TotalPrice)
+	discountTotal := 0.0
+	if promoResp.Code != "" {
+		resp.PromoCode = promoResp.Code
 
-	return GetCartResponse{
-		CartItems:  cartItems,
-		TotalPrice: getResp.TotalPrice,
-	}, nil
-}
+		discount, err := u.evaluatePromo(ctx, clientID, promoResp.Code, resp.CartItems)
+		if err != nil {
+			u.log.InfoContext(ctx, "promo code is not applicable to cart",
+				slog.String("code", promoResp.Code), slog.Int("client_id", int(clientID)), logger.Err(err))
+			resp.promoErr = err
+		} else {
+			resp.Discounts = append(resp.Discounts, discount)
+			discountTotal += discount.Amount
+		}
+	}
 
-func (u *UserUseCase) SimulatePayment(ctx context.Context, req PaymentRequest) (resp PaymentResponse, err error) {
+	resp.DiscountTotal = repository.FormatPrice(discountTotal)
 
-	paymentResp, err := u.r.SimulatePayment(
+	// Without a delivery address yet, the cart is taxed in the client's home
+	// region. CreateCheckout retaxes it for the address it ships to.
+	regionResp, err := u.r.GetClientRegion(
 		ctx,
-		repository.PaymentRequest{
-			ClientId: req.ClientId,
+		repository.GetClientRegionRequest{
+			ClientId: clientID,
 		})
 	if err != nil {
-		if err.Error() == "платёж не выполнен: возможно, недостаточно средств на счёте" {
-			message := fmt.Sprintf("Недостаточно средств для клиента %d для выполнения платежа", req.ClientId)
-			err := u.sendKafkaMessage(message)
-			if err != nil {
-				log.Printf("Ошибка отправки сообщения в Kafka: %v", err)
-			} else {
-				log.Printf("Событие отправлено в Kafka: %v", req)
-			}
-		}
-		return PaymentResponse{Success: false}, fmt.Errorf("ошибка выполнения платежа: %w", err)
+		return GetCartResponse{}, err
 	}
 
-	if paymentResp.Success {
+	taxResp, err := u.calculateTax(regionResp.Region, resp.CartItems, currentTotal, discountTotal)
+	if err != nil {
+		return GetCartResponse{}, err
+	}
 
-		message := fmt.Sprintf("Товар успешно оплачен {\"client_id\":%d}",
-			req.ClientId)
+	resp.Subtotal = repository.FormatPrice(taxResp.Subtotal)
+	resp.TaxTotal = repository.FormatPrice(taxResp.Tax)
+	resp.GrandTotal = repository.FormatPrice(taxResp.GrandTotal)
+	return resp, nil
+}
 
-		err := u.sendKafkaMessage(message)
-		if err != nil {
-			log.Printf("Ошибка отправки сообщения в Kafka: %v", err)
-		} else {
-			log.Printf("Событие отправлено в Kafka: %v", req)
+// calculateTax taxes the cart lines at current prices in region, spreading
+// the discount over the lines in proportion to their amounts.
+func (u *UserUseCase) calculateTax(region string, items []CartItem, currentTotal, discountTotal float64) (tax.Result, error) {
+
+	lines := make([]tax.Line, 0, len(items))
+	for _, item := range items {
+		amount := item.CurrentPrice * float64(item.ProductQuantity)
+		if currentTotal > 0 {
+			amount -= discountTotal * amount / currentTotal
 		}
+		lines = append(lines, tax.Line{
+			ProductID: item.ProductID,
+			TaxClass:  item.TaxClass,
+			Amount:    amount,
+		})
 	}
 
-	return PaymentResponse{
-		Success: paymentResp.Success,
-	}, nil
+	res, err := u.tax.Calculate(region, lines)
+	if err != nil {
+		return tax.Result{}, fmt.Errorf("failed to calculate tax: %w", err)
+	}
+	return res, nil
 }
 
-func (u *UserUseCase) sendKafkaMessage(message interface{}) error {
-	// Настройки продюсера Kafka
-	config := sarama.NewConfig()
-	config.Producer.Return.Successes = true
+// SimulatePayment creates a checkout session for the cart and confirms it
+// right away.
+func (u *UserUseCase) SimulatePayment(ctx context.Context, req PaymentRequest) (resp PaymentResponse, err error) {
+
+	createResp, err := u.CreateCheckout(ctx, CreateCheckoutRequest{
+		ClientId:          req.ClientId,
+		AcknowledgedTotal: req.AcknowledgedTotal,
+		AddressID:         req.AddressID,
+		ShippingOption:    req.ShippingOption,
+	})
+	if err != nil {
+		return PaymentResponse{Success: false}, fmt.Errorf("ошибка выполнения платежа: %w", err)
+	}
 
-	// Создаем синхронного продюсера
-	producer, err := sarama.NewSyncProducer([]string{"localhost:29092"}, config)
+	confirmResp, err := u.ConfirmCheckout(ctx, ConfirmCheckoutRequest{
+		ClientId:   req.ClientId,
+		CheckoutID: createResp.CheckoutID,
+	})
 	if err != nil {
-		return fmt.Errorf("ошибка создания Kafka producer: %w", err)
+		return PaymentResponse{Success: false}, fmt.Errorf("ошибка выполнения платежа: %w", err)
 	}
-	defer producer.Close()
+
+	return PaymentResponse{
+		Success:      true,
+		OrderID:      confirmResp.OrderID,
+		ChargedTotal: confirmResp.ChargedTotal,
+	}, nil
+}
+
+func (u *UserUseCase) sendKafkaMessage(ctx context.Context, message interface{}) (err error) {
+	topic := u.producer.Topic()
+	ctx, span := tracer.Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystemKafka, semconv.MessagingDestinationName(topic)),
+	)
+	defer func() {
+		result := "success"
+		if err != nil {
+			result = "failure"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		kafkaMessagesTotal.WithLabelValues(topic, result).Inc()
+	}()
 
 	// Сериализуем сообщение в JSON
 	messageBytes, err := json.Marshal(message)
@@ -238,16 +341,143 @@ func (u *UserUseCase) sendKafkaMessage(message interface{}) error {
 
 	// Создаем сообщение для отправки
 	msg := &sarama.ProducerMessage{
-		Topic: "test1",
+		Topic: topic,
 		Value: sarama.StringEncoder(messageBytes),
 	}
+	otel.GetTextMapPropagator().Inject(ctx, (*kafkaHeaderCarrier)(&msg.Headers))
 
 	// Отправляем сообщение в Kafka
-	partition, offset, err := producer.SendMessage(msg)
+	partition, offset, err := u.producer.SendMessage(msg)
 	if err != nil {
 		return fmt.Errorf("ошибка отправки сообщения в Kafka: %w", err)
 	}
 
-	log.Printf("Сообщение успешно отправлено в Kafka: partition=%d, offset=%d", partition, offset)
+	u.log.DebugContext(ctx, "event published",
+		slog.String("topic", topic), slog.Int("partition", int(partition)), slog.Int64("offset", offset))
 	return nil
 }
+
+// publish sends an event to Kafka. Events are best effort, a failure is
+// logged and does not fail the request.
+func (u *UserUseCase) publish(ctx context.Context, message string) {
+	if !u.producer.Enabled() {
+		return
+	}
+	if err := u.sendKafkaMessage(ctx, message); err != nil {
+		u.log.WarnContext(ctx, "failed to publish event", slog.String("topic", u.producer.Topic()), logger.Err(err))
+	}
+}
+
+func (u *UserUseCase) AddItemToGuestCart(ctx context.Context, req AddItemToGuestCartRequest) (resp AddItemToGuestCartResponse, err error) {
+
+	if req.SessionToken == "" {
+		req.SessionToken, err = newSessionToken()
+		if err != nil {
+			return AddItemToGuestCartResponse{Success: false}, err
+		}
+	} else if !validSessionToken(req.SessionToken) {
+		return AddItemToGuestCartResponse{Success: false}, domain.InvalidArgument("session_token", "invalid session token")
+	}
+
+	addResp, err := u.r.AddItemToGuestCart(
+		ctx,
+		repository.AddItemToGuestCartRequest{
+			SessionToken: req.SessionToken,
+			ProductID:    req.ProductID,
+			Quantity:     req.Quantity,
+		})
+	if err != nil {
+		return AddItemToGuestCartResponse{Success: false}, fmt.Errorf("failed to add to guest cart: %w", err)
+	}
+
+	return AddItemToGuestCartResponse{
+		SessionToken: req.SessionToken,
+		Success:      addResp.Success,
+	}, nil
+}
+
+func (u *UserUseCase) DeleteItemFromGuestCart(ctx context.Context, req DeleteItemFromGuestCartRequest) (resp DeleteItemFromGuestCartResponse, err error) {
+
+	if !validSessionToken(req.SessionToken) {
+		return DeleteItemFromGuestCartResponse{Success: false}, domain.InvalidArgument("session_token", "invalid session token")
+	}
+
+	deleteResp, err := u.r.DeleteItemFromGuestCart(
+		ctx,
+		repository.DeleteItemFromGuestCartRequest{
+			SessionToken: req.SessionToken,
+			ProductID:    req.ProductID,
+		})
+	if err != nil {
+		return DeleteItemFromGuestCartResponse{Success: false}, fmt.Errorf("failed to delete from guest cart: %w", err)
+	}
+
+	return DeleteItemFromGuestCartResponse{
+		Success: deleteResp.Success,
+	}, nil
+}
+
+func (u *UserUseCase) GetGuestCart(ctx context.Context, req GetGuestCartRequest) (resp GetCartResponse, err error) {
+
+	if !validSessionToken(req.SessionToken) {
+		return GetCartResponse{}, domain.InvalidArgument("session_token", "invalid session token")
+	}
+
+	getResp, err := u.r.GetGuestCart(
+		ctx,
+		repository.GetGuestCartRequest{
+			SessionToken: req.SessionToken,
+		})
+	if err != nil {
+		return GetCartResponse{}, fmt.Errorf("usecase: failed to get guest cart: %w", err)
+	}
+
+	var cartItems []CartItem
+	for _, repoItem := range getResp.CartItems {
+		cartItems = append(cartItems, CartItem{
+			ProductID:       repoItem.ProductID,
+			ProductQuantity: repoItem.ProductQuantity,
+			ProductPrice:    repoItem.ProductPrice,
+		})
+	}
+
+	return GetCartResponse{
+		CartItems:  cartItems,
+		TotalPrice: getResp.TotalPrice,
+	}, nil
+}
+
+func (u *UserUseCase) MergeCart(ctx context.Context, req MergeCartRequest) (resp MergeCartResponse, err error) {
+
+	if !validSessionToken(req.SessionToken) {
+		return MergeCartResponse{}, domain.InvalidArgument("session_token", "invalid session token")
+	}
+	if req.ClientId == 0 {
+		return MergeCartResponse{}, domain.InvalidArgument("user_id", "invalid user_id: %d", req.ClientId)
+	}
+
+	mergeResp, err := u.r.MergeCart(
+		ctx,
+		repository.MergeCartRequest{
+			SessionToken: req.SessionToken,
+			ClientId:     req.ClientId,
+		})
+	if err != nil {
+		return MergeCartResponse{}, fmt.Errorf("failed to merge cart: %w", err)
+	}
+
+	for _, item := range mergeResp.Items {
+		resp.Items = append(resp.Items, MergedItem{
+			ProductID:         item.ProductID,
+			RequestedQuantity: item.RequestedQuantity,
+			MergedQuantity:    item.MergedQuantity,
+		})
+	}
+
+	message := fmt.Sprintf("Гостевая корзина объединена с корзиной клиента {\"client_id\":%d,\"items\":%d}",
+		req.ClientId, len(resp.Items))
+
+	u.publish(ctx, message)
+
+	return resp, nil
+}
@@ -0,0 +1,7 @@
+package webhook
+
+type Config struct {
+	Host             string
+	Secret           string `secret:"true"` // shared HMAC key of the payment provider
+	ToleranceSeconds int    // maximum age of a signed callback
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Dmitrij-bot/marketserv/internal/domain"
+	"github.com/Dmitrij-bot/marketserv/internal/usecase"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const maxBodyBytes = 64 << 10
+
+// RequestIDHeader carries the request id, see grpc.RequestIDHeader.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLen = 128
+
+// Event is the settlement callback sent by a payment provider.
+type Event struct {
+	EventID          string `json:"event_id"`
+	PaymentReference string `json:"payment_reference"`
+	Result           string `json:"result"` // payment.SettlementSucceeded or payment.SettlementFailed
+}
+
+type handler struct {
+	secret    string
+	tolerance time.Duration
+	useCase   *usecase.UserUseCase
+	log       *slog.Logger
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDLen {
+		id = logger.NewRequestID()
+	}
+	ctx := logger.WithRequestID(r.Context(), id)
+	w.Header().Set(RequestIDHeader, id)
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxBodyBytes {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	err = Verify(h.secret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, h.tolerance, time.Now())
+	if err != nil {
+		h.log.WarnContext(ctx, "rejected payment webhook", logger.Err(err))
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	h.log.InfoContext(ctx, "payment webhook received",
+		slog.String("event_id", event.EventID),
+		slog.String("payment_reference", event.PaymentReference),
+		slog.String("result", event.Result),
+	)
+
+	resp, err := h.useCase.SettlePayment(ctx, usecase.SettlePaymentRequest{
+		EventID:          event.EventID,
+		PaymentReference: event.PaymentReference,
+		Result:           event.Result,
+	})
+	switch {
+	case errors.Is(err, domain.ErrInvalidArgument):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, domain.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, domain.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.log.ErrorContext(ctx, "failed to settle payment", slog.String("payment_reference", event.PaymentReference), logger.Err(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Dmitrij-bot/marketserv/internal/payment"
+	"github.com/Dmitrij-bot/marketserv/internal/repository"
+	"github.com/Dmitrij-bot/marketserv/internal/shipping"
+	"github.com/Dmitrij-bot/marketserv/internal/tax"
+	"github.com/Dmitrij-bot/marketserv/internal/usecase"
+	"github.com/Dmitrij-bot/marketserv/pkg/kafka"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+// newTestServer serves the callback handler over a memory repository with a
+// pending order paid through reference "pay-1".
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	m := repository.NewMemoryRepository(repository.Config{}, repository.MemoryData{
+		Clients:  []repository.MemoryClient{{ID: 1, Username: "alice", Role: "user"}},
+		Products: []repository.MemoryProduct{{ID: 1, Name: "Wireless mouse", Price: 100, Quantity: 5}},
+	}, log)
+	if _, err := m.AddItemToCart(ctx, repository.AddItemToCartRequest{ClientId: 1, ProductID: 1, Quantity: 1}); err != nil {
+		t.Fatalf("AddItemToCart() error = %v", err)
+	}
+	if _, err := m.CreateCheckoutSession(ctx, repository.CreateCheckoutSessionRequest{ID: "s1", ClientId: 1, ExpectedTotal: 100}); err != nil {
+		t.Fatalf("CreateCheckoutSession() error = %v", err)
+	}
+	_, err := m.ConfirmCheckoutSession(ctx, repository.ConfirmCheckoutSessionRequest{
+		ID: "s1", ClientId: 1, PaymentProvider: payment.ProviderFake, PaymentReference: "pay-1",
+	})
+	if err != nil {
+		t.Fatalf("ConfirmCheckoutSession() error = %v", err)
+	}
+
+	u := usecase.New(m, tax.NewTable(tax.Config{}), shipping.NewTable(shipping.Config{}), nil, kafka.NewProducer(kafka.Config{}, log), log)
+	srv := httptest.NewServer(&handler{secret: testSecret, tolerance: 5 * time.Minute, useCase: u, log: log})
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func post(t *testing.T, srv *httptest.Server, body string, timestamp int64, signature string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+CallbackPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func postSigned(t *testing.T, srv *httptest.Server, body string, timestamp int64) *http.Response {
+	t.Helper()
+	return post(t, srv, body, timestamp, Sign(testSecret, timestamp, []byte(body)))
+}
+
+func settlement(t *testing.T, resp *http.Response) usecase.SettlePaymentResponse {
+	t.Helper()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d (%s), want 200", resp.StatusCode, b)
+	}
+	var settled usecase.SettlePaymentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&settled); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return settled
+}
+
+const paidEvent = `{"event_id":"evt-1","payment_reference":"pay-1","result":"succeeded"}`
+
+func TestHandlerRejectsBadSignature(t *testing.T) {
+	srv := newTestServer(t)
+	now := time.Now().Unix()
+
+	resp := post(t, srv, paidEvent, now, Sign("other secret", now, []byte(paidEvent)))
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+
+	// The rejected callback changed nothing.
+	if settled := settlement(t, postSigned(t, srv, paidEvent, now)); !settled.Changed {
+		t.Errorf("settlement after a rejected callback = %+v, want a change", settled)
+	}
+}
+
+func TestHandlerRejectsStaleTimestamp(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := postSigned(t, srv, paidEvent, time.Now().Add(-10*time.Minute).Unix())
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestHandlerSettlesPendingOrder(t *testing.T) {
+	srv := newTestServer(t)
+
+	settled := settlement(t, postSigned(t, srv, paidEvent, time.Now().Unix()))
+	if !settled.Changed || settled.Status != repository.OrderStatusPaid || settled.OrderID == 0 {
+		t.Errorf("settlement = %+v, want the order changed to paid", settled)
+	}
+}
+
+func TestHandlerAcknowledgesRedelivery(t *testing.T) {
+	srv := newTestServer(t)
+	settlement(t, postSigned(t, srv, paidEvent, time.Now().Unix()))
+
+	settled := settlement(t, postSigned(t, srv, paidEvent, time.Now().Unix()))
+	if settled.Changed || settled.Status != repository.OrderStatusPaid {
+		t.Errorf("redelivered settlement = %+v, want paid without a change", settled)
+	}
+}
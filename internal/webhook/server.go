@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"github.com/Dmitrij-bot/marketserv/internal/usecase"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const CallbackPath = "/payments/callback"
+
+// Server receives payment provider callbacks over HTTP.
+type Server struct {
+	cfg        Config
+	useCase    *usecase.UserUseCase
+	httpServer *http.Server
+	log        *slog.Logger
+}
+
+func NewServer(cfg Config, useCase *usecase.UserUseCase, log *slog.Logger) *Server {
+	return &Server{
+		cfg:     cfg,
+		useCase: useCase,
+		log:     log,
+	}
+}
+
+func (s *Server) tolerance() time.Duration {
+	if s.cfg.ToleranceSeconds <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(s.cfg.ToleranceSeconds) * time.Second
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	if s.httpServer != nil {
+		return errors.New("webhook server is already running")
+	}
+	if s.cfg.Secret == "" {
+		return errors.New("webhook secret is not configured")
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(CallbackPath, &handler{
+		secret:    s.cfg.Secret,
+		tolerance: s.tolerance(),
+		useCase:   s.useCase,
+		log:       s.log,
+	})
+
+	lis, err := net.Listen("tcp", s.cfg.Host)
+	if err != nil {
+		return err
+	}
+
+	s.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := s.httpServer.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error("failed to serve webhooks", logger.Err(err))
+		}
+	}()
+
+	s.log.Info("webhook server is running", slog.String("addr", s.cfg.Host), slog.String("path", CallbackPath))
+
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+
+	err := s.httpServer.Shutdown(ctx)
+	s.httpServer = nil
+	return err
+}
@@ -0,0 +1,15 @@
+-- Notifies the service about changed products so it can invalidate its
+-- product and search caches.
+CREATE OR REPLACE FUNCTION notify_product_updated() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('products_updated', COALESCE(NEW.id, OLD.id)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS products_updated ON products;
+
+CREATE TRIGGER products_updated
+    AFTER INSERT OR UPDATE OF name, description, price OR DELETE ON products
+    FOR EACH ROW
+EXECUTE FUNCTION notify_product_updated();
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS promotions
+(
+    code                   TEXT PRIMARY KEY,
+    description            TEXT           NOT NULL DEFAULT '',
+    rule_type              TEXT           NOT NULL CHECK (rule_type IN ('percentage', 'fixed_amount', 'buy_x_get_y', 'minimum_spend')),
+    percent                NUMERIC(5, 2)  NOT NULL DEFAULT 0,
+    amount                 NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    product_id             INT REFERENCES products (id),
+    buy_quantity           INT            NOT NULL DEFAULT 0,
+    free_quantity          INT            NOT NULL DEFAULT 0,
+    min_spend              NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    valid_from             TIMESTAMPTZ,
+    valid_until            TIMESTAMPTZ,
+    usage_limit_per_client INT            NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions
+(
+    id          BIGSERIAL PRIMARY KEY,
+    code        TEXT           NOT NULL REFERENCES promotions (code),
+    client_id   INT            NOT NULL,
+    discount    NUMERIC(12, 2) NOT NULL,
+    redeemed_at TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS promo_redemptions_code_client_idx ON promo_redemptions (code, client_id);
+
+ALTER TABLE carts
+    ADD COLUMN IF NOT EXISTS promo_code TEXT REFERENCES promotions (code);
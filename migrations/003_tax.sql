@@ -0,0 +1,5 @@
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS tax_class TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE clients_table
+    ADD COLUMN IF NOT EXISTS region TEXT NOT NULL DEFAULT '';
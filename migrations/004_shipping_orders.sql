@@ -0,0 +1,43 @@
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS weight_grams INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS client_addresses
+(
+    id          SERIAL PRIMARY KEY,
+    client_id   INT         NOT NULL REFERENCES clients_table (id),
+    recipient   TEXT        NOT NULL,
+    line1       TEXT        NOT NULL,
+    line2       TEXT        NOT NULL DEFAULT '',
+    city        TEXT        NOT NULL,
+    postal_code TEXT        NOT NULL,
+    country     TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS client_addresses_client_idx ON client_addresses (client_id) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS orders
+(
+    id              BIGSERIAL PRIMARY KEY,
+    client_id       INT            NOT NULL REFERENCES clients_table (id),
+    status          TEXT           NOT NULL,
+    items_total     NUMERIC(12, 2) NOT NULL,
+    discount        NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    tax             NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    shipping_cost   NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    total           NUMERIC(12, 2) NOT NULL,
+    promo_code      TEXT,
+    address_id      INT REFERENCES client_addresses (id),
+    shipping_option TEXT,
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS order_items
+(
+    order_id   BIGINT         NOT NULL REFERENCES orders (id),
+    product_id INT            NOT NULL REFERENCES products (id),
+    quantity   INT            NOT NULL,
+    price      NUMERIC(12, 2) NOT NULL,
+    PRIMARY KEY (order_id, product_id)
+);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS checkout_sessions
+(
+    id              TEXT PRIMARY KEY,
+    client_id       INT            NOT NULL REFERENCES clients_table (id),
+    cart_id         INT            NOT NULL REFERENCES carts (cart_id),
+    status          TEXT           NOT NULL,
+    items_total     NUMERIC(12, 2) NOT NULL,
+    discount        NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    tax             NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    shipping_cost   NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    total           NUMERIC(12, 2) NOT NULL,
+    promo_code      TEXT,
+    address_id      INT REFERENCES client_addresses (id),
+    shipping_option TEXT,
+    order_id        BIGINT REFERENCES orders (id),
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    expires_at      TIMESTAMPTZ    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS checkout_sessions_client_open_idx ON checkout_sessions (client_id) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS checkout_session_items
+(
+    session_id TEXT           NOT NULL REFERENCES checkout_sessions (id),
+    product_id INT            NOT NULL REFERENCES products (id),
+    quantity   INT            NOT NULL,
+    price      NUMERIC(12, 2) NOT NULL,
+    PRIMARY KEY (session_id, product_id)
+);
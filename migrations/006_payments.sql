@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS payment_authorizations
+(
+    id              TEXT PRIMARY KEY,
+    client_id       INT            NOT NULL REFERENCES clients_table (id),
+    reference       TEXT,
+    status          TEXT           NOT NULL,
+    amount          NUMERIC(12, 2) NOT NULL,
+    captured_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    refunded_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_provider TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_reference TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS payment_events
+(
+    event_id          TEXT PRIMARY KEY,
+    payment_reference TEXT        NOT NULL,
+    status            TEXT        NOT NULL,
+    received_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS orders_payment_reference_idx ON orders (payment_reference);
@@ -0,0 +1,2 @@
+-- Removing the last unit of a cart line used to leave it with quantity 0.
+DELETE FROM cart_items WHERE quantity <= 0;
@@ -0,0 +1,8 @@
+-- Guest carts merged into a client cart. The merge records the token in its
+-- transaction, so a guest cart left in Redis is never merged twice.
+CREATE TABLE IF NOT EXISTS merged_guest_carts
+(
+    session_token TEXT PRIMARY KEY,
+    client_id     INT         NOT NULL,
+    merged_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,6 @@
+package kafka
+
+type Config struct {
+	Brokers []string // bootstrap brokers, host:port
+	Topic   string   // topic events are published to
+}
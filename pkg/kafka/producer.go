@@ -0,0 +1,181 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"github.com/IBM/sarama"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Producer is a synchronous Kafka producer shared by all requests. Kafka is
+// not required to run the service: while the brokers cannot be reached the
+// producer reconnects in the background with backoff and SendMessage fails
+// fast with ErrUnavailable. A producer without brokers is disabled and drops
+// events without connecting.
+type Producer struct {
+	cfg      Config
+	log      *slog.Logger
+	mu       sync.Mutex
+	client   sarama.Client
+	producer sarama.SyncProducer
+	stopped  bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewProducer(cfg Config, log *slog.Logger) *Producer {
+	return &Producer{cfg: cfg, log: log}
+}
+
+var (
+	// ErrDisabled is returned by SendMessage of a producer without brokers.
+	ErrDisabled = errors.New("kafka producer is disabled")
+	// ErrUnavailable is returned by SendMessage while the producer is not
+	// connected to the brokers.
+	ErrUnavailable = errors.New("kafka is unavailable")
+)
+
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// Enabled reports whether the producer has brokers to publish to.
+func (p *Producer) Enabled() bool {
+	return len(p.cfg.Brokers) > 0
+}
+
+// Topic returns the topic events are published to.
+func (p *Producer) Topic() string {
+	return p.cfg.Topic
+}
+
+// Start connects in the background and returns at once.
+func (p *Producer) Start(ctx context.Context) error {
+	if !p.Enabled() {
+		p.log.Info("Kafka is disabled, events are dropped")
+		return nil
+	}
+
+	done := make(chan struct{})
+	p.mu.Lock()
+	p.done = done
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.reconnect(done)
+	}()
+	return nil
+}
+
+// reconnect dials the brokers until it succeeds or the producer is stopped,
+// doubling the wait between attempts.
+func (p *Producer) reconnect(done <-chan struct{}) {
+	backoff := minReconnectBackoff
+	for {
+		err := p.connect()
+		if err == nil {
+			p.log.Info("connected to Kafka")
+			return
+		}
+		p.log.Warn("Kafka is unavailable, events are dropped until it is reachable",
+			slog.Duration("retry_in", backoff), logger.Err(err))
+
+		select {
+		case <-done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxReconnectBackoff)
+	}
+}
+
+// connect dials the brokers without holding p.mu, so that sends fail fast
+// meanwhile.
+func (p *Producer) connect() error {
+	cfg := sarama.NewConfig()
+	cfg.Producer.Return.Successes = true
+	cfg.Net.DialTimeout = 3 * time.Second
+	cfg.Metadata.Retry.Max = 1
+
+	client, err := sarama.NewClient(p.cfg.Brokers, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Kafka: %w", err)
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return errors.Join(producer.Close(), client.Close())
+	}
+	p.client, p.producer = client, producer
+	return nil
+}
+
+// connected returns the producer and its client, or an error while there is
+// no connection.
+func (p *Producer) connected() (sarama.Client, sarama.SyncProducer, error) {
+	if !p.Enabled() {
+		return nil, nil, ErrDisabled
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return nil, nil, errors.New("kafka producer is stopped")
+	}
+	if p.producer == nil {
+		return nil, nil, ErrUnavailable
+	}
+	return p.client, p.producer, nil
+}
+
+// SendMessage delivers msg and returns where it was stored.
+func (p *Producer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	_, producer, err := p.connected()
+	if err != nil {
+		return 0, 0, err
+	}
+	return producer.SendMessage(msg)
+}
+
+// Health reports whether the cluster metadata can be fetched from any broker.
+func (p *Producer) Health(ctx context.Context) error {
+	client, _, err := p.connected()
+	if err != nil {
+		return err
+	}
+	return client.RefreshMetadata()
+}
+
+func (p *Producer) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	done := p.done
+	p.stopped, p.done = true, nil
+	p.mu.Unlock()
+
+	if done != nil {
+		close(done)
+		p.wg.Wait()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.producer == nil {
+		return nil
+	}
+	err := errors.Join(p.producer.Close(), p.client.Close())
+	p.client, p.producer = nil, nil
+	return err
+}
@@ -0,0 +1,50 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"github.com/IBM/sarama"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestSendMessageFailsFastWhileDisconnected(t *testing.T) {
+	p := NewProducer(Config{Brokers: []string{"127.0.0.1:1"}, Topic: "events"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	started := time.Now()
+	_, _, err := p.SendMessage(&sarama.ProducerMessage{Topic: "events", Value: sarama.StringEncoder("event")})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("SendMessage() error = %v, want %v", err, ErrUnavailable)
+	}
+	if elapsed := time.Since(started); elapsed > 100*time.Millisecond {
+		t.Errorf("SendMessage() took %s, want it not to wait for the brokers", elapsed)
+	}
+	if err := p.Health(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Health() error = %v, want %v", err, ErrUnavailable)
+	}
+
+	if err := p.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if err := p.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop() error = %v", err)
+	}
+}
+
+func TestDisabledProducer(t *testing.T) {
+	p := NewProducer(Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, _, err := p.SendMessage(&sarama.ProducerMessage{}); !errors.Is(err, ErrDisabled) {
+		t.Errorf("SendMessage() error = %v, want %v", err, ErrDisabled)
+	}
+	if err := p.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
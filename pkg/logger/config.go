@@ -0,0 +1,12 @@
+package logger
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type Config struct {
+	Level      string   `reload:"true"` // "debug", "info" (default), "warn" or "error"
+	Format     string   // "json" (default) or "text"
+	RedactKeys []string // attribute keys masked in addition to the built-in ones
+}
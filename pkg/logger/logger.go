@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose log records carry the request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request id stored in ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a random id for a request that came without one.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ParseLevel returns the level named by s, info when s is empty.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s != "" {
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			return level, fmt.Errorf("invalid log level %q: %w", s, err)
+		}
+	}
+	return level, nil
+}
+
+// New builds a logger writing to w. Records logged with a context get the
+// request id and the trace id of the current span attached, and attributes
+// with sensitive keys are masked. The level of cfg is stored in level, which
+// can be changed later to adjust the logger at runtime; nil fixes it.
+func New(cfg Config, level *slog.LevelVar, w io.Writer) (*slog.Logger, error) {
+	l, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	if level == nil {
+		level = new(slog.LevelVar)
+	}
+	level.Set(l)
+
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: newRedactor(cfg.RedactKeys),
+	}
+
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	return slog.New(contextHandler{h}), nil
+}
+
+// Err is the attribute errors are logged under.
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
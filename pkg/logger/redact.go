@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"log/slog"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values never reach the log output.
+// They are matched case-insensitively at any depth, so the fields of a
+// shipping address are masked inside a group too.
+var sensitiveKeys = []string{
+	"username",
+	"balance",
+	"password",
+	"secret",
+	"token",
+	"session_token",
+	"email",
+	"phone",
+	"street",
+	"address",
+	"recipient",
+	"line1",
+	"line2",
+	"postal_code",
+}
+
+func newRedactor(extra []string) func(groups []string, a slog.Attr) slog.Attr {
+	keys := make(map[string]struct{}, len(sensitiveKeys)+len(extra))
+	for _, k := range append(sensitiveKeys, extra...) {
+		keys[strings.ToLower(k)] = struct{}{}
+	}
+
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if _, ok := keys[strings.ToLower(a.Key)]; ok {
+			return slog.String(a.Key, redacted)
+		}
+		return a
+	}
+}
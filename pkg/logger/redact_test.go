@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	tests := []struct {
+		name   string
+		extra  []string
+		log    func(l *slog.Logger)
+		hidden []string
+		shown  []string
+	}{
+		{
+			name: "top-level keys",
+			log: func(l *slog.Logger) {
+				l.Info("login", slog.String("username", "alice"), slog.String("Password", "hunter2"), slog.Int("client_id", 7))
+			},
+			hidden: []string{"alice", "hunter2"},
+			shown:  []string{`"client_id":7`},
+		},
+		{
+			name: "address in a group",
+			log: func(l *slog.Logger) {
+				l.Info("address added", slog.Group("shipping",
+					slog.String("recipient", "Alice Smith"),
+					slog.String("line1", "Lenina 1"),
+					slog.String("line2", "apt 5"),
+					slog.String("postal_code", "220000"),
+					slog.String("phone", "+375291234567"),
+					slog.String("country", "BY"),
+				))
+			},
+			hidden: []string{"Alice Smith", "Lenina 1", "apt 5", "220000", "+375291234567"},
+			shown:  []string{`"country":"BY"`},
+		},
+		{
+			name: "nested groups",
+			log: func(l *slog.Logger) {
+				l.Info("checkout", slog.Group("order", slog.Group("address", slog.String("postal_code", "220000"))))
+			},
+			hidden: []string{"220000"},
+		},
+		{
+			name: "group from WithGroup",
+			log: func(l *slog.Logger) {
+				l.WithGroup("req").With(slog.String("session_token", "abc123")).Info("guest cart", slog.String("line1", "Lenina 1"))
+			},
+			hidden: []string{"abc123", "Lenina 1"},
+		},
+		{
+			name: "whole address value",
+			log: func(l *slog.Logger) {
+				l.Info("address added", slog.Any("address", map[string]string{"line1": "Lenina 1"}))
+			},
+			hidden: []string{"Lenina 1"},
+		},
+		{
+			name:  "configured keys",
+			extra: []string{"Card_Number"},
+			log: func(l *slog.Logger) {
+				l.Info("payment", slog.String("card_number", "4242424242424242"))
+			},
+			hidden: []string{"4242424242424242"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l, err := New(Config{Level: "info", RedactKeys: tt.extra}, nil, &buf)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			tt.log(l)
+
+			out := buf.String()
+			if !json.Valid(buf.Bytes()) {
+				t.Fatalf("output is not JSON: %s", out)
+			}
+			for _, s := range tt.hidden {
+				if strings.Contains(out, s) {
+					t.Errorf("output contains %q: %s", s, out)
+				}
+			}
+			for _, s := range tt.shown {
+				if !strings.Contains(out, s) {
+					t.Errorf("output lacks %q: %s", s, out)
+				}
+			}
+			if len(tt.hidden) > 0 && !strings.Contains(out, redacted) {
+				t.Errorf("output lacks %s: %s", redacted, out)
+			}
+		})
+	}
+}
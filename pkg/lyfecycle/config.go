@@ -0,0 +1,7 @@
+package lyfecycle
+
+type Config struct {
+	StartAttempts       int // attempts to start a component, 1 by default
+	RetryBackoffSeconds int // delay before the first retry, doubled after each failure
+	MaxBackoffSeconds   int // upper bound of the retry delay, 0 means unbounded
+}
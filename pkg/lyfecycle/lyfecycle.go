@@ -6,3 +6,9 @@ type Lyfecycle interface {
 	Start(ctx context.Context) error
 	Stop(ctx context.Context) error
 }
+
+// HealthChecker is implemented by components that depend on an external
+// service and can tell whether it is reachable.
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
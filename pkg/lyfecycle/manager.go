@@ -0,0 +1,224 @@
+package lyfecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type component struct {
+	name      string
+	service   Lyfecycle
+	dependsOn []string
+	started   bool
+}
+
+// Manager starts and stops components in dependency order. A component is
+// started once all of its dependencies have started and is stopped once all
+// components depending on it have stopped; unrelated components are started
+// and stopped in parallel.
+type Manager struct {
+	cfg        Config
+	log        *slog.Logger
+	components []*component
+}
+
+func NewManager(cfg Config, log *slog.Logger) *Manager {
+	return &Manager{cfg: cfg, log: log}
+}
+
+// Add registers a component under a unique name. dependsOn lists the names of
+// components that must be running before it starts.
+func (m *Manager) Add(name string, service Lyfecycle, dependsOn ...string) {
+	m.components = append(m.components, &component{name: name, service: service, dependsOn: dependsOn})
+}
+
+// Start starts every component. If one of them cannot be started the ones
+// already running are stopped again and the joined errors are returned.
+func (m *Manager) Start(ctx context.Context) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(map[string]chan struct{}, len(m.components))
+	for _, c := range m.components {
+		done[c.name] = make(chan struct{})
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, c := range m.components {
+		wg.Add(1)
+		go func(c *component) {
+			defer wg.Done()
+
+			for _, dep := range c.dependsOn {
+				select {
+				case <-done[dep]:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if err := m.start(ctx, c); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				cancel()
+				return
+			}
+			close(done[c.name])
+		}(c)
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("start interrupted: %w", err))
+		}
+	}
+	if len(errs) > 0 {
+		stopCtx, stopCancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer stopCancel()
+		if err := m.Stop(stopCtx); err != nil {
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (m *Manager) start(ctx context.Context, c *component) error {
+	attempts := max(m.cfg.StartAttempts, 1)
+	backoff := time.Duration(m.cfg.RetryBackoffSeconds) * time.Second
+
+	for attempt := 1; ; attempt++ {
+		m.log.Info("component is starting", slog.String("component", c.name), slog.Int("attempt", attempt))
+
+		err := c.service.Start(ctx)
+		if err == nil {
+			c.started = true
+			m.log.Info("component started", slog.String("component", c.name))
+			return nil
+		}
+		if attempt >= attempts || ctx.Err() != nil {
+			m.log.Error("component failed to start", slog.String("component", c.name), logger.Err(err))
+			return fmt.Errorf("cannot start %s: %w", c.name, err)
+		}
+
+		m.log.Warn("component failed to start, retrying",
+			slog.String("component", c.name), slog.Duration("backoff", backoff), logger.Err(err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("cannot start %s: %w", c.name, err)
+		}
+
+		backoff *= 2
+		if maxBackoff := time.Duration(m.cfg.MaxBackoffSeconds) * time.Second; maxBackoff > 0 && backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// Stop stops every started component, dependents before their dependencies.
+// A failure does not prevent the remaining components from being stopped;
+// all errors are joined.
+func (m *Manager) Stop(ctx context.Context) error {
+	dependents := make(map[string][]string, len(m.components))
+	done := make(map[string]chan struct{}, len(m.components))
+	for _, c := range m.components {
+		done[c.name] = make(chan struct{})
+		for _, dep := range c.dependsOn {
+			dependents[dep] = append(dependents[dep], c.name)
+		}
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, c := range m.components {
+		wg.Add(1)
+		go func(c *component) {
+			defer wg.Done()
+			defer close(done[c.name])
+
+			for _, d := range dependents[c.name] {
+				<-done[d]
+			}
+			if !c.started {
+				return
+			}
+
+			m.log.Info("component is stopping", slog.String("component", c.name))
+			if err := c.service.Stop(ctx); err != nil {
+				m.log.Error("component failed to stop", slog.String("component", c.name), logger.Err(err))
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("cannot stop %s: %w", c.name, err))
+				mu.Unlock()
+			}
+			c.started = false
+		}(c)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// validate makes sure names are unique, dependencies exist and there are no
+// dependency cycles.
+func (m *Manager) validate() error {
+	byName := make(map[string]*component, len(m.components))
+	for _, c := range m.components {
+		if _, ok := byName[c.name]; ok {
+			return fmt.Errorf("component %s is registered twice", c.name)
+		}
+		byName[c.name] = c
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(m.components))
+	var visit func(c *component, path []string) error
+	visit = func(c *component, path []string) error {
+		switch state[c.name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v", append(path, c.name))
+		case visited:
+			return nil
+		}
+		state[c.name] = visiting
+		for _, dep := range c.dependsOn {
+			d, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("component %s depends on unknown component %s", c.name, dep)
+			}
+			if err := visit(d, append(path, c.name)); err != nil {
+				return err
+			}
+		}
+		state[c.name] = visited
+		return nil
+	}
+	for _, c := range m.components {
+		if err := visit(c, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
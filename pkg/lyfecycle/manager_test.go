@@ -0,0 +1,233 @@
+package lyfecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects "start <name>" and "stop <name>" events in order.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+// before reports whether a was recorded before b.
+func (r *recorder) before(a, b string) bool {
+	events := r.get()
+	i, j := slices.Index(events, a), slices.Index(events, b)
+	return i >= 0 && j >= 0 && i < j
+}
+
+type fakeComponent struct {
+	name     string
+	rec      *recorder
+	failures int   // failed starts before one succeeds, -1 fails forever
+	stopErr  error // returned by Stop
+	started  func(ctx context.Context) error
+	attempts int
+}
+
+func (f *fakeComponent) Start(ctx context.Context) error {
+	f.attempts++
+	if f.failures < 0 || f.attempts <= f.failures {
+		return errors.New(f.name + " is down")
+	}
+	if f.started != nil {
+		if err := f.started(ctx); err != nil {
+			return err
+		}
+	}
+	f.rec.add("start " + f.name)
+	return nil
+}
+
+func (f *fakeComponent) Stop(ctx context.Context) error {
+	f.rec.add("stop " + f.name)
+	return f.stopErr
+}
+
+func newTestManager(cfg Config) *Manager {
+	return NewManager(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestManagerStartsAndStopsInDependencyOrder(t *testing.T) {
+	rec := &recorder{}
+	m := newTestManager(Config{StartAttempts: 1})
+	m.Add("grpc", &fakeComponent{name: "grpc", rec: rec}, "postgres", "redis")
+	m.Add("postgres", &fakeComponent{name: "postgres", rec: rec})
+	m.Add("redis", &fakeComponent{name: "redis", rec: rec})
+	m.Add("monitor", &fakeComponent{name: "monitor", rec: rec}, "redis")
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	for _, order := range [][2]string{{"postgres", "grpc"}, {"redis", "grpc"}, {"redis", "monitor"}} {
+		if !rec.before("start "+order[0], "start "+order[1]) {
+			t.Errorf("%s did not start before %s: %q", order[0], order[1], rec.get())
+		}
+	}
+
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	for _, order := range [][2]string{{"grpc", "postgres"}, {"grpc", "redis"}, {"monitor", "redis"}} {
+		if !rec.before("stop "+order[0], "stop "+order[1]) {
+			t.Errorf("%s did not stop before %s: %q", order[0], order[1], rec.get())
+		}
+	}
+}
+
+func TestManagerStartsIndependentComponentsInParallel(t *testing.T) {
+	rec := &recorder{}
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	// Each component starts only once the other one is starting too.
+	meet := func(ctx context.Context) error {
+		barrier.Done()
+		waited := make(chan struct{})
+		go func() { barrier.Wait(); close(waited) }()
+		select {
+		case <-waited:
+			return nil
+		case <-time.After(time.Second):
+			return errors.New("started alone")
+		}
+	}
+
+	m := newTestManager(Config{StartAttempts: 1})
+	m.Add("a", &fakeComponent{name: "a", rec: rec, started: meet})
+	m.Add("b", &fakeComponent{name: "b", rec: rec, started: meet})
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+}
+
+func TestManagerRetriesWithBackoff(t *testing.T) {
+	rec := &recorder{}
+	db := &fakeComponent{name: "db", rec: rec, failures: 2}
+	m := newTestManager(Config{StartAttempts: 3, RetryBackoffSeconds: 1, MaxBackoffSeconds: 1})
+	m.Add("db", db)
+
+	started := time.Now()
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if db.attempts != 3 {
+		t.Errorf("attempts = %d, want 3", db.attempts)
+	}
+	// Two retries of 1s each, the second capped by MaxBackoffSeconds.
+	if elapsed := time.Since(started); elapsed < 2*time.Second || elapsed > 5*time.Second {
+		t.Errorf("Start() took %s, want about 2s of backoff", elapsed)
+	}
+}
+
+func TestManagerRollsBackOnFailure(t *testing.T) {
+	rec := &recorder{}
+	m := newTestManager(Config{StartAttempts: 2})
+	m.Add("db", &fakeComponent{name: "db", rec: rec})
+	m.Add("cache", &fakeComponent{name: "cache", rec: rec, failures: -1}, "db")
+	m.Add("grpc", &fakeComponent{name: "grpc", rec: rec}, "cache")
+
+	err := m.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "cannot start cache: cache is down") {
+		t.Fatalf("Start() error = %v, want cache's failure", err)
+	}
+	if want := []string{"start db", "stop db"}; !slices.Equal(rec.get(), want) {
+		t.Errorf("events = %q, want %q", rec.get(), want)
+	}
+}
+
+func TestManagerJoinsStopErrors(t *testing.T) {
+	rec := &recorder{}
+	errA, errB := errors.New("a failed"), errors.New("b failed")
+	m := newTestManager(Config{StartAttempts: 1})
+	m.Add("a", &fakeComponent{name: "a", rec: rec, stopErr: errA})
+	m.Add("b", &fakeComponent{name: "b", rec: rec, stopErr: errB}, "a")
+	m.Add("c", &fakeComponent{name: "c", rec: rec}, "a")
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	err := m.Stop(context.Background())
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("Stop() error = %v, want both failures", err)
+	}
+	// A failed stop does not keep the others running.
+	if events := rec.get(); !slices.Contains(events, "stop c") || !slices.Contains(events, "stop a") {
+		t.Errorf("events = %q, want every component stopped", events)
+	}
+}
+
+func TestManagerRejectsInvalidGraphs(t *testing.T) {
+	tests := []struct {
+		name string
+		add  func(m *Manager, rec *recorder)
+		want string
+	}{
+		{
+			name: "cycle",
+			add: func(m *Manager, rec *recorder) {
+				m.Add("a", &fakeComponent{name: "a", rec: rec}, "b")
+				m.Add("b", &fakeComponent{name: "b", rec: rec}, "c")
+				m.Add("c", &fakeComponent{name: "c", rec: rec}, "a")
+			},
+			want: "dependency cycle",
+		},
+		{
+			name: "self dependency",
+			add: func(m *Manager, rec *recorder) {
+				m.Add("a", &fakeComponent{name: "a", rec: rec}, "a")
+			},
+			want: "dependency cycle",
+		},
+		{
+			name: "unknown dependency",
+			add: func(m *Manager, rec *recorder) {
+				m.Add("a", &fakeComponent{name: "a", rec: rec}, "kafka")
+			},
+			want: "depends on unknown component kafka",
+		},
+		{
+			name: "duplicate name",
+			add: func(m *Manager, rec *recorder) {
+				m.Add("a", &fakeComponent{name: "a", rec: rec})
+				m.Add("a", &fakeComponent{name: "a", rec: rec})
+			},
+			want: "registered twice",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			m := newTestManager(Config{StartAttempts: 1})
+			tt.add(m, rec)
+
+			err := m.Start(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Start() error = %v, want %q", err, tt.want)
+			}
+			if events := rec.get(); len(events) > 0 {
+				t.Errorf("events = %q, want nothing started", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+package metrics
+
+type Config struct {
+	Host string
+}
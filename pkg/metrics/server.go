@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const Path = "/metrics"
+
+// Server exposes the default Prometheus registry over HTTP.
+type Server struct {
+	cfg        Config
+	httpServer *http.Server
+	log        *slog.Logger
+}
+
+func NewServer(cfg Config, log *slog.Logger) *Server {
+	return &Server{cfg: cfg, log: log}
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	if s.httpServer != nil {
+		return errors.New("metrics server is already running")
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, promhttp.Handler())
+
+	lis, err := net.Listen("tcp", s.cfg.Host)
+	if err != nil {
+		return err
+	}
+
+	s.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := s.httpServer.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error("failed to serve metrics", logger.Err(err))
+		}
+	}()
+
+	s.log.Info("metrics server is running", slog.String("addr", s.cfg.Host), slog.String("path", Path))
+
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+
+	err := s.httpServer.Shutdown(ctx)
+	s.httpServer = nil
+	return err
+}
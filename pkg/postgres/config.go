@@ -4,7 +4,24 @@ type Config struct {
 	DBHost     string
 	DBPort     string
 	DBUser     string
-	DBPassword string
+	DBPassword string `secret:"true"` // may be empty with client certificate auth
 	DBName     string
 	SSLMode    string
+
+	SSLRootCert string // CA bundle checked with verify-ca and verify-full
+	SSLCert     string // client certificate for certificate auth
+	SSLKey      string // client key, must not be readable by others
+
+	MaxOpenConns            int // 0 means unlimited
+	MaxIdleConns            int // idle connections kept in the pool, 2 when 0
+	ConnMaxLifetimeSeconds  int // connections are closed after this long, 0 keeps them
+	ConnMaxIdleTimeSeconds  int // idle connections are closed after this long, 0 keeps them
+	StatementTimeoutSeconds int // server-side limit of a single statement, 0 disables it
+
+	// Replicas are "host:port" addresses of read replicas reached with the
+	// credentials above. A replica serves reads while it is up and lags the
+	// primary by at most MaxReplicaLagSeconds.
+	Replicas                    []string
+	MaxReplicaLagSeconds        int // staleness tolerated by replica reads, 2 by default
+	ReplicaCheckIntervalSeconds int // replica health and lag probe interval, 5 by default
 }
@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newSaturationGauge reports the share of the connection limit in use, so
+// that an alert can fire before requests start queueing for connections.
+func newSaturationGauge(db *sql.DB, dbName string) prometheus.Collector {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "marketserv_postgres_pool_saturation",
+		Help:        "Connections in use divided by the maximum open connections; 0 when the pool is unlimited.",
+		ConstLabels: prometheus.Labels{"db_name": dbName},
+	}, func() float64 {
+		stats := db.Stats()
+		if stats.MaxOpenConnections <= 0 {
+			return 0
+		}
+		return float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	})
+}
@@ -3,43 +3,156 @@ package postgres
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"github.com/XSAM/otelsql"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type DB struct {
 	*sqlx.DB
-	cfg Config
+	cfg        Config
+	label      string // db_name of the pool metrics
+	stats      prometheus.Collector
+	saturation prometheus.Collector
+	queries    []string
+	stmts      map[string]*sqlx.Stmt
+	log        *slog.Logger
+
+	replicas     []*replica
+	next         atomic.Uint32
+	stopReplicas func()
+}
+
+func NewDB(config Config, log *slog.Logger) *DB {
+	return &DB{
+		cfg:      config,
+		label:    config.DBName,
+		log:      log,
+		replicas: newReplicas(config, log),
+	}
+}
+
+// ConnString returns the lib/pq connection string for the configured database.
+func (d *DB) ConnString() string {
+	params := []struct{ key, value string }{
+		{"host", d.cfg.DBHost},
+		{"port", d.cfg.DBPort},
+		{"user", d.cfg.DBUser},
+		{"password", d.cfg.DBPassword},
+		{"dbname", d.cfg.DBName},
+		{"sslmode", d.cfg.SSLMode},
+		{"sslrootcert", d.cfg.SSLRootCert},
+		{"sslcert", d.cfg.SSLCert},
+		{"sslkey", d.cfg.SSLKey},
+	}
+	if d.cfg.StatementTimeoutSeconds > 0 {
+		// lib/pq passes unknown keys to the server as run-time parameters.
+		ms := strconv.Itoa(d.cfg.StatementTimeoutSeconds * 1000)
+		params = append(params, struct{ key, value string }{"statement_timeout", ms})
+	}
+
+	var parts []string
+	for _, p := range params {
+		if p.value != "" {
+			parts = append(parts, p.key+"="+quoteParam(p.value))
+		}
+	}
+	return strings.Join(parts, " ")
 }
 
-func NewDB(config Config) *DB {
-	return &DB{cfg: config}
+// quoteParam quotes a connection string value so that secrets read from
+// files may contain spaces and quotes.
+func quoteParam(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
 }
 
 func (d *DB) Start(ctx context.Context) error {
-	connectionURL := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		d.cfg.DBHost,
-		d.cfg.DBPort,
-		d.cfg.DBUser,
-		d.cfg.DBPassword,
-		d.cfg.DBName,
-		d.cfg.SSLMode,
+	// Every query run under a traced context gets a child span.
+	sqlDB, err := otelsql.Open("postgres", d.ConnString(),
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBNamespace(d.cfg.DBName)),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
 	)
-
-	db, err := sqlx.Open("postgres", connectionURL)
 	if err != nil {
 		return err
 	}
+	db := sqlx.NewDb(sqlDB, "postgres")
+	d.configurePool(db)
 
 	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return err
+	}
+
+	stmts, err := prepare(ctx, db, d.queries)
+	if err != nil {
+		_ = db.Close()
 		return err
 	}
 
 	d.DB = db
+	d.stmts = stmts
+
+	// Pool stats are exported as go_sql_* metrics labelled with the database name.
+	d.stats = collectors.NewDBStatsCollector(db.DB, d.label)
+	if err := prometheus.Register(d.stats); err != nil {
+		return fmt.Errorf("failed to register pool metrics: %w", err)
+	}
+	d.saturation = newSaturationGauge(db.DB, d.label)
+	if err := prometheus.Register(d.saturation); err != nil {
+		return fmt.Errorf("failed to register pool metrics: %w", err)
+	}
+
+	d.startReplicas()
 	return nil
 }
 
+func (d *DB) configurePool(db *sqlx.DB) {
+	db.SetMaxOpenConns(d.cfg.MaxOpenConns)
+	if d.cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(d.cfg.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(time.Duration(d.cfg.ConnMaxLifetimeSeconds) * time.Second)
+	db.SetConnMaxIdleTime(time.Duration(d.cfg.ConnMaxIdleTimeSeconds) * time.Second)
+}
+
+// Health pings the database.
+func (d *DB) Health(ctx context.Context) error {
+	if d.DB == nil {
+		return errors.New("postgres is not connected")
+	}
+	return d.PingContext(ctx)
+}
+
 func (d *DB) Stop(ctx context.Context) error {
+	d.closeReplicas(ctx)
+	if d.stats != nil {
+		prometheus.Unregister(d.stats)
+		d.stats = nil
+	}
+	if d.saturation != nil {
+		prometheus.Unregister(d.saturation)
+		d.saturation = nil
+	}
+	closeStmts(d.stmts)
+	d.stmts = nil
 	return d.DB.Close()
 }
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+// PrepareOnStart registers queries to be prepared once the database is
+// started. Registered queries passed to QueryContext, QueryRowContext,
+// ExecContext, GetContext and SelectContext run through their prepared
+// statement; any other query is sent as is.
+func (d *DB) PrepareOnStart(queries ...string) {
+	d.queries = append(d.queries, queries...)
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if stmt, ok := d.stmts[query]; ok {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return d.DB.QueryContext(ctx, query, args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if stmt, ok := d.stmts[query]; ok {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return d.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if stmt, ok := d.stmts[query]; ok {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return d.DB.ExecContext(ctx, query, args...)
+}
+
+func (d *DB) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	if stmt, ok := d.stmts[query]; ok {
+		return stmt.GetContext(ctx, dest, args...)
+	}
+	return d.DB.GetContext(ctx, dest, query, args...)
+}
+
+func (d *DB) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	if stmt, ok := d.stmts[query]; ok {
+		return stmt.SelectContext(ctx, dest, args...)
+	}
+	return d.DB.SelectContext(ctx, dest, query, args...)
+}
+
+func prepare(ctx context.Context, db *sqlx.DB, queries []string) (map[string]*sqlx.Stmt, error) {
+	stmts := make(map[string]*sqlx.Stmt, len(queries))
+	for _, query := range queries {
+		if _, ok := stmts[query]; ok {
+			continue
+		}
+		stmt, err := db.PreparexContext(ctx, query)
+		if err != nil {
+			closeStmts(stmts)
+			return nil, fmt.Errorf("failed to prepare %q: %w", strings.Join(strings.Fields(query), " "), err)
+		}
+		stmts[query] = stmt
+	}
+	return stmts, nil
+}
+
+func closeStmts(stmts map[string]*sqlx.Stmt) {
+	for _, stmt := range stmts {
+		_ = stmt.Close()
+	}
+}
@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// replicaLagSQL is how far a replica is behind the primary. A replica that
+// has replayed everything it received reports no lag, even when the last
+// replayed transaction is old because the primary is idle.
+const replicaLagSQL = `
+    SELECT CASE
+        WHEN pg_last_wal_receive_lsn() IS NULL
+          OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+        ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+    END`
+
+type replica struct {
+	db      *DB
+	usable  atomic.Bool // up and within the staleness tolerance
+	closed  atomic.Bool // set before the pool is closed on Stop
+	checked bool        // set by the first probe
+}
+
+func newReplicas(cfg Config, log *slog.Logger) []*replica {
+	replicas := make([]*replica, 0, len(cfg.Replicas))
+	for _, addr := range cfg.Replicas {
+		rcfg := cfg
+		rcfg.DBHost, rcfg.DBPort, _ = net.SplitHostPort(addr)
+		rcfg.Replicas = nil
+		db := NewDB(rcfg, log.With(slog.String("replica", addr)))
+		db.label = cfg.DBName + "@" + addr
+		replicas = append(replicas, &replica{db: db})
+	}
+	return replicas
+}
+
+// Replica returns a database for reads that tolerate MaxReplicaLagSeconds of
+// staleness: a usable replica, picked round robin, or the primary itself when
+// no replica is configured, up or recent enough.
+func (d *DB) Replica() *DB {
+	n := len(d.replicas)
+	start := int(d.next.Add(1))
+	for i := 0; i < n; i++ {
+		r := d.replicas[(start+i)%n]
+		if r.usable.Load() && !r.closed.Load() {
+			return r.db
+		}
+	}
+	return d
+}
+
+// MaxReplicaLag is the staleness of data read through Replica, 0 without
+// replicas.
+func (d *DB) MaxReplicaLag() time.Duration {
+	if len(d.replicas) == 0 {
+		return 0
+	}
+	return d.maxReplicaLag()
+}
+
+func (d *DB) maxReplicaLag() time.Duration {
+	if d.cfg.MaxReplicaLagSeconds <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(d.cfg.MaxReplicaLagSeconds) * time.Second
+}
+
+func (d *DB) replicaCheckInterval() time.Duration {
+	if d.cfg.ReplicaCheckIntervalSeconds <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(d.cfg.ReplicaCheckIntervalSeconds) * time.Second
+}
+
+// startReplicas connects to the replicas in the background; until a replica
+// is found usable its reads go to the primary.
+func (d *DB) startReplicas() {
+	if len(d.replicas) == 0 {
+		return
+	}
+	for _, r := range d.replicas {
+		if r.closed.Swap(false) {
+			// Reconnect on the first probe after a restart.
+			r.db.DB = nil
+			r.checked = false
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	d.stopReplicas = func() {
+		cancel()
+		<-done
+	}
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(d.replicaCheckInterval())
+		defer ticker.Stop()
+		for {
+			for _, r := range d.replicas {
+				d.checkReplica(ctx, r)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (d *DB) checkReplica(ctx context.Context, r *replica) {
+	ctx, cancel := context.WithTimeout(ctx, d.replicaCheckInterval())
+	defer cancel()
+
+	if r.db.DB == nil {
+		if err := r.db.Start(ctx); err != nil {
+			d.setReplicaUsable(r, false, logger.Err(err))
+			return
+		}
+	}
+
+	var lag float64
+	if err := r.db.DB.QueryRowContext(ctx, replicaLagSQL).Scan(&lag); err != nil {
+		d.setReplicaUsable(r, false, logger.Err(err))
+		return
+	}
+	lagAttr := slog.Duration("lag", time.Duration(lag*float64(time.Second)))
+	d.setReplicaUsable(r, lag <= d.maxReplicaLag().Seconds(), lagAttr)
+}
+
+func (d *DB) setReplicaUsable(r *replica, usable bool, attr slog.Attr) {
+	if r.usable.Swap(usable) == usable && r.checked {
+		return
+	}
+	r.checked = true
+	if usable {
+		r.db.log.Info("postgres replica serving reads", attr)
+	} else {
+		r.db.log.Warn("postgres replica skipped, reads fall back to the primary", attr)
+	}
+}
+
+// closeReplicas stops probing and disconnects the replicas. Each replica is
+// taken out of rotation before its pool is closed so that Replica hands out
+// the primary from then on. The pool itself is kept: a read that picked the
+// replica just before gets an error from the closed pool rather than a nil
+// dereference.
+func (d *DB) closeReplicas(ctx context.Context) {
+	if d.stopReplicas != nil {
+		d.stopReplicas()
+		d.stopReplicas = nil
+	}
+	for _, r := range d.replicas {
+		r.closed.Store(true)
+		r.usable.Store(false)
+		if r.db.DB == nil {
+			continue
+		}
+		if err := r.db.Stop(ctx); err != nil {
+			r.db.log.Warn("failed to close postgres replica", logger.Err(err))
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// newTestDB returns a primary with usable replicas whose pools are opened but
+// never connected.
+func newTestDB(t *testing.T, replicas ...string) *DB {
+	t.Helper()
+
+	open := func() *sqlx.DB {
+		db, err := sqlx.Open("postgres", "host=127.0.0.1 port=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	d := NewDB(Config{Replicas: replicas}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	d.DB = open()
+	t.Cleanup(func() { _ = d.DB.Close() })
+	for _, r := range d.replicas {
+		r.db.DB = open()
+		r.usable.Store(true)
+	}
+	return d
+}
+
+func TestReplica(t *testing.T) {
+	t.Run("no replicas", func(t *testing.T) {
+		d := newTestDB(t)
+		if got := d.Replica(); got != d {
+			t.Error("Replica() did not return the primary")
+		}
+	})
+
+	t.Run("only usable replicas", func(t *testing.T) {
+		d := newTestDB(t, "10.0.0.1:5432", "10.0.0.2:5432", "10.0.0.3:5432")
+		d.replicas[1].usable.Store(false)
+
+		seen := make(map[*DB]int)
+		for i := 0; i < 6; i++ {
+			seen[d.Replica()]++
+		}
+		if len(seen) != 2 || seen[d.replicas[0].db] == 0 || seen[d.replicas[2].db] == 0 {
+			t.Errorf("Replica() spread = %v, want only replicas 0 and 2", seen)
+		}
+	})
+
+	t.Run("primary when no replica is usable", func(t *testing.T) {
+		d := newTestDB(t, "10.0.0.1:5432")
+		d.replicas[0].usable.Store(false)
+		if got := d.Replica(); got != d {
+			t.Error("Replica() did not fall back to the primary")
+		}
+	})
+
+	t.Run("primary once replicas are closed", func(t *testing.T) {
+		d := newTestDB(t, "10.0.0.1:5432", "10.0.0.2:5432")
+		d.closeReplicas(context.Background())
+
+		for i := 0; i < len(d.replicas); i++ {
+			if got := d.Replica(); got != d {
+				t.Fatal("Replica() returned a closed replica")
+			}
+		}
+	})
+}
+
+func TestCloseReplicasDuringReads(t *testing.T) {
+	d := newTestDB(t, "10.0.0.1:5432", "10.0.0.2:5432")
+
+	var wg, running sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		running.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				if n == 100 {
+					running.Done()
+				}
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if db := d.Replica(); db.DB == nil {
+					t.Error("Replica() returned a database without a pool")
+				}
+			}
+		}()
+	}
+
+	running.Wait()
+	d.closeReplicas(context.Background())
+	for range d.replicas {
+		if d.Replica() != d {
+			t.Error("Replica() returned a closed replica")
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+// Tx is a transaction that, like DB, runs the queries registered with
+// PrepareOnStart through their prepared statements.
+type Tx struct {
+	*sqlx.Tx
+	db *DB
+}
+
+// BeginTxx starts a transaction on the primary.
+func (d *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := d.DB.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: d}, nil
+}
+
+func (t *Tx) stmt(ctx context.Context, query string) (*sqlx.Stmt, bool) {
+	stmt, ok := t.db.stmts[query]
+	if !ok {
+		return nil, false
+	}
+	return t.Tx.StmtxContext(ctx, stmt), true
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if stmt, ok := t.stmt(ctx, query); ok {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return t.Tx.QueryContext(ctx, query, args...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if stmt, ok := t.stmt(ctx, query); ok {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return t.Tx.QueryRowContext(ctx, query, args...)
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if stmt, ok := t.stmt(ctx, query); ok {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return t.Tx.ExecContext(ctx, query, args...)
+}
+
+func (t *Tx) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	if stmt, ok := t.stmt(ctx, query); ok {
+		return stmt.GetContext(ctx, dest, args...)
+	}
+	return t.Tx.GetContext(ctx, dest, query, args...)
+}
+
+func (t *Tx) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	if stmt, ok := t.stmt(ctx, query); ok {
+		return stmt.SelectContext(ctx, dest, args...)
+	}
+	return t.Tx.SelectContext(ctx, dest, query, args...)
+}
@@ -0,0 +1,176 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/Dmitrij-bot/marketserv/pkg/logger"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache is a cache-aside store of JSON encoded values kept under
+// <prefix>:<key>. Concurrent misses for the same key share one load.
+type Cache[T any] struct {
+	db     *RedisDB
+	prefix string
+	ttl    atomic.Int64 // time.Duration, changed by SetTTL on reload
+	group  singleflight.Group
+	log    *slog.Logger
+
+	// generation counts invalidations, a load that saw an older one is stale.
+	generation atomic.Uint64
+}
+
+func NewCache[T any](db *RedisDB, prefix string, ttl time.Duration, log *slog.Logger) *Cache[T] {
+	c := &Cache[T]{
+		db:     db,
+		prefix: prefix,
+		log:    log,
+	}
+	c.ttl.Store(int64(ttl))
+	return c
+}
+
+// SetTTL changes the lifetime of values cached from now on.
+func (c *Cache[T]) SetTTL(ttl time.Duration) {
+	c.ttl.Store(int64(ttl))
+}
+
+// loadTimeout bounds a shared load, which outlives the caller that started it.
+const loadTimeout = 10 * time.Second
+
+// Get returns the cached value for key or calls load and caches its result.
+// Redis failures are logged and fall through to load. Concurrent callers share
+// one load that is not cancelled with any of them; each caller stops waiting
+// when its own ctx is done.
+func (c *Cache[T]) Get(ctx context.Context, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	fullKey := c.key(key)
+
+	if value, ok := c.lookup(ctx, fullKey); ok {
+		return value, nil
+	}
+
+	ch := c.group.DoChan(fullKey, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		if value, ok := c.lookup(ctx, fullKey); ok {
+			return value, nil
+		}
+
+		generation := c.generation.Load()
+		value, err := load(ctx)
+		if err != nil {
+			return value, err
+		}
+
+		c.store(ctx, fullKey, value, generation)
+		return value, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
+
+// store caches a value loaded at generation. A value loaded before an
+// invalidation is not written, or deleted again if the invalidation raced
+// with the write, so it cannot outlive the change that invalidated it.
+func (c *Cache[T]) store(ctx context.Context, fullKey string, value T, generation uint64) {
+	if c.generation.Load() != generation {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		c.log.WarnContext(ctx, "failed to encode cached value", slog.String("key", fullKey), logger.Err(err))
+		return
+	}
+	if err := c.db.Client.Set(ctx, fullKey, data, time.Duration(c.ttl.Load())).Err(); err != nil {
+		c.log.WarnContext(ctx, "failed to write cache", slog.String("key", fullKey), logger.Err(err))
+		return
+	}
+
+	if c.generation.Load() != generation {
+		if err := c.db.Client.Del(ctx, fullKey).Err(); err != nil {
+			c.log.WarnContext(ctx, "failed to drop stale cached value", slog.String("key", fullKey), logger.Err(err))
+		}
+	}
+}
+
+// Invalidate removes the given keys.
+func (c *Cache[T]) Invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	c.generation.Add(1)
+
+	fullKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fullKey := c.key(key)
+		// Callers arriving from now on must not join a load started before.
+		c.group.Forget(fullKey)
+		fullKeys = append(fullKeys, fullKey)
+	}
+
+	return c.db.Client.Del(ctx, fullKeys...).Err()
+}
+
+// InvalidateAll removes every key of the cache.
+func (c *Cache[T]) InvalidateAll(ctx context.Context) error {
+	c.generation.Add(1)
+
+	iter := c.db.Client.Scan(ctx, 0, c.prefix+":*", 100).Iterator()
+
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 100 {
+			if err := c.db.Client.Del(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return c.db.Client.Del(ctx, batch...).Err()
+	}
+
+	return nil
+}
+
+func (c *Cache[T]) lookup(ctx context.Context, fullKey string) (value T, ok bool) {
+	data, err := c.db.Client.Get(ctx, fullKey).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.log.WarnContext(ctx, "failed to read cache", slog.String("key", fullKey), logger.Err(err))
+		}
+		return value, false
+	}
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		c.log.WarnContext(ctx, "failed to decode cached value", slog.String("key", fullKey), logger.Err(err))
+		return value, false
+	}
+
+	return value, true
+}
+
+func (c *Cache[T]) key(key string) string {
+	return c.prefix + ":" + key
+}
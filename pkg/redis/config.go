@@ -1,6 +1,17 @@
 package redis
 
 type Config struct {
-	Host string
-	Port string
+	Host     string
+	Port     string
+	Username string // ACL user, empty for the default user
+	Password string `secret:"true"`
+	TLS      TLSConfig
+}
+
+type TLSConfig struct {
+	Enabled    bool
+	CAFile     string // CA bundle, the system pool when empty
+	CertFile   string // client certificate for mutual TLS
+	KeyFile    string
+	ServerName string // defaults to Host
 }
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"time"
+)
+
+var (
+	commandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "marketserv_redis_command_duration_seconds",
+		Help:    "Latency of Redis commands; pipelines are reported as a single \"pipeline\" command.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command"})
+	commandErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "marketserv_redis_command_errors_total",
+		Help: "Redis commands that failed; a nil reply is not an error.",
+	}, []string{"command"})
+)
+
+type startKey struct{}
+
+// metricsHook records command latencies and errors of a client.
+type metricsHook struct{}
+
+func (metricsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (metricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	observe(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (metricsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (metricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+			err = cmdErr
+			break
+		}
+	}
+	observe(ctx, "pipeline", err)
+	return nil
+}
+
+func observe(ctx context.Context, command string, err error) {
+	if start, ok := ctx.Value(startKey{}).(time.Time); ok {
+		commandDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		commandErrors.WithLabelValues(command).Inc()
+	}
+}
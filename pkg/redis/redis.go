@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/go-redis/redis/v8"
 )
@@ -21,17 +22,37 @@ func (r *RedisDB) Start(ctx context.Context) error {
 		r.cfg.Port,
 	)
 
-	r.Client = redis.NewClient(&redis.Options{
-		Addr: address,
+	tlsConfig, err := r.cfg.TLS.build()
+	if err != nil {
+		return err
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:      address,
+		Username:  r.cfg.Username,
+		Password:  r.cfg.Password,
+		TLSConfig: tlsConfig,
 	})
+	client.AddHook(metricsHook{})
+	client.AddHook(tracingHook{})
 
-	if _, err := r.Client.Ping(ctx).Result(); err != nil {
+	if _, err := client.Ping(ctx).Result(); err != nil {
+		_ = client.Close()
 		return err
 	}
 
+	r.Client = client
 	return nil
 }
 
+// Health pings Redis.
+func (r *RedisDB) Health(ctx context.Context) error {
+	if r.Client == nil {
+		return errors.New("redis is not connected")
+	}
+	return r.Client.Ping(ctx).Err()
+}
+
 func (r *RedisDB) Stop(ctx context.Context) error {
 	return r.Client.Close()
 }
@@ -0,0 +1,15 @@
+package tracing
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type Config struct {
+	Exporter    string  // "none" (default), "stdout" or "otlp"
+	Endpoint    string  // OTLP gRPC collector address, e.g. localhost:4317
+	Insecure    bool    // plaintext connection to the collector
+	ServiceName string  // service.name resource attribute
+	SampleRatio float64 // fraction of new traces to sample, 0 means all
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"log/slog"
+)
+
+// Provider installs the global OpenTelemetry tracer provider and W3C trace
+// context propagator. Tracers obtained with otel.Tracer before Start begin
+// recording once it has run.
+type Provider struct {
+	cfg      Config
+	provider *sdktrace.TracerProvider
+	log      *slog.Logger
+}
+
+func NewProvider(cfg Config, log *slog.Logger) *Provider {
+	return &Provider{cfg: cfg, log: log}
+}
+
+func (p *Provider) Start(ctx context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, err := p.exporter(ctx)
+	if err != nil {
+		return err
+	}
+	if exporter == nil {
+		p.log.Info("tracing is disabled")
+		return nil
+	}
+
+	serviceName := p.cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "marketserv"
+	}
+
+	ratio := p.cfg.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	p.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(p.provider)
+
+	p.log.Info("tracing is enabled", slog.String("exporter", p.cfg.Exporter))
+	return nil
+}
+
+func (p *Provider) exporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	switch p.cfg.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(p.cfg.Endpoint)}
+		if p.cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", p.cfg.Exporter)
+	}
+}
+
+// Stop flushes buffered spans.
+func (p *Provider) Stop(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+
+	err := p.provider.Shutdown(ctx)
+	p.provider = nil
+	return err
+}
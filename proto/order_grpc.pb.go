@@ -1,5 +1,5 @@
 //cd C:\Users\дмитрий\marketserv\proto
-//protoc --proto_path=. --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative order.proto
+//protoc --proto_path=. --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative order.proto validate.proto
 
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
 // versions:
@@ -22,12 +22,25 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	UserService_FindClientByUsername_FullMethodName = "/order.UserService/FindClientByUsername"
-	UserService_SearchProductByName_FullMethodName  = "/order.UserService/SearchProductByName"
-	UserService_AddItemToCart_FullMethodName        = "/order.UserService/AddItemToCart"
-	UserService_DeleteItemFromCart_FullMethodName   = "/order.UserService/DeleteItemFromCart"
-	UserService_GetCart_FullMethodName              = "/order.UserService/GetCart"
-	UserService_SimulatePayment_FullMethodName      = "/order.UserService/SimulatePayment"
+	UserService_FindClientByUsername_FullMethodName    = "/order.UserService/FindClientByUsername"
+	UserService_SearchProductByName_FullMethodName     = "/order.UserService/SearchProductByName"
+	UserService_AddItemToCart_FullMethodName           = "/order.UserService/AddItemToCart"
+	UserService_DeleteItemFromCart_FullMethodName      = "/order.UserService/DeleteItemFromCart"
+	UserService_GetCart_FullMethodName                 = "/order.UserService/GetCart"
+	UserService_SimulatePayment_FullMethodName         = "/order.UserService/SimulatePayment"
+	UserService_AddItemToGuestCart_FullMethodName      = "/order.UserService/AddItemToGuestCart"
+	UserService_DeleteItemFromGuestCart_FullMethodName = "/order.UserService/DeleteItemFromGuestCart"
+	UserService_GetGuestCart_FullMethodName            = "/order.UserService/GetGuestCart"
+	UserService_MergeCart_FullMethodName               = "/order.UserService/MergeCart"
+	UserService_ApplyPromoCode_FullMethodName          = "/order.UserService/ApplyPromoCode"
+	UserService_RemovePromoCode_FullMethodName         = "/order.UserService/RemovePromoCode"
+	UserService_AddAddress_FullMethodName              = "/order.UserService/AddAddress"
+	UserService_ListAddresses_FullMethodName           = "/order.UserService/ListAddresses"
+	UserService_DeleteAddress_FullMethodName           = "/order.UserService/DeleteAddress"
+	UserService_GetShippingOptions_FullMethodName      = "/order.UserService/GetShippingOptions"
+	UserService_Checkout_FullMethodName                = "/order.UserService/Checkout"
+	UserService_CreateCheckout_FullMethodName          = "/order.UserService/CreateCheckout"
+	UserService_ConfirmCheckout_FullMethodName         = "/order.UserService/ConfirmCheckout"
 )
 
 // UserServiceClient is the client API for UserService service.
@@ -40,6 +53,19 @@ type UserServiceClient interface {
 	DeleteItemFromCart(ctx context.Context, in *DeleteFromCartRequest, opts ...grpc.CallOption) (*DeleteFromCartResponse, error)
 	GetCart(ctx context.Context, in *GetCartRequest, opts ...grpc.CallOption) (*GetCartResponse, error)
 	SimulatePayment(ctx context.Context, in *PaymentRequest, opts ...grpc.CallOption) (*PaymentResponse, error)
+	AddItemToGuestCart(ctx context.Context, in *AddToGuestCartRequest, opts ...grpc.CallOption) (*AddToGuestCartResponse, error)
+	DeleteItemFromGuestCart(ctx context.Context, in *DeleteFromGuestCartRequest, opts ...grpc.CallOption) (*DeleteFromCartResponse, error)
+	GetGuestCart(ctx context.Context, in *GetGuestCartRequest, opts ...grpc.CallOption) (*GetCartResponse, error)
+	MergeCart(ctx context.Context, in *MergeCartRequest, opts ...grpc.CallOption) (*MergeCartResponse, error)
+	ApplyPromoCode(ctx context.Context, in *ApplyPromoCodeRequest, opts ...grpc.CallOption) (*ApplyPromoCodeResponse, error)
+	RemovePromoCode(ctx context.Context, in *RemovePromoCodeRequest, opts ...grpc.CallOption) (*RemovePromoCodeResponse, error)
+	AddAddress(ctx context.Context, in *AddAddressRequest, opts ...grpc.CallOption) (*AddAddressResponse, error)
+	ListAddresses(ctx context.Context, in *ListAddressesRequest, opts ...grpc.CallOption) (*ListAddressesResponse, error)
+	DeleteAddress(ctx context.Context, in *DeleteAddressRequest, opts ...grpc.CallOption) (*DeleteAddressResponse, error)
+	GetShippingOptions(ctx context.Context, in *GetShippingOptionsRequest, opts ...grpc.CallOption) (*GetShippingOptionsResponse, error)
+	Checkout(ctx context.Context, in *CheckoutRequest, opts ...grpc.CallOption) (*CheckoutResponse, error)
+	CreateCheckout(ctx context.Context, in *CreateCheckoutRequest, opts ...grpc.CallOption) (*CreateCheckoutResponse, error)
+	ConfirmCheckout(ctx context.Context, in *ConfirmCheckoutRequest, opts ...grpc.CallOption) (*ConfirmCheckoutResponse, error)
 }
 
 type userServiceClient struct {
@@ -110,6 +136,136 @@ func (c *userServiceClient) SimulatePayment(ctx context.Context, in *PaymentRequ
 	return out, nil
 }
 
+func (c *userServiceClient) AddItemToGuestCart(ctx context.Context, in *AddToGuestCartRequest, opts ...grpc.CallOption) (*AddToGuestCartResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddToGuestCartResponse)
+	err := c.cc.Invoke(ctx, UserService_AddItemToGuestCart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) DeleteItemFromGuestCart(ctx context.Context, in *DeleteFromGuestCartRequest, opts ...grpc.CallOption) (*DeleteFromCartResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteFromCartResponse)
+	err := c.cc.Invoke(ctx, UserService_DeleteItemFromGuestCart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetGuestCart(ctx context.Context, in *GetGuestCartRequest, opts ...grpc.CallOption) (*GetCartResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCartResponse)
+	err := c.cc.Invoke(ctx, UserService_GetGuestCart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) MergeCart(ctx context.Context, in *MergeCartRequest, opts ...grpc.CallOption) (*MergeCartResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MergeCartResponse)
+	err := c.cc.Invoke(ctx, UserService_MergeCart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ApplyPromoCode(ctx context.Context, in *ApplyPromoCodeRequest, opts ...grpc.CallOption) (*ApplyPromoCodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ApplyPromoCodeResponse)
+	err := c.cc.Invoke(ctx, UserService_ApplyPromoCode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) RemovePromoCode(ctx context.Context, in *RemovePromoCodeRequest, opts ...grpc.CallOption) (*RemovePromoCodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemovePromoCodeResponse)
+	err := c.cc.Invoke(ctx, UserService_RemovePromoCode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) AddAddress(ctx context.Context, in *AddAddressRequest, opts ...grpc.CallOption) (*AddAddressResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddAddressResponse)
+	err := c.cc.Invoke(ctx, UserService_AddAddress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ListAddresses(ctx context.Context, in *ListAddressesRequest, opts ...grpc.CallOption) (*ListAddressesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAddressesResponse)
+	err := c.cc.Invoke(ctx, UserService_ListAddresses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) DeleteAddress(ctx context.Context, in *DeleteAddressRequest, opts ...grpc.CallOption) (*DeleteAddressResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteAddressResponse)
+	err := c.cc.Invoke(ctx, UserService_DeleteAddress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetShippingOptions(ctx context.Context, in *GetShippingOptionsRequest, opts ...grpc.CallOption) (*GetShippingOptionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetShippingOptionsResponse)
+	err := c.cc.Invoke(ctx, UserService_GetShippingOptions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) Checkout(ctx context.Context, in *CheckoutRequest, opts ...grpc.CallOption) (*CheckoutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckoutResponse)
+	err := c.cc.Invoke(ctx, UserService_Checkout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) CreateCheckout(ctx context.Context, in *CreateCheckoutRequest, opts ...grpc.CallOption) (*CreateCheckoutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateCheckoutResponse)
+	err := c.cc.Invoke(ctx, UserService_CreateCheckout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ConfirmCheckout(ctx context.Context, in *ConfirmCheckoutRequest, opts ...grpc.CallOption) (*ConfirmCheckoutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ConfirmCheckoutResponse)
+	err := c.cc.Invoke(ctx, UserService_ConfirmCheckout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UserServiceServer is the server API for UserService service.
 // All implementations must embed UnimplementedUserServiceServer
 // for forward compatibility.
@@ -120,6 +276,19 @@ type UserServiceServer interface {
 	DeleteItemFromCart(context.Context, *DeleteFromCartRequest) (*DeleteFromCartResponse, error)
 	GetCart(context.Context, *GetCartRequest) (*GetCartResponse, error)
 	SimulatePayment(context.Context, *PaymentRequest) (*PaymentResponse, error)
+	AddItemToGuestCart(context.Context, *AddToGuestCartRequest) (*AddToGuestCartResponse, error)
+	DeleteItemFromGuestCart(context.Context, *DeleteFromGuestCartRequest) (*DeleteFromCartResponse, error)
+	GetGuestCart(context.Context, *GetGuestCartRequest) (*GetCartResponse, error)
+	MergeCart(context.Context, *MergeCartRequest) (*MergeCartResponse, error)
+	ApplyPromoCode(context.Context, *ApplyPromoCodeRequest) (*ApplyPromoCodeResponse, error)
+	RemovePromoCode(context.Context, *RemovePromoCodeRequest) (*RemovePromoCodeResponse, error)
+	AddAddress(context.Context, *AddAddressRequest) (*AddAddressResponse, error)
+	ListAddresses(context.Context, *ListAddressesRequest) (*ListAddressesResponse, error)
+	DeleteAddress(context.Context, *DeleteAddressRequest) (*DeleteAddressResponse, error)
+	GetShippingOptions(context.Context, *GetShippingOptionsRequest) (*GetShippingOptionsResponse, error)
+	Checkout(context.Context, *CheckoutRequest) (*CheckoutResponse, error)
+	CreateCheckout(context.Context, *CreateCheckoutRequest) (*CreateCheckoutResponse, error)
+	ConfirmCheckout(context.Context, *ConfirmCheckoutRequest) (*ConfirmCheckoutResponse, error)
 	mustEmbedUnimplementedUserServiceServer()
 }
 
@@ -148,6 +317,45 @@ func (UnimplementedUserServiceServer) GetCart(context.Context, *GetCartRequest)
 func (UnimplementedUserServiceServer) SimulatePayment(context.Context, *PaymentRequest) (*PaymentResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SimulatePayment not implemented")
 }
+func (UnimplementedUserServiceServer) AddItemToGuestCart(context.Context, *AddToGuestCartRequest) (*AddToGuestCartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddItemToGuestCart not implemented")
+}
+func (UnimplementedUserServiceServer) DeleteItemFromGuestCart(context.Context, *DeleteFromGuestCartRequest) (*DeleteFromCartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteItemFromGuestCart not implemented")
+}
+func (UnimplementedUserServiceServer) GetGuestCart(context.Context, *GetGuestCartRequest) (*GetCartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGuestCart not implemented")
+}
+func (UnimplementedUserServiceServer) MergeCart(context.Context, *MergeCartRequest) (*MergeCartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeCart not implemented")
+}
+func (UnimplementedUserServiceServer) ApplyPromoCode(context.Context, *ApplyPromoCodeRequest) (*ApplyPromoCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyPromoCode not implemented")
+}
+func (UnimplementedUserServiceServer) RemovePromoCode(context.Context, *RemovePromoCodeRequest) (*RemovePromoCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemovePromoCode not implemented")
+}
+func (UnimplementedUserServiceServer) AddAddress(context.Context, *AddAddressRequest) (*AddAddressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddAddress not implemented")
+}
+func (UnimplementedUserServiceServer) ListAddresses(context.Context, *ListAddressesRequest) (*ListAddressesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAddresses not implemented")
+}
+func (UnimplementedUserServiceServer) DeleteAddress(context.Context, *DeleteAddressRequest) (*DeleteAddressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAddress not implemented")
+}
+func (UnimplementedUserServiceServer) GetShippingOptions(context.Context, *GetShippingOptionsRequest) (*GetShippingOptionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetShippingOptions not implemented")
+}
+func (UnimplementedUserServiceServer) Checkout(context.Context, *CheckoutRequest) (*CheckoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Checkout not implemented")
+}
+func (UnimplementedUserServiceServer) CreateCheckout(context.Context, *CreateCheckoutRequest) (*CreateCheckoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCheckout not implemented")
+}
+func (UnimplementedUserServiceServer) ConfirmCheckout(context.Context, *ConfirmCheckoutRequest) (*ConfirmCheckoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmCheckout not implemented")
+}
 func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
 func (UnimplementedUserServiceServer) testEmbeddedByValue()                     {}
 
@@ -277,6 +485,240 @@ func _UserService_SimulatePayment_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_AddItemToGuestCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddToGuestCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).AddItemToGuestCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_AddItemToGuestCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).AddItemToGuestCart(ctx, req.(*AddToGuestCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_DeleteItemFromGuestCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteFromGuestCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).DeleteItemFromGuestCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_DeleteItemFromGuestCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).DeleteItemFromGuestCart(ctx, req.(*DeleteFromGuestCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetGuestCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGuestCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetGuestCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetGuestCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetGuestCart(ctx, req.(*GetGuestCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_MergeCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).MergeCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_MergeCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).MergeCart(ctx, req.(*MergeCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ApplyPromoCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyPromoCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ApplyPromoCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ApplyPromoCode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ApplyPromoCode(ctx, req.(*ApplyPromoCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_RemovePromoCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemovePromoCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).RemovePromoCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_RemovePromoCode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).RemovePromoCode(ctx, req.(*RemovePromoCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_AddAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).AddAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_AddAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).AddAddress(ctx, req.(*AddAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ListAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAddressesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ListAddresses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ListAddresses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ListAddresses(ctx, req.(*ListAddressesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_DeleteAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).DeleteAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_DeleteAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).DeleteAddress(ctx, req.(*DeleteAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetShippingOptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetShippingOptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetShippingOptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetShippingOptions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetShippingOptions(ctx, req.(*GetShippingOptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_Checkout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Checkout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_Checkout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Checkout(ctx, req.(*CheckoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_CreateCheckout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCheckoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).CreateCheckout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_CreateCheckout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).CreateCheckout(ctx, req.(*CreateCheckoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ConfirmCheckout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmCheckoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ConfirmCheckout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ConfirmCheckout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ConfirmCheckout(ctx, req.(*ConfirmCheckoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -308,6 +750,58 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SimulatePayment",
 			Handler:    _UserService_SimulatePayment_Handler,
 		},
+		{
+			MethodName: "AddItemToGuestCart",
+			Handler:    _UserService_AddItemToGuestCart_Handler,
+		},
+		{
+			MethodName: "DeleteItemFromGuestCart",
+			Handler:    _UserService_DeleteItemFromGuestCart_Handler,
+		},
+		{
+			MethodName: "GetGuestCart",
+			Handler:    _UserService_GetGuestCart_Handler,
+		},
+		{
+			MethodName: "MergeCart",
+			Handler:    _UserService_MergeCart_Handler,
+		},
+		{
+			MethodName: "ApplyPromoCode",
+			Handler:    _UserService_ApplyPromoCode_Handler,
+		},
+		{
+			MethodName: "RemovePromoCode",
+			Handler:    _UserService_RemovePromoCode_Handler,
+		},
+		{
+			MethodName: "AddAddress",
+			Handler:    _UserService_AddAddress_Handler,
+		},
+		{
+			MethodName: "ListAddresses",
+			Handler:    _UserService_ListAddresses_Handler,
+		},
+		{
+			MethodName: "DeleteAddress",
+			Handler:    _UserService_DeleteAddress_Handler,
+		},
+		{
+			MethodName: "GetShippingOptions",
+			Handler:    _UserService_GetShippingOptions_Handler,
+		},
+		{
+			MethodName: "Checkout",
+			Handler:    _UserService_Checkout_Handler,
+		},
+		{
+			MethodName: "CreateCheckout",
+			Handler:    _UserService_CreateCheckout_Handler,
+		},
+		{
+			MethodName: "ConfirmCheckout",
+			Handler:    _UserService_ConfirmCheckout_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "order.proto",